@@ -2,3 +2,7 @@
 
 hash=$(git rev-parse --short HEAD)
 GOOS=windows GOARCH=amd64 go build -ldflags "-X main.Hash=$hash" -o dist/mt-to-exante-sdk.exe cmd/api/main.go
+GOOS=windows GOARCH=amd64 go build -ldflags "-X main.Hash=$hash" -o dist/mt-to-exante-transactions.exe cmd/transactions/main.go
+GOOS=windows GOARCH=amd64 go build -ldflags "-X main.Hash=$hash" -o dist/mt-to-exante-state.exe cmd/state/main.go
+GOOS=windows GOARCH=amd64 go build -ldflags "-X main.Hash=$hash" -o dist/mt-to-exante-exchanges.exe cmd/exchanges/main.go
+GOOS=windows GOARCH=amd64 go build -ldflags "-X main.Hash=$hash" -o dist/mt-to-exante-validate.exe cmd/validate/main.go
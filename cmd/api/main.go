@@ -1,23 +1,53 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
+	"github.com/danielsussa/mt5-to-exante/internal/audit"
 	"github.com/danielsussa/mt5-to-exante/internal/controller"
 	"github.com/danielsussa/mt5-to-exante/internal/exante"
 	"github.com/danielsussa/mt5-to-exante/internal/exchanges"
+	"github.com/danielsussa/mt5-to-exante/internal/idempotency"
+	"github.com/danielsussa/mt5-to-exante/internal/journal"
 	"github.com/danielsussa/mt5-to-exante/internal/orderdb"
-	"github.com/danielsussa/mt5-to-exante/internal/utils"
+	"github.com/danielsussa/mt5-to-exante/internal/queue"
+	"github.com/google/uuid"
 	"github.com/joho/godotenv"
 	"github.com/labstack/echo/v4"
 )
 
 var Hash string
 
+const (
+	// journalLines returned by /journal
+	journalLines = 200
+	compactEvery = 24 * time.Hour
+	// exchangesWatchEvery check EXCHANGE_PATH for changes
+	exchangesWatchEvery = 5 * time.Second
+	// shutdownTimeout wait for the requests in progress on ctrl+c
+	shutdownTimeout = 10 * time.Second
+	// idempotencyTTL is how long /v1 responses are kept by Idempotency-Key
+	idempotencyTTL    = 24 * time.Hour
+	idempotencyHeader = "Idempotency-Key"
+	// idempotencyRetry is set on the context when the key is released
+	idempotencyRetry = "idempotency.retry"
+)
+
 func main() {
 	ex, _ := os.Executable()
 	exPath := filepath.Dir(ex)
@@ -34,41 +64,162 @@ func main() {
 		panic(err)
 	}
 
-	orderState, err := orderdb.New(exPath)
+	// ORDERDB_BACKEND is diskv (default) or file
+	orderStorage, err := orderdb.OpenStorage(exPath, os.Getenv("ORDERDB_BACKEND"))
 	if err != nil {
 		panic("cannot create local DB")
 	}
+	orderState, err := orderdb.NewWithStorage(orderStorage)
+	if err != nil {
+		panic(err)
+	}
+	defer orderState.Close()
+	// ORDERDB_REBUILD="true" replay the event log of every ticket, used after a corruption
+	if os.Getenv("ORDERDB_REBUILD") == "true" {
+		if err := orderState.Rebuild(); err != nil {
+			panic(err)
+		}
+	}
+
+	pending, err := queue.New(exPath)
+	if err != nil {
+		panic(err)
+	}
+
+	// BASE_URL="fake" run the SDK against an in memory exante server
+	var fakeServer *exante.FakeServer
+	var exanteApi *exante.Api
+	if os.Getenv("BASE_URL") == "fake" {
+		fakeServer = exante.NewFakeServer()
+		defer fakeServer.Close()
+		exanteApi = fakeServer.NewApi()
+	} else {
+		exanteApi = exante.NewApi(
+			os.Getenv("BASE_URL"),
+			os.Getenv("APPLICATION_ID"),
+			os.Getenv("CLIENT_ID"),
+			os.Getenv("SHARED_KEY"),
+		)
+	}
+
+	// EXANTE_RECORD save every exante request/response to a cassette,
+	// written when the SDK stops
+	if recordPath := os.Getenv("EXANTE_RECORD"); len(recordPath) > 0 {
+		recorder := exante.NewRecordingTransport(fmt.Sprintf("%s/%s", exPath, recordPath))
+		exanteApi.SetTransport(recorder)
+		defer func() {
+			if err := recorder.Close(); err != nil {
+				fmt.Println("error saving exante cassette: ", err.Error())
+			}
+		}()
+	}
+
+	auditPath := os.Getenv("AUDIT_PATH")
+	if len(auditPath) == 0 {
+		auditPath = "audit"
+	}
+	auditLog, err := audit.New(fmt.Sprintf("%s/%s", exPath, auditPath))
+	if err != nil {
+		panic(err)
+	}
+	defer auditLog.Close()
+	exanteApi.SetAuditLog(auditLog)
 
-	exanteApi := exante.NewApi(
-		os.Getenv("BASE_URL"),
-		os.Getenv("APPLICATION_ID"),
-		os.Getenv("CLIENT_ID"),
-		os.Getenv("SHARED_KEY"),
-	)
+	account, err := exanteApi.DiscoverAccount(os.Getenv("ACCOUNT_ID"), os.Getenv("ACCOUNT_ALIASES"))
+	if err != nil {
+		fmt.Println(fmt.Sprintf("cannot start: %s", err.Error()))
+		os.Exit(1)
+	}
+	fmt.Println(fmt.Sprintf("account: %s", account.AccountID))
 
-	c := controller.New(exanteApi, *exchangeApi)
+	c := controller.New(exanteApi, orderState, exchangeApi, pending)
+	// MARKET_ORDER_MAX_AGE (seconds) of a queued market order before it is dead lettered
+	if maxAge, err := strconv.Atoi(os.Getenv("MARKET_ORDER_MAX_AGE")); err == nil {
+		c.MarketOrderMaxAge = time.Duration(maxAge) * time.Second
+	}
+	// RETENTION_DAYS a closed group is kept before it is archived
+	if days, err := strconv.Atoi(os.Getenv("RETENTION_DAYS")); err == nil {
+		c.Retention = time.Duration(days) * 24 * time.Hour
+	}
+	historyPath := fmt.Sprintf("%s/%s", exPath, controller.HistoryFile)
+	err = c.LoadHistory(historyPath)
+	if err != nil {
+		panic(err)
+	}
+	idempotencyStore, err := idempotency.New(fmt.Sprintf("%s/%s", exPath, idempotency.FileName), idempotencyTTL)
+	if err != nil {
+		panic(err)
+	}
 
 	h := api{
-		accountID:   os.Getenv("ACCOUNT_ID"),
+		accountID:   account.AccountID,
 		exApi:       exanteApi,
 		orderState:  orderState,
 		exchangeApi: exchangeApi,
 		controller:  c,
+		fakeServer:  fakeServer,
+		auditLog:    auditLog,
+		pending:     pending,
+		journal:     journal.New(fmt.Sprintf("%s/%s", exPath, journal.FileName)),
+		historyPath: historyPath,
+		idempotency: idempotencyStore,
 	}
+	stopWatch := exchangeApi.Watch(exchangesWatchEvery, h.exchangesReloaded)
+	defer stopWatch()
+	h.compact()
+	go func() {
+		for range time.Tick(compactEvery) {
+			h.compact()
+		}
+	}()
 
 	e := echo.New()
 
-	e.GET("/health", func(c echo.Context) error {
-		fmt.Println("health")
-		return c.String(http.StatusOK, "ok")
-	})
+	e.GET("/health", h.health)
 
 	e.GET("/jwt", h.getJwt)
 	e.GET("/accounts", h.getAccounts)
 	e.GET("/orders", h.getOrders)
+	e.GET("/summary", h.getSummary)
+	e.GET("/audit", h.getAudit)
+	e.GET("/quote", h.getQuote)
+	e.GET("/quote/stream", h.streamQuotes)
+	e.GET("/ohlc", h.getOHLC)
 	e.POST("/sync", h.sync)
-	e.POST("/journal", h.journal)
-	e.Logger.Fatal(e.Start(":1323"))
+	e.POST("/journal", h.getJournal)
+	e.GET("/state/orders", h.getStateOrders)
+	e.GET("/state/orders/:ticket/events", h.getOrderEvents)
+	e.GET("/state/archive/:ticket", h.getArchivedOrder)
+	e.GET("/admin/queue", h.getQueue)
+	e.POST("/admin/queue/dead/:id/replay", h.replayDeadLetter)
+	e.DELETE("/admin/queue/dead/:id", h.discardDeadLetter)
+	e.POST("/admin/roll", h.roll)
+
+	v1 := e.Group("/v1", h.idempotent)
+	v1.POST("/orders", h.placeOrder)
+	v1.GET("/orders/:ticket", h.getOrder)
+	v1.PATCH("/orders/:ticket", h.modifyOrder)
+	v1.DELETE("/orders/:ticket", h.cancelOrder)
+	v1.POST("/positions/:ticket/close", h.closePosition)
+
+	if fakeServer != nil {
+		e.POST("/fake/price", h.setFakePrice)
+	}
+	go func() {
+		if err := e.Start(":1323"); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			e.Logger.Fatal(err)
+		}
+	}()
+
+	// stop on ctrl+c so the deferred closes run
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	<-stop
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := e.Shutdown(ctx); err != nil {
+		fmt.Println("error stopping server: ", err.Error())
+	}
 }
 
 type api struct {
@@ -77,6 +228,34 @@ type api struct {
 	orderState  *orderdb.OrderState
 	exchangeApi *exchanges.Api
 	controller  *controller.Api
+	fakeServer  *exante.FakeServer
+	auditLog    *audit.Log
+	pending     *queue.Queue
+	journal     *journal.Journal
+	historyPath string
+	idempotency *idempotency.Store
+}
+
+func (a api) health(c echo.Context) error {
+	state := a.exApi.BreakerState()
+	code, status := healthStatus(state)
+
+	return c.JSON(code, echo.Map{
+		"status":  status,
+		"breaker": state,
+	})
+}
+
+// healthStatus is unavailable while the breaker keeps requests from exante
+// and degraded while it probes exante again
+func healthStatus(state exante.BreakerState) (int, string) {
+	switch state {
+	case exante.BreakerOpen:
+		return http.StatusServiceUnavailable, "unavailable"
+	case exante.BreakerHalfOpen:
+		return http.StatusOK, "degraded"
+	}
+	return http.StatusOK, "ok"
 }
 
 func (a api) getJwt(c echo.Context) error {
@@ -93,267 +272,490 @@ func (a api) getAccounts(c echo.Context) error {
 	return c.JSON(http.StatusOK, accounts)
 }
 
-type placeOrderRequest struct {
-	SymbolID   string  `json:"symbolID"`
-	Duration   string  `json:"duration"`
-	OrderType  string  `json:"orderType"`
-	Quantity   float64 `json:"quantity"`
-	Side       string  `json:"side"`
-	AccountId  string  `json:"accountId"`
-	LimitPrice float64 `json:"limitPrice"`
-	TakeProfit float64 `json:"takeProfit"`
-	StopLoss   float64 `json:"stopLoss"`
-}
-
-func (a api) placeOrder(c echo.Context) error {
-	req := new(placeOrderRequest)
-	if err := c.Bind(req); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+func (a api) getSummary(c echo.Context) error {
+	currency := c.QueryParam("currency")
+	if len(currency) == 0 {
+		currency = "USD"
 	}
 
-	exchange, has := a.exchangeApi.GetByMTValue(req.SymbolID)
-	if !has {
+	summary, err := a.exApi.GetAccountSummary(a.accountID, currency)
+	if err != nil {
 		return c.JSON(http.StatusBadRequest, echo.Map{
-			"error": "symbol not found",
+			"error": err.Error(),
 		})
 	}
-	orderID := c.Param("orderID")
 
-	orderDB, hasOrder := a.orderState.Get(orderID)
-	if hasOrder {
-		return c.JSON(http.StatusBadRequest, echo.Map{
-			"error": "order already exist",
-		})
+	return c.JSON(http.StatusOK, summary)
+}
+
+// getAudit query the audit log by ticket, operation and time range (RFC3339)
+func (a api) getAudit(c echo.Context) error {
+	filter := audit.Filter{
+		Ticket:    c.QueryParam("ticket"),
+		Operation: c.QueryParam("operation"),
 	}
 
-	// no active order
-	orders, err := a.exApi.PlaceOrderV3(&exante.OrderSentTypeV3{
-		SymbolID:   exchange.Exante,
-		Duration:   req.Duration,
-		OrderType:  req.OrderType,
-		Quantity:   utils.Convert5Decimals(req.Quantity * exchange.PriceStep),
-		Side:       req.Side,
-		LimitPrice: utils.Convert5Decimals(req.LimitPrice),
-		Instrument: exchange.Exante,
-		StopLoss:   utils.Convert5DecimalsOrNil(req.StopLoss),
-		TakeProfit: utils.Convert5DecimalsOrNil(req.TakeProfit),
+	var err error
+	if from := c.QueryParam("from"); len(from) > 0 {
+		if filter.From, err = time.Parse(time.RFC3339, from); err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+	if to := c.QueryParam("to"); len(to) > 0 {
+		if filter.To, err = time.Parse(time.RFC3339, to); err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+	if limit := c.QueryParam("limit"); len(limit) > 0 {
+		if filter.Limit, err = strconv.Atoi(limit); err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{
+				"error": err.Error(),
+			})
+		}
+	}
 
-		AccountID: req.AccountId,
-		ClientTag: c.Param("orderID"),
-	})
+	entries, err := a.auditLog.Query(filter)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, echo.Map{
+		return c.JSON(http.StatusInternalServerError, echo.Map{
 			"error": err.Error(),
 		})
 	}
 
-	if len(orders) == 0 {
-		return c.JSON(http.StatusBadRequest, echo.Map{
-			"error": "no order registered",
-		})
+	return c.JSON(http.StatusOK, entries)
+}
+
+// getStateOrders query the groups the bridge keeps on orderdb by symbol
+// (exante `symbolId` or MT5 `symbol`), account, status, ocoGroup and
+// last update range (RFC3339), paginated with offset and limit
+func (a api) getStateOrders(c echo.Context) error {
+	q := orderdb.Query{
+		AccountID: c.QueryParam("account"),
+		Status:    c.QueryParam("status"),
+		OcoGroup:  c.QueryParam("ocoGroup"),
+	}
+	if len(c.QueryParam("symbolId")) > 0 || len(c.QueryParam("symbol")) > 0 {
+		symbolID, has := a.symbolID(c)
+		if !has {
+			return c.JSON(http.StatusBadRequest, echo.Map{
+				"error": "symbol not found",
+			})
+		}
+		q.Symbol = symbolID
+	}
+
+	var err error
+	if from := c.QueryParam("from"); len(from) > 0 {
+		if q.From, err = time.Parse(time.RFC3339, from); err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+	if to := c.QueryParam("to"); len(to) > 0 {
+		if q.To, err = time.Parse(time.RFC3339, to); err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+	if offset := c.QueryParam("offset"); len(offset) > 0 {
+		if q.Offset, err = strconv.Atoi(offset); err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+	if limit := c.QueryParam("limit"); len(limit) > 0 {
+		if q.Limit, err = strconv.Atoi(limit); err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{
+				"error": err.Error(),
+			})
+		}
 	}
 
-	orderDB = orderdb.NewOrderGroup()
+	return c.JSON(http.StatusOK, a.orderState.Query(q))
+}
 
-	parentOrder, has := utils.GetParentOrder(orders)
-	if has {
-		orderDB.Order = *utils.ConvertExOrderToDB(*parentOrder)
+// getOrderEvents return the lifecycle log of a ticket
+func (a api) getOrderEvents(c echo.Context) error {
+	events, err := a.orderState.Events(c.Param("ticket"))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"error": err.Error(),
+		})
 	}
 
-	slOrder, has := utils.GetStopLossOrder(orders)
-	if has {
-		orderDB.StopLoss = utils.ConvertExOrderToDB(*slOrder)
+	return c.JSON(http.StatusOK, events)
+}
+
+func (a api) getArchivedOrder(c echo.Context) error {
+	archived, has, err := a.orderState.Archived(c.Param("ticket"))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"error": err.Error(),
+		})
+	}
+	if !has {
+		return c.JSON(http.StatusNotFound, echo.Map{
+			"error": "no archived order found",
+		})
 	}
 
-	tpOrder, has := utils.GetTakeProfitOrder(orders)
-	if has {
-		orderDB.TakeProfit = utils.ConvertExOrderToDB(*tpOrder)
+	return c.JSON(http.StatusOK, archived)
+}
+
+// compact archive the closed groups and forget the closed requests
+func (a api) compact() {
+	res, err := a.controller.Compact(time.Now())
+	if err != nil {
+		fmt.Println("error compacting state: ", err.Error())
+	}
+	err = a.controller.SaveHistory(a.historyPath)
+	if err != nil {
+		fmt.Println("error saving history: ", err.Error())
+	}
+	if res.Evicted == 0 && len(res.Archived) == 0 {
+		return
 	}
 
-	a.orderState.Upsert(orderID, orderDB)
+	err = a.journal.Add(fmt.Sprintf("COMPACT > ARCHIVED %d > EVICTED %d", len(res.Archived), res.Evicted))
+	if err != nil {
+		fmt.Println("error writing journal: ", err.Error())
+	}
+}
 
-	return c.JSON(http.StatusOK, "ok")
+// exchangesReloaded report a reload of EXCHANGE_PATH, an invalid file
+// keeps the previous mapping
+func (a api) exchangesReloaded(change exchanges.Change, err error) {
+	text := ""
+	switch {
+	case err != nil:
+		text = fmt.Sprintf("EXCHANGES > RELOAD FAILED > %s", strings.ReplaceAll(err.Error(), "\n", "; "))
+	case change.IsEmpty():
+		return
+	default:
+		text = fmt.Sprintf("EXCHANGES > RELOADED > ADDED %v > REMOVED %v > UPDATED %v", change.Added, change.Removed, change.Updated)
+	}
 
+	fmt.Println(text)
+	err = a.journal.Add(text)
+	if err != nil {
+		fmt.Println("error writing journal: ", err.Error())
+	}
 }
 
-type modifyOrderRequest struct {
-	LimitPrice float64 `json:"limitPrice"`
-	TakeProfit float64 `json:"takeProfit"`
-	StopLoss   float64 `json:"stopLoss"`
+func (a api) getQueue(c echo.Context) error {
+	return c.JSON(http.StatusOK, echo.Map{
+		"pending": a.pending.Pending(),
+		"dead":    a.pending.DeadLetters(),
+	})
 }
 
-func (a api) modifyOrder(c echo.Context) error {
-	req := new(modifyOrderRequest)
-	if err := c.Bind(req); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+// replayDeadLetter send a dead letter again on the next sync
+func (a api) replayDeadLetter(c echo.Context) error {
+	err := a.pending.Replay(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, echo.Map{
+			"error": err.Error(),
+		})
 	}
 
-	orderID := c.Param("orderID")
+	return c.JSON(http.StatusOK, "ok")
+}
 
-	orderDB, has := a.orderState.Get(orderID)
-	if !has {
-		return c.JSON(http.StatusBadRequest, echo.Map{
-			"error": "no parent order registered",
+func (a api) discardDeadLetter(c echo.Context) error {
+	err := a.pending.Discard(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, echo.Map{
+			"error": err.Error(),
 		})
 	}
 
-	_, _ = a.exApi.ReplaceOrder(orderDB.Order.ID, exante.ReplaceOrderPayload{
-		Action: "replace",
-		Parameters: exante.ReplaceOrderParameters{
-			Quantity:   orderDB.Order.Quantity,
-			LimitPrice: utils.Convert5Decimals(req.LimitPrice),
-		},
-	})
+	return c.JSON(http.StatusOK, "ok")
+}
 
-	// cancel stop loss order
-	if req.StopLoss == 0 && orderDB.StopLoss != nil {
-		err := a.exApi.CancelOrder(orderDB.StopLoss.ID)
-		if err == nil {
-			orderDB.StopLoss = nil
+// roll move the positions and pending orders on a contract past its roll
+// date to the current contract, SL/TP are placed again on the next sync
+func (a api) roll(c echo.Context) error {
+	rolled, err := a.controller.Roll(a.accountID, time.Now())
+	for _, r := range rolled {
+		if jerr := a.journal.Add(fmt.Sprintf("[%s] ROLL > %s > %s", r.Ticket, r.From, r.To)); jerr != nil {
+			fmt.Println("error writing journal: ", jerr.Error())
 		}
 	}
-
-	// cancel take profit order
-	if req.TakeProfit == 0 && orderDB.TakeProfit != nil {
-		err := a.exApi.CancelOrder(orderDB.TakeProfit.ID)
-		if err == nil {
-			orderDB.TakeProfit = nil
-		}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"error":  err.Error(),
+			"rolled": rolled,
+		})
 	}
 
-	if req.StopLoss > 0 {
-		if orderDB.StopLoss == nil {
-			orders, err := a.exApi.PlaceOrderV3(&exante.OrderSentTypeV3{
-				SymbolID:       orderDB.Order.Symbol,
-				Duration:       orderDB.Order.Duration,
-				OrderType:      "stop",
-				Quantity:       orderDB.Order.Quantity,
-				Side:           utils.GetReverseOrderSide(orderDB.Order.Side),
-				StopPrice:      utils.Convert5DecimalsOrNil(req.StopLoss),
-				IfDoneParentID: orderDB.Order.ID,
-				Instrument:     orderDB.Order.Symbol,
-				OcoGroup:       orderDB.OcoGroup,
-				AccountID:      orderDB.Order.AccountId,
-			})
-			if err == nil {
-				orderDB.StopLoss = utils.ConvertExOrderToDB(orders[0])
-			}
+	return c.JSON(http.StatusOK, rolled)
+}
 
-		} else {
-			_, err := a.exApi.ReplaceOrder(orderDB.StopLoss.ID, exante.ReplaceOrderPayload{
-				Action: "replace",
-				Parameters: exante.ReplaceOrderParameters{
-					Quantity:  orderDB.Order.Quantity,
-					StopPrice: utils.Convert5Decimals(req.StopLoss),
-				},
-			})
-			if err != nil {
-				fmt.Println(fmt.Sprintf("error to replace order: %s", err.Error()))
-			}
-		}
+// v1TicketPattern is what clients may use as ticket, it becomes the exante client tag
+var v1TicketPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+type v1OrderRequest struct {
+	Ticket     string  `json:"ticket"`
+	Symbol     string  `json:"symbol"`
+	Side       string  `json:"side"`
+	OrderType  string  `json:"orderType"`
+	Volume     float64 `json:"volume"`
+	Price      float64 `json:"price"`
+	StopLoss   float64 `json:"stopLoss"`
+	TakeProfit float64 `json:"takeProfit"`
+}
+
+func (r v1OrderRequest) validate() error {
+	errs := make([]error, 0)
+	if len(r.Ticket) > 0 && !v1TicketPattern.MatchString(r.Ticket) {
+		errs = append(errs, fmt.Errorf("ticket must match %s", v1TicketPattern.String()))
 	}
+	if len(r.Symbol) == 0 {
+		errs = append(errs, fmt.Errorf("symbol is required"))
+	}
+	if !slices.Contains(controller.OrderSides, r.Side) {
+		errs = append(errs, fmt.Errorf("side must be one of %v", controller.OrderSides))
+	}
+	if !slices.Contains(controller.OrderTypes, r.OrderType) {
+		errs = append(errs, fmt.Errorf("orderType must be one of %v", controller.OrderTypes))
+	}
+	if r.Volume <= 0 {
+		errs = append(errs, fmt.Errorf("volume must be greater than zero"))
+	}
+	if r.OrderType == "limit" && r.Price <= 0 {
+		errs = append(errs, fmt.Errorf("price must be greater than zero for limit orders"))
+	}
+	if r.Price < 0 || r.StopLoss < 0 || r.TakeProfit < 0 {
+		errs = append(errs, fmt.Errorf("price, stopLoss and takeProfit must not be negative"))
+	}
+	return errors.Join(errs...)
+}
 
-	if req.TakeProfit > 0 {
-		if orderDB.TakeProfit == nil {
-			orders, err := a.exApi.PlaceOrderV3(&exante.OrderSentTypeV3{
-				SymbolID:       orderDB.Order.Symbol,
-				Duration:       orderDB.Order.Duration,
-				OrderType:      "limit",
-				Quantity:       orderDB.Order.Quantity,
-				Side:           utils.GetReverseOrderSide(orderDB.Order.Side),
-				LimitPrice:     utils.Convert5Decimals(req.TakeProfit),
-				Instrument:     orderDB.Order.Symbol,
-				OcoGroup:       orderDB.OcoGroup,
-				IfDoneParentID: orderDB.Order.ID,
-				AccountID:      orderDB.Order.AccountId,
-			})
-			if err == nil {
-				orderDB.TakeProfit = utils.ConvertExOrderToDB(orders[0])
-			}
-		} else {
-			_, _ = a.exApi.ReplaceOrder(orderDB.TakeProfit.ID, exante.ReplaceOrderPayload{
-				Action: "replace",
-				Parameters: exante.ReplaceOrderParameters{
-					Quantity:   orderDB.Order.Quantity,
-					LimitPrice: utils.Convert5Decimals(req.TakeProfit),
-				},
-			})
+// placeOrder place an order with the MT5 symbol and volume, ticket is
+// derived from the idempotency key or generated when empty
+func (a api) placeOrder(c echo.Context) error {
+	req := new(v1OrderRequest)
+	if err := bindV1(c, req); err != nil {
+		return v1Error(c, http.StatusBadRequest, "invalid_request", err)
+	}
+	if err := req.validate(); err != nil {
+		return v1Error(c, http.StatusBadRequest, "invalid_request", err)
+	}
+	if len(req.Ticket) == 0 {
+		req.Ticket = fmt.Sprintf("api-%s", uuid.NewString())
+		if key := c.Request().Header.Get(idempotencyHeader); len(key) > 0 {
+			req.Ticket = fmt.Sprintf("api-%s", idempotency.Fingerprint("", "", []byte(key))[:32])
 		}
 	}
 
-	a.orderState.Upsert(orderID, orderDB)
-
-	return c.JSON(http.StatusOK, "ok")
+	group, err := a.controller.PlaceOrder(a.accountID, controller.OrderRequest{
+		Ticket:     req.Ticket,
+		Symbol:     req.Symbol,
+		Side:       req.Side,
+		OrderType:  req.OrderType,
+		Volume:     req.Volume,
+		Price:      req.Price,
+		StopLoss:   req.StopLoss,
+		TakeProfit: req.TakeProfit,
+	})
+	if err != nil {
+		return v1ControllerError(c, err)
+	}
 
+	a.journalAPI(req.Ticket, "PLACE")
+	return c.JSON(http.StatusCreated, group)
 }
 
-type cancelOrderRequest struct {
+type v1ModifyRequest struct {
+	Price      *float64 `json:"price"`
+	StopLoss   *float64 `json:"stopLoss"`
+	TakeProfit *float64 `json:"takeProfit"`
 }
 
-func (a api) cancelOrder(c echo.Context) error {
-	req := new(cancelOrderRequest)
-	if err := c.Bind(req); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+func (r v1ModifyRequest) validate() error {
+	if r.Price == nil && r.StopLoss == nil && r.TakeProfit == nil {
+		return fmt.Errorf("one of price, stopLoss or takeProfit is required")
 	}
+	if r.Price != nil && *r.Price <= 0 {
+		return fmt.Errorf("price must be greater than zero")
+	}
+	if r.StopLoss != nil && *r.StopLoss < 0 || r.TakeProfit != nil && *r.TakeProfit < 0 {
+		return fmt.Errorf("stopLoss and takeProfit must not be negative, zero cancels them")
+	}
+	return nil
+}
 
-	orderId := c.Param("orderID")
-
-	orderDB, hasOrder := a.orderState.Get(orderId)
-	if !hasOrder {
-		return c.JSON(http.StatusBadRequest, echo.Map{
-			"error": "no active order found",
-		})
+func (a api) modifyOrder(c echo.Context) error {
+	req := new(v1ModifyRequest)
+	if err := bindV1(c, req); err != nil {
+		return v1Error(c, http.StatusBadRequest, "invalid_request", err)
+	}
+	if err := req.validate(); err != nil {
+		return v1Error(c, http.StatusBadRequest, "invalid_request", err)
 	}
 
-	err := a.exApi.CancelOrder(orderDB.Order.ID)
+	group, err := a.controller.ModifyOrder(a.accountID, c.Param("ticket"), controller.ModifyRequest{
+		Price:      req.Price,
+		StopLoss:   req.StopLoss,
+		TakeProfit: req.TakeProfit,
+	})
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, echo.Map{
-			"error": err.Error(),
-		})
+		return v1ControllerError(c, err)
 	}
 
-	a.orderState.Delete(orderId)
-
-	return c.JSON(http.StatusOK, "ok")
+	a.journalAPI(c.Param("ticket"), "MODIFY")
+	return c.JSON(http.StatusOK, group)
 }
 
-type closePositionRequest struct {
+func (a api) cancelOrder(c echo.Context) error {
+	group, err := a.controller.CancelOrder(a.accountID, c.Param("ticket"))
+	if err != nil {
+		return v1ControllerError(c, err)
+	}
+
+	a.journalAPI(c.Param("ticket"), "CANCEL")
+	return c.JSON(http.StatusOK, group)
 }
 
 func (a api) closePosition(c echo.Context) error {
-	req := new(closePositionRequest)
-	if err := c.Bind(req); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	group, err := a.controller.ClosePosition(a.accountID, c.Param("ticket"))
+	if err != nil {
+		return v1ControllerError(c, err)
 	}
 
-	orderId := c.Param("orderID")
+	a.journalAPI(c.Param("ticket"), "CLOSE")
+	return c.JSON(http.StatusOK, group)
+}
 
-	orderDB, hasOrder := a.orderState.Get(orderId)
-	if !hasOrder {
-		return c.JSON(http.StatusBadRequest, echo.Map{
-			"error": "no active order found",
-		})
+func (a api) getOrder(c echo.Context) error {
+	group, orders, err := a.controller.TicketOrders(a.accountID, c.Param("ticket"))
+	if err != nil {
+		return v1ControllerError(c, err)
 	}
 
-	_, err := a.exApi.PlaceOrderV3(&exante.OrderSentTypeV3{
-		AccountID:  orderDB.Order.AccountId,
-		Instrument: orderDB.Order.Symbol,
-		Side:       utils.GetReverseOrderSide(orderDB.Order.Side),
-		Quantity:   orderDB.Order.Quantity,
-		OcoGroup:   orderDB.OcoGroup,
-		Duration:   "good_till_cancel",
-		OrderType:  "market",
-		SymbolID:   orderDB.Order.Symbol,
+	return c.JSON(http.StatusOK, echo.Map{
+		"group":  group,
+		"orders": orders,
 	})
+}
+
+// journalAPI write an action done through /v1 to the journal
+func (a api) journalAPI(ticket string, action string) {
+	err := a.journal.Add(fmt.Sprintf("[%s] API > %s", ticket, action))
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, echo.Map{
-			"error": err.Error(),
-		})
+		fmt.Println("error writing journal: ", err.Error())
 	}
+}
 
-	a.orderState.Delete(orderId)
+// bindV1 decode the json body into v, unknown fields are rejected
+func bindV1(c echo.Context, v any) error {
+	dec := json.NewDecoder(c.Request().Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("invalid body: %s", err.Error())
+	}
+	return nil
+}
 
-	return c.JSON(http.StatusOK, "ok")
+// v1Error is the error body of every /v1 route: the message on error and
+// a stable code on code
+func v1Error(c echo.Context, status int, code string, err error) error {
+	return c.JSON(status, echo.Map{
+		"error": err.Error(),
+		"code":  code,
+	})
+}
+
+// v1ControllerError map the controller and exante errors to their status
+func v1ControllerError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, controller.ErrInvalidOrder):
+		return v1Error(c, http.StatusUnprocessableEntity, "invalid_order", err)
+	case errors.Is(err, controller.ErrTicketNotFound):
+		return v1Error(c, http.StatusNotFound, "not_found", err)
+	case errors.Is(err, controller.ErrTicketConflict):
+		return v1Error(c, http.StatusConflict, "conflict", err)
+	case exante.IsCircuitOpen(err):
+		// nothing was sent, the request can run again with its key
+		c.Set(idempotencyRetry, true)
+		return v1Error(c, http.StatusServiceUnavailable, "exante_unavailable", err)
+	case exante.IsTemporary(err):
+		return v1Error(c, http.StatusServiceUnavailable, "exante_unavailable", err)
+	}
+	return v1Error(c, http.StatusBadGateway, "exante_error", err)
+}
+
+// bodyRecorder keep a copy of the response written by a handler
+type bodyRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *bodyRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// idempotent replay the first response of a request sent again with the
+// same Idempotency-Key. Errors are kept too, since exante may have placed
+// the order before failing, unless the handler knows nothing was sent or
+// it panicked.
+func (a api) idempotent(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		key := c.Request().Header.Get(idempotencyHeader)
+		if len(key) == 0 || c.Request().Method == http.MethodGet {
+			return next(c)
+		}
+		if len(key) > 255 {
+			return v1Error(c, http.StatusBadRequest, "invalid_request", fmt.Errorf("%s must have at most 255 characters", idempotencyHeader))
+		}
+
+		body, err := io.ReadAll(c.Request().Body)
+		if err != nil {
+			return v1Error(c, http.StatusBadRequest, "invalid_request", err)
+		}
+		c.Request().Body = io.NopCloser(bytes.NewReader(body))
+
+		fingerprint := idempotency.Fingerprint(c.Request().Method, c.Request().URL.Path, body)
+		entry, done, err := a.idempotency.Begin(key, fingerprint, time.Now())
+		switch {
+		case errors.Is(err, idempotency.ErrMismatch):
+			return v1Error(c, http.StatusUnprocessableEntity, "idempotency_mismatch", err)
+		case errors.Is(err, idempotency.ErrInFlight):
+			return v1Error(c, http.StatusConflict, "idempotency_in_flight", err)
+		case done:
+			c.Response().Header().Set("Idempotent-Replayed", "true")
+			return c.JSONBlob(entry.Status, entry.Body)
+		}
+
+		finished := false
+		defer func() {
+			if !finished {
+				a.idempotency.Release(key)
+			}
+		}()
+
+		recorder := &bodyRecorder{ResponseWriter: c.Response().Writer}
+		c.Response().Writer = recorder
+		if err = next(c); err != nil {
+			c.Error(err)
+		}
+
+		if retry, _ := c.Get(idempotencyRetry).(bool); retry {
+			return nil
+		}
+		finished = true
+		if err = a.idempotency.Finish(key, c.Response().Status, recorder.body.Bytes(), time.Now()); err != nil {
+			fmt.Println("error saving idempotency keys: ", err.Error())
+		}
+		return nil
+	}
 }
 
 func (a api) sync(c echo.Context) error {
@@ -369,41 +771,145 @@ func (a api) sync(c echo.Context) error {
 		fmt.Println("error processing sync: ", err.Error())
 	}
 
+	err = a.controller.SaveHistory(a.historyPath)
+	if err != nil {
+		fmt.Println("error saving history: ", err.Error())
+	}
+	err = a.journal.Add(res.JournalF)
+	if err != nil {
+		fmt.Println("error writing journal: ", err.Error())
+	}
+
 	return c.JSON(http.StatusOK, res)
 }
 
-func (a api) getOrder(c echo.Context) error {
-	orderDB, hasOrder := a.orderState.Get(c.Param("orderID"))
-	if !hasOrder {
+func (a api) getOrders(c echo.Context) error {
+	orders, err := a.exApi.GetOrdersByLimitV3(5, a.accountID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, orders)
+}
+
+func (a api) getJournal(c echo.Context) error {
+	entries, err := a.journal.Recent(journalLines)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"error": err.Error(),
+		})
+	}
+
+	lines := make([]string, 0, len(entries))
+	for _, e := range entries {
+		lines = append(lines, fmt.Sprintf("%s %s", e.Time.Format(time.RFC822Z), e.Text))
+	}
+	return c.JSON(http.StatusOK, controller.SyncResponse{
+		JournalF: strings.Join(lines, "\n"),
+	})
+}
+
+// symbolID return the exante symbol from query, it accepts
+// the exante `symbolId` or the metaTrader `symbol`
+func (a api) symbolID(c echo.Context) (string, bool) {
+	if symbolID := c.QueryParam("symbolId"); len(symbolID) > 0 {
+		return symbolID, true
+	}
+
+	exchange, has := a.exchangeApi.GetByMTValue(c.QueryParam("symbol"))
+	if !has {
+		return "", false
+	}
+	return exchange.Exante, true
+}
+
+type fakePriceRequest struct {
+	SymbolID string  `json:"symbolId"`
+	Price    float64 `json:"price"`
+}
+
+// setFakePrice move the fake server price, filling crossed orders
+func (a api) setFakePrice(c echo.Context) error {
+	req := new(fakePriceRequest)
+	if err := c.Bind(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	a.fakeServer.SetPrice(req.SymbolID, req.Price)
+	return c.JSON(http.StatusOK, "ok")
+}
+
+func (a api) getQuote(c echo.Context) error {
+	symbolID, has := a.symbolID(c)
+	if !has {
 		return c.JSON(http.StatusBadRequest, echo.Map{
-			"error": "no active order found",
+			"error": "symbol not found",
 		})
 	}
 
-	order, err := a.exApi.GetOrder(orderDB.Order.ID)
+	quote, err := a.exApi.GetLastQuote(symbolID)
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, echo.Map{
 			"error": err.Error(),
 		})
 	}
 
-	return c.JSON(http.StatusOK, order)
+	return c.JSON(http.StatusOK, quote)
 }
 
-func (a api) getOrders(c echo.Context) error {
-	orders, err := a.exApi.GetOrdersByLimitV3(5, a.accountID)
+func (a api) streamQuotes(c echo.Context) error {
+	symbolID, has := a.symbolID(c)
+	if !has {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"error": "symbol not found",
+		})
+	}
+
+	quotes, err := a.exApi.StreamQuotes(c.Request().Context(), symbolID)
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, echo.Map{
 			"error": err.Error(),
 		})
 	}
 
-	return c.JSON(http.StatusOK, orders)
+	c.Response().Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	c.Response().WriteHeader(http.StatusOK)
+
+	enc := json.NewEncoder(c.Response())
+	for quote := range quotes {
+		if err := enc.Encode(quote); err != nil {
+			return err
+		}
+		c.Response().Flush()
+	}
+	return nil
 }
 
-func (a api) journal(c echo.Context) error {
+func (a api) getOHLC(c echo.Context) error {
+	symbolID, has := a.symbolID(c)
+	if !has {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"error": "symbol not found",
+		})
+	}
 
-	return c.JSON(http.StatusOK, controller.SyncResponse{
-		JournalF: "dadadda\nnnndad\ndada",
-	})
+	duration, err := strconv.Atoi(c.QueryParam("duration"))
+	if err != nil {
+		duration = 60
+	}
+	size, err := strconv.Atoi(c.QueryParam("size"))
+	if err != nil {
+		size = 60
+	}
+
+	candles, err := a.exApi.GetOHLC(symbolID, duration, size)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, candles)
 }
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/danielsussa/mt5-to-exante/internal/exante"
+	"github.com/danielsussa/mt5-to-exante/internal/idempotency"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotent(t *testing.T) {
+	send := func(e *echo.Echo, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/orders", strings.NewReader(`{"symbol":"EURUSD"}`))
+		req.Header.Set(idempotencyHeader, key)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("an order failing after it may have reached exante should be replayed", func(t *testing.T) {
+		h := api{idempotency: idempotency.NewNoDisk(time.Hour)}
+		calls := 0
+		e := echo.New()
+		e.POST("/v1/orders", func(c echo.Context) error {
+			calls++
+			return v1ControllerError(c, exante.ErrInternalServer)
+		}, h.idempotent)
+
+		assert.Equal(t, http.StatusServiceUnavailable, send(e, "k1").Code)
+		rec := send(e, "k1")
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Equal(t, "true", rec.Header().Get("Idempotent-Replayed"))
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("an order not sent because the circuit is open should run again", func(t *testing.T) {
+		h := api{idempotency: idempotency.NewNoDisk(time.Hour)}
+		calls := 0
+		e := echo.New()
+		e.POST("/v1/orders", func(c echo.Context) error {
+			calls++
+			if calls == 1 {
+				return v1ControllerError(c, exante.CircuitOpenError{})
+			}
+			return c.JSON(http.StatusCreated, echo.Map{"ticket": "1"})
+		}, h.idempotent)
+
+		assert.Equal(t, http.StatusServiceUnavailable, send(e, "k1").Code)
+		assert.Equal(t, http.StatusCreated, send(e, "k1").Code)
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("a panic should release the key", func(t *testing.T) {
+		h := api{idempotency: idempotency.NewNoDisk(time.Hour)}
+		handler := h.idempotent(func(c echo.Context) error {
+			panic("boom")
+		})
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/v1/orders", strings.NewReader(`{}`))
+		req.Header.Set(idempotencyHeader, "k1")
+		c := e.NewContext(req, httptest.NewRecorder())
+		assert.Panics(t, func() { _ = handler(c) })
+
+		_, _, err := h.idempotency.Begin("k1", idempotency.Fingerprint(http.MethodPost, "/v1/orders", []byte(`{}`)), time.Now())
+		assert.NoError(t, err)
+	})
+}
+
+func TestHealth(t *testing.T) {
+	t.Run("status should match the breaker state", func(t *testing.T) {
+		tests := []struct {
+			state  exante.BreakerState
+			code   int
+			status string
+		}{
+			{exante.BreakerClosed, http.StatusOK, "ok"},
+			{exante.BreakerHalfOpen, http.StatusOK, "degraded"},
+			{exante.BreakerOpen, http.StatusServiceUnavailable, "unavailable"},
+		}
+		for _, tt := range tests {
+			code, status := healthStatus(tt.state)
+			assert.Equal(t, tt.code, code, tt.state)
+			assert.Equal(t, tt.status, status, tt.state)
+		}
+	})
+
+	t.Run("handler should report the breaker", func(t *testing.T) {
+		fake := exante.NewFakeServer()
+		defer fake.Close()
+		h := api{exApi: fake.NewApi()}
+
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/health", nil), rec)
+		assert.NoError(t, h.health(c))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"status":"ok","breaker":"closed"}`, rec.Body.String())
+	})
+}
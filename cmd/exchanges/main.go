@@ -0,0 +1,108 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/danielsussa/mt5-to-exante/internal/exante"
+	"github.com/danielsussa/mt5-to-exante/internal/exchanges"
+	"github.com/joho/godotenv"
+)
+
+// suggest exante symbols for a list of MT5 symbols and write a draft
+// exchanges.yaml to review before use
+//
+//	exchanges production draft -in symbols.csv -suffix .m,pro -out exchanges.draft.yaml
+func main() {
+	if len(os.Args) < 3 {
+		fmt.Println("usage: exchanges <env> draft -in FILE [-suffix .m,pro] [-out FILE]")
+		os.Exit(1)
+	}
+
+	ex, _ := os.Executable()
+	exPath := filepath.Dir(ex)
+
+	err := godotenv.Load(fmt.Sprintf("%s/%s.env", exPath, os.Args[1]))
+	if err != nil {
+		panic("cannot locate environment file")
+	}
+
+	switch os.Args[2] {
+	case "draft":
+		draft(os.Args[3:])
+	default:
+		fmt.Println(fmt.Sprintf("unknown command %s", os.Args[2]))
+		os.Exit(1)
+	}
+}
+
+func draft(args []string) {
+	flags := flag.NewFlagSet("draft", flag.ExitOnError)
+	in := flags.String("in", "", "MT5 symbols, one per line or a Market Watch csv export")
+	suffix := flags.String("suffix", "", "comma separated suffixes the broker add to symbols")
+	out := flags.String("out", "exchanges.draft.yaml", "output file")
+	_ = flags.Parse(args)
+
+	f, err := os.Open(*in)
+	if err != nil {
+		panic(err)
+	}
+	mt5Symbols, err := exchanges.ParseMt5Symbols(f)
+	_ = f.Close()
+	if err != nil {
+		panic(err)
+	}
+
+	suffixes := make([]string, 0)
+	if len(*suffix) > 0 {
+		suffixes = strings.Split(*suffix, ",")
+	}
+
+	exanteApi := exante.NewApi(
+		os.Getenv("BASE_URL"),
+		os.Getenv("APPLICATION_ID"),
+		os.Getenv("CLIENT_ID"),
+		os.Getenv("SHARED_KEY"),
+	)
+
+	symbols, err := exanteApi.GetSymbols()
+	if err != nil {
+		panic(err)
+	}
+
+	entries := exchanges.Suggest(mt5Symbols, symbols, suffixes)
+	missing := 0
+	for idx, entry := range entries {
+		if entry.Symbol == nil {
+			missing++
+			continue
+		}
+		instrument, err := exanteApi.GetInstrument(entry.Symbol.SymbolID)
+		if err != nil {
+			fmt.Println(fmt.Sprintf("%s: cannot read %s metadata: %s", entry.Mt5.Name, entry.Symbol.SymbolID, err.Error()))
+			continue
+		}
+		entries[idx].Instrument = instrument
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "-" {
+		f, err := os.Create(*out)
+		if err != nil {
+			panic(err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	err = exchanges.WriteDraft(w, entries, suffixes)
+	if err != nil {
+		panic(err)
+	}
+	// stdout may be the draft itself
+	fmt.Fprintln(os.Stderr, fmt.Sprintf("%d symbols, %d without exante symbol (%s), written to %s", len(entries), missing, exchanges.Placeholder, *out))
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/danielsussa/mt5-to-exante/internal/state"
+	"github.com/joho/godotenv"
+)
+
+// export and import the bridge state to move the SDK to another machine,
+// the SDK must be stopped on both sides
+//
+//	state production export -out state.tar.gz
+//	state production import -in state.tar.gz
+func main() {
+	if len(os.Args) < 3 {
+		fmt.Println("usage: state <env> export [-out FILE] | import -in FILE [-force]")
+		os.Exit(1)
+	}
+
+	ex, _ := os.Executable()
+	exPath := filepath.Dir(ex)
+
+	err := godotenv.Load(fmt.Sprintf("%s/%s.env", exPath, os.Args[1]))
+	if err != nil {
+		panic("cannot locate environment file")
+	}
+
+	dir := state.Dir{
+		Path:           exPath,
+		OrderdbBackend: os.Getenv("ORDERDB_BACKEND"),
+	}
+
+	switch os.Args[2] {
+	case "export":
+		flags := flag.NewFlagSet("export", flag.ExitOnError)
+		out := flags.String("out", fmt.Sprintf("state-%s.tar.gz", os.Args[1]), "archive file")
+		_ = flags.Parse(os.Args[3:])
+
+		f, err := os.Create(*out)
+		if err != nil {
+			panic(err)
+		}
+		defer f.Close()
+
+		err = state.Export(f, dir)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Println(fmt.Sprintf("state exported to %s", *out))
+
+	case "import":
+		flags := flag.NewFlagSet("import", flag.ExitOnError)
+		in := flags.String("in", "", "archive file")
+		force := flags.Bool("force", false, "replace the current state")
+		_ = flags.Parse(os.Args[3:])
+
+		f, err := os.Open(*in)
+		if err != nil {
+			panic(err)
+		}
+		defer f.Close()
+
+		err = state.Import(f, dir, *force)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Println(fmt.Sprintf("state imported from %s", *in))
+
+	default:
+		fmt.Println(fmt.Sprintf("unknown command %s", os.Args[2]))
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/danielsussa/mt5-to-exante/internal/exante"
+	"github.com/joho/godotenv"
+)
+
+// export exante transactions to reconcile with MT5 statements
+//
+//	transactions production -format csv -type TRADE,COMMISSION -from 2024-01-01 -out trades.csv
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Println("usage: transactions <env> [-format csv|json] [-symbol SYMBOL] [-type TRADE,COMMISSION] [-from 2006-01-02] [-to 2006-01-02] [-out FILE]")
+		os.Exit(1)
+	}
+
+	ex, _ := os.Executable()
+	exPath := filepath.Dir(ex)
+
+	err := godotenv.Load(fmt.Sprintf("%s/%s.env", exPath, os.Args[1]))
+	if err != nil {
+		panic("cannot locate environment file")
+	}
+
+	flags := flag.NewFlagSet("transactions", flag.ExitOnError)
+	format := flags.String("format", "csv", "output format: csv or json")
+	symbol := flags.String("symbol", "", "exante symbol id")
+	operationType := flags.String("type", "", "comma separated operation types")
+	from := flags.String("from", "", "from date (2006-01-02)")
+	to := flags.String("to", "", "to date (2006-01-02), included")
+	out := flags.String("out", "", "output file, default stdout")
+	_ = flags.Parse(os.Args[2:])
+
+	filter := exante.TransactionsFilter{
+		SymbolID: *symbol,
+	}
+	if len(*operationType) > 0 {
+		filter.OperationTypes = strings.Split(*operationType, ",")
+	}
+	if filter.FromDate, err = parseDate(*from); err != nil {
+		panic(err)
+	}
+	if filter.ToDate, err = parseToDate(*to); err != nil {
+		panic(err)
+	}
+
+	exanteApi := exante.NewApi(
+		os.Getenv("BASE_URL"),
+		os.Getenv("APPLICATION_ID"),
+		os.Getenv("CLIENT_ID"),
+		os.Getenv("SHARED_KEY"),
+	)
+
+	// ACCOUNT_ID may be empty or an alias, as for the api
+	account, err := exanteApi.DiscoverAccount(os.Getenv("ACCOUNT_ID"), os.Getenv("ACCOUNT_ALIASES"))
+	if err != nil {
+		fmt.Println(fmt.Sprintf("cannot export transactions: %s", err.Error()))
+		os.Exit(1)
+	}
+	filter.AccountID = account.AccountID
+
+	transactions, err := exanteApi.GetAllTransactions(filter)
+	if err != nil {
+		panic(err)
+	}
+
+	var w io.Writer = os.Stdout
+	if len(*out) > 0 {
+		f, err := os.Create(*out)
+		if err != nil {
+			panic(err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	switch *format {
+	case "json":
+		err = writeJSON(w, transactions)
+	case "csv":
+		err = writeCSV(w, transactions)
+	default:
+		err = fmt.Errorf("unknown format %s", *format)
+	}
+	if err != nil {
+		panic(err)
+	}
+}
+
+func parseDate(s string) (time.Time, error) {
+	if len(s) == 0 {
+		return time.Time{}, nil
+	}
+	return time.Parse("2006-01-02", s)
+}
+
+// parseToDate return the end of the -to day, so its transactions are exported
+func parseToDate(s string) (time.Time, error) {
+	date, err := parseDate(s)
+	if err != nil || date.IsZero() {
+		return date, err
+	}
+	return date.AddDate(0, 0, 1), nil
+}
+
+func writeJSON(w io.Writer, transactions exante.Transactions) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(transactions)
+}
+
+func writeCSV(w io.Writer, transactions exante.Transactions) error {
+	cw := csv.NewWriter(w)
+	err := cw.Write([]string{
+		"id", "uuid", "parentUuid", "time", "accountId", "symbolId", "asset",
+		"amount", "operationType", "orderId", "orderPos", "valueDate", "comment",
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, t := range transactions {
+		err = cw.Write([]string{
+			fmt.Sprintf("%d", t.ID),
+			t.UUID,
+			t.ParentUUID,
+			time.UnixMilli(t.Timestamp).UTC().Format(time.RFC3339),
+			t.AccountID,
+			t.SymbolID,
+			t.Asset,
+			t.Amount,
+			t.OperationType,
+			t.OrderID,
+			fmt.Sprintf("%d", t.OrderPos),
+			t.ValueDate,
+			t.Comment,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
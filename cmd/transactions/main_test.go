@@ -0,0 +1,30 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseToDate(t *testing.T) {
+	t.Run("to date should include the whole day", func(t *testing.T) {
+		to, err := parseToDate("2026-01-31")
+		assert.NoError(t, err)
+		assert.Equal(t, time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC), to)
+
+		lastTrade := time.Date(2026, 1, 31, 23, 59, 59, 0, time.UTC)
+		assert.True(t, lastTrade.Before(to))
+	})
+
+	t.Run("empty to date should not filter", func(t *testing.T) {
+		to, err := parseToDate("")
+		assert.NoError(t, err)
+		assert.True(t, to.IsZero())
+	})
+
+	t.Run("invalid to date should fail", func(t *testing.T) {
+		_, err := parseToDate("31/01/2026")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/danielsussa/mt5-to-exante/internal/exante"
+	"github.com/danielsussa/mt5-to-exante/internal/exchanges"
+	"github.com/joho/godotenv"
+)
+
+// placeholders left in the sample env files
+var placeholders = []string{"INSERT_VALUE", "ID_HERE"}
+
+// check the env file and exchanges.yaml before starting the SDK, exit 1
+// when something must be fixed. Unless -offline, the exante account and
+// symbols are looked up on BASE_URL.
+//
+//	validate production
+//	validate production -offline
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Println("usage: validate <env> [-offline]")
+		os.Exit(1)
+	}
+
+	flags := flag.NewFlagSet("validate", flag.ExitOnError)
+	offline := flags.Bool("offline", false, "do not call exante")
+	_ = flags.Parse(os.Args[2:])
+
+	ex, _ := os.Executable()
+	exPath := filepath.Dir(ex)
+
+	envPath := fmt.Sprintf("%s/%s.env", exPath, os.Args[1])
+	env, err := godotenv.Read(envPath)
+	if err != nil {
+		fmt.Println(fmt.Sprintf("ERROR %s: cannot read environment file: %s", envPath, err.Error()))
+		os.Exit(1)
+	}
+
+	r := &report{}
+	checkEnv(r, env)
+
+	var data exchanges.Data
+	if len(env["EXCHANGE_PATH"]) > 0 {
+		data = checkExchanges(r, fmt.Sprintf("%s/%s", exPath, env["EXCHANGE_PATH"]))
+	}
+
+	switch {
+	case *offline:
+		r.info("exante checks skipped, -offline")
+	case env["BASE_URL"] == "fake":
+		r.info("exante checks skipped, the fake server starts without symbols")
+	case r.hasErrors():
+		r.info("exante checks skipped, fix the errors above first")
+	default:
+		checkExante(r, env, data)
+	}
+
+	r.print()
+	if r.hasErrors() {
+		os.Exit(1)
+	}
+}
+
+type report struct {
+	errors   []string
+	warnings []string
+	infos    []string
+}
+
+func (r *report) error(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *report) warn(format string, args ...any) {
+	r.warnings = append(r.warnings, fmt.Sprintf(format, args...))
+}
+
+func (r *report) info(format string, args ...any) {
+	r.infos = append(r.infos, fmt.Sprintf(format, args...))
+}
+
+func (r *report) hasErrors() bool {
+	return len(r.errors) > 0
+}
+
+func (r *report) print() {
+	for _, line := range r.errors {
+		fmt.Println(fmt.Sprintf("ERROR %s", line))
+	}
+	for _, line := range r.warnings {
+		fmt.Println(fmt.Sprintf("WARN  %s", line))
+	}
+	for _, line := range r.infos {
+		fmt.Println(fmt.Sprintf("INFO  %s", line))
+	}
+	fmt.Println(fmt.Sprintf("%d errors, %d warnings", len(r.errors), len(r.warnings)))
+}
+
+// checkEnv report missing and placeholder values, credentials are not
+// needed by the fake server and an empty ACCOUNT_ID selects the only account
+func checkEnv(r *report, env map[string]string) {
+	required := []string{"BASE_URL", "EXCHANGE_PATH"}
+	if env["BASE_URL"] != "fake" {
+		required = append(required, "APPLICATION_ID", "CLIENT_ID", "SHARED_KEY")
+	}
+
+	for _, key := range required {
+		value := strings.TrimSpace(env[key])
+		switch {
+		case len(value) == 0:
+			r.error("%s is missing", key)
+		case isPlaceholder(value):
+			r.error("%s is still %s", key, value)
+		}
+	}
+
+	accountID := strings.TrimSpace(env["ACCOUNT_ID"])
+	switch {
+	case len(accountID) == 0:
+		r.info("ACCOUNT_ID is empty, the only tradable account is used")
+	case isPlaceholder(accountID):
+		r.warn("ACCOUNT_ID is still %s, the only tradable account is used", accountID)
+	}
+	if _, err := exante.ParseAccountAliases(env["ACCOUNT_ALIASES"]); err != nil {
+		r.error("ACCOUNT_ALIASES: %s", err.Error())
+	}
+}
+
+func isPlaceholder(value string) bool {
+	for _, placeholder := range placeholders {
+		if value == placeholder {
+			return true
+		}
+	}
+	return false
+}
+
+func checkExchanges(r *report, path string) exchanges.Data {
+	data, err := exchanges.Load(path)
+	if err != nil {
+		// Load join one error per problem
+		var joined interface{ Unwrap() []error }
+		if errors.As(err, &joined) {
+			for _, e := range joined.Unwrap() {
+				r.error("%s: %s", filepath.Base(path), e.Error())
+			}
+		} else {
+			r.error("%s: %s", filepath.Base(path), err.Error())
+		}
+		return data
+	}
+
+	for _, warning := range data.Warnings(time.Now()) {
+		r.warn("%s: %s", filepath.Base(path), warning)
+	}
+	r.info("%s: %d exchanges", filepath.Base(path), len(data.Exchanges))
+	return data
+}
+
+// checkExante look up the account and every exante symbol on BASE_URL
+func checkExante(r *report, env map[string]string, data exchanges.Data) {
+	exanteApi := exante.NewApi(env["BASE_URL"], env["APPLICATION_ID"], env["CLIENT_ID"], env["SHARED_KEY"])
+
+	accounts, err := exanteApi.GetUserAccounts()
+	if err != nil {
+		r.error("cannot list exante accounts on %s: %s", env["BASE_URL"], err.Error())
+		return
+	}
+	if accounts == nil {
+		accounts = &exante.UserAccounts{}
+	}
+	aliases, _ := exante.ParseAccountAliases(env["ACCOUNT_ALIASES"])
+	account, err := exante.SelectAccount(*accounts, env["ACCOUNT_ID"], aliases)
+	if err != nil {
+		r.error("ACCOUNT_ID: %s", err.Error())
+	} else {
+		r.info("account %s", account.AccountID)
+	}
+
+	for _, symbolID := range data.ExanteSymbols() {
+		_, err := exanteApi.GetSymbol(symbolID)
+		switch {
+		case errors.Is(err, exante.ErrInternalServer):
+			r.warn("cannot check exante symbol %s: %s", symbolID, err.Error())
+		case err != nil:
+			r.error("exante symbol %s: %s", symbolID, err.Error())
+		}
+	}
+}
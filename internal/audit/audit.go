@@ -0,0 +1,218 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Entry is a single mutating call sent to exante
+type Entry struct {
+	Time         time.Time `json:"time"`
+	Ticket       string    `json:"ticket,omitempty"`
+	Operation    string    `json:"operation"`
+	Method       string    `json:"method"`
+	URL          string    `json:"url"`
+	RequestBody  string    `json:"requestBody,omitempty"`
+	ResponseBody string    `json:"responseBody,omitempty"`
+	StatusCode   int       `json:"statusCode"`
+	LatencyMs    int64     `json:"latencyMs"`
+	Error        string    `json:"error,omitempty"`
+}
+
+const (
+	OperationPlace   = "place"
+	OperationReplace = "replace"
+	OperationCancel  = "cancel"
+
+	defaultMaxSize = 10 * 1024 * 1024
+	filePrefix     = "audit-"
+	fileExt        = ".log"
+)
+
+// Log is an append only audit log stored as json lines, a new file
+// is started every day and when the current one reaches MaxSize.
+// Rotated files are never deleted.
+type Log struct {
+	dir     string
+	MaxSize int64
+
+	mu   sync.Mutex
+	file *os.File
+	name string
+	size int64
+}
+
+func New(dir string) (*Log, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create audit folder: %s", err.Error())
+	}
+	return &Log{dir: dir, MaxSize: defaultMaxSize}, nil
+}
+
+func (l *Log) Append(e Entry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	err = l.rotate(e.Time, int64(len(b)))
+	if err != nil {
+		return err
+	}
+
+	n, err := l.file.Write(b)
+	l.size += int64(n)
+	if err != nil {
+		return err
+	}
+	return l.file.Sync()
+}
+
+// rotate must be called with l.mu locked
+func (l *Log) rotate(t time.Time, next int64) error {
+	day := t.Format("20060102")
+	if l.file != nil && strings.HasPrefix(l.name, filePrefix+day) && l.size+next <= l.MaxSize {
+		return nil
+	}
+
+	if l.file != nil {
+		_ = l.file.Close()
+		l.file = nil
+	}
+
+	for part := 0; ; part++ {
+		name := fmt.Sprintf("%s%s-%03d%s", filePrefix, day, part, fileExt)
+		info, err := os.Stat(filepath.Join(l.dir, name))
+		if err == nil && info.Size()+next > l.MaxSize {
+			continue
+		}
+
+		f, err := os.OpenFile(filepath.Join(l.dir, name), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		l.file = f
+		l.name = name
+		l.size = 0
+		if info != nil {
+			l.size = info.Size()
+		}
+		return nil
+	}
+}
+
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// Filter of Query, empty values match everything
+type Filter struct {
+	Ticket    string
+	Operation string
+	From      time.Time
+	To        time.Time
+	Limit     int
+}
+
+func (f Filter) match(e Entry) bool {
+	if len(f.Ticket) > 0 && e.Ticket != f.Ticket {
+		return false
+	}
+	if len(f.Operation) > 0 && e.Operation != f.Operation {
+		return false
+	}
+	if !f.From.IsZero() && e.Time.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && e.Time.After(f.To) {
+		return false
+	}
+	return true
+}
+
+// Query read the audit files and return entries matching filter, oldest
+// first. Files are read without the lock, calls are audited meanwhile.
+func (l *Log) Query(filter Filter) ([]Entry, error) {
+	l.mu.Lock()
+	names, err := filepath.Glob(filepath.Join(l.dir, filePrefix+"*"+fileExt))
+	l.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	entries := make([]Entry, 0)
+	for _, name := range names {
+		day, ok := fileDay(name)
+		if !ok {
+			continue
+		}
+		// skip files of days out of the range
+		if (!filter.From.IsZero() && day.AddDate(0, 0, 1).Before(filter.From)) || (!filter.To.IsZero() && day.After(filter.To)) {
+			continue
+		}
+
+		fileEntries, err := readEntries(name, filter)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, fileEntries...)
+	}
+
+	if filter.Limit > 0 && len(entries) > filter.Limit {
+		entries = entries[len(entries)-filter.Limit:]
+	}
+	return entries, nil
+}
+
+// fileDay return the day of an audit file, false for other files
+func fileDay(name string) (time.Time, bool) {
+	rest := strings.TrimPrefix(filepath.Base(name), filePrefix)
+	if len(rest) < 8 {
+		return time.Time{}, false
+	}
+	day, err := time.Parse("20060102", rest[:8])
+	return day, err == nil
+}
+
+func readEntries(name string, filter Filter) ([]Entry, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	entries := make([]Entry, 0)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		if filter.match(e) {
+			entries = append(entries, e)
+		}
+	}
+	return entries, scanner.Err()
+}
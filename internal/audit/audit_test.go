@@ -0,0 +1,80 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLog(t *testing.T) {
+	day := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	t.Run("entries should be queried by ticket, operation and time", func(t *testing.T) {
+		l, err := New(t.TempDir())
+		assert.NoError(t, err)
+		defer l.Close()
+
+		assert.NoError(t, l.Append(Entry{Time: day, Ticket: "1", Operation: OperationPlace}))
+		assert.NoError(t, l.Append(Entry{Time: day.Add(time.Hour), Ticket: "1", Operation: OperationCancel}))
+		assert.NoError(t, l.Append(Entry{Time: day.AddDate(0, 0, 1), Ticket: "2", Operation: OperationPlace}))
+
+		entries, err := l.Query(Filter{Ticket: "1"})
+		assert.NoError(t, err)
+		assert.Len(t, entries, 2)
+
+		entries, err = l.Query(Filter{Operation: OperationPlace})
+		assert.NoError(t, err)
+		assert.Len(t, entries, 2)
+
+		entries, err = l.Query(Filter{From: day.AddDate(0, 0, 1)})
+		assert.NoError(t, err)
+		assert.Len(t, entries, 1)
+		assert.Equal(t, "2", entries[0].Ticket)
+
+		entries, err = l.Query(Filter{Limit: 1})
+		assert.NoError(t, err)
+		assert.Len(t, entries, 1)
+		assert.Equal(t, "2", entries[0].Ticket, "limit keeps the newest")
+	})
+
+	t.Run("files should rotate by day and size", func(t *testing.T) {
+		dir := t.TempDir()
+		l, err := New(dir)
+		assert.NoError(t, err)
+		defer l.Close()
+		l.MaxSize = 100
+
+		assert.NoError(t, l.Append(Entry{Time: day, Ticket: "1", Operation: OperationPlace}))
+		assert.NoError(t, l.Append(Entry{Time: day, Ticket: "2", Operation: OperationPlace}))
+		assert.NoError(t, l.Append(Entry{Time: day.AddDate(0, 0, 1), Ticket: "3", Operation: OperationPlace}))
+
+		names, _ := filepath.Glob(filepath.Join(dir, "*.log"))
+		assert.Equal(t, []string{
+			filepath.Join(dir, "audit-20260310-000.log"),
+			filepath.Join(dir, "audit-20260310-001.log"),
+			filepath.Join(dir, "audit-20260311-000.log"),
+		}, names)
+
+		entries, err := l.Query(Filter{})
+		assert.NoError(t, err)
+		assert.Len(t, entries, 3)
+	})
+
+	t.Run("stray files should be skipped", func(t *testing.T) {
+		dir := t.TempDir()
+		l, err := New(dir)
+		assert.NoError(t, err)
+		defer l.Close()
+
+		assert.NoError(t, l.Append(Entry{Time: day, Ticket: "1", Operation: OperationPlace}))
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "audit-x.log"), []byte(`{"ticket":"2"}`), 0644))
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "audit-notadate.log"), []byte(`{"ticket":"3"}`), 0644))
+
+		entries, err := l.Query(Filter{})
+		assert.NoError(t, err)
+		assert.Len(t, entries, 1)
+	})
+}
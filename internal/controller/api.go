@@ -1,33 +1,64 @@
 package controller
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/danielsussa/mt5-to-exante/internal/exante"
 	"github.com/danielsussa/mt5-to-exante/internal/exchanges"
+	"github.com/danielsussa/mt5-to-exante/internal/orderdb"
+	"github.com/danielsussa/mt5-to-exante/internal/queue"
 	"github.com/danielsussa/mt5-to-exante/internal/utils"
 	"slices"
 	"strings"
+	"sync"
 	"time"
 )
 
+const defaultMarketOrderMaxAge = 60 * time.Second
+
 type Api struct {
 	exanteApi exante.Iface
-	exchange  exchanges.Api
-
-	history map[string]string
+	db        orderdb.Iface
+	exchange  *exchanges.Api
+	pending   *queue.Queue
+
+	// MarketOrderMaxAge is the max age of a queued market order,
+	// older ones are dead lettered instead of being sent
+	MarketOrderMaxAge time.Duration
+	// Retention of the groups done on exante before they are archived
+	Retention time.Duration
+	// HistoryIdle is how long MT5 must stop reporting a request
+	// before it can be forgotten
+	HistoryIdle time.Duration
+
+	// mu serialize what changes exante orders and orderdb: Sync, Roll,
+	// Compact and the v1 api, so two of them never act on the same ticket
+	mu sync.Mutex
+
+	historyMu sync.Mutex
+	history   map[string]historyEntry
 }
 
-func New(exanteApi exante.Iface, exchange exchanges.Api) *Api {
+func New(exanteApi exante.Iface, db orderdb.Iface, exchange *exchanges.Api, pending *queue.Queue) *Api {
 	return &Api{
-		exanteApi: exanteApi,
-		exchange:  exchange,
-		history:   make(map[string]string),
+		exanteApi:         exanteApi,
+		db:                db,
+		exchange:          exchange,
+		pending:           pending,
+		MarketOrderMaxAge: defaultMarketOrderMaxAge,
+		Retention:         defaultRetention,
+		HistoryIdle:       defaultHistoryIdle,
+		history:           make(map[string]historyEntry),
 	}
 }
 
 type (
 	Mt5Requests interface {
 		WithTicket() string
+		// GroupTicket is the orderdb ticket of the request
+		GroupTicket() string
 	}
 
 	SyncRequest struct {
@@ -102,6 +133,14 @@ func (m Mt5PositionHistory) WithTicket() string {
 	return fmt.Sprintf("position-history-%s", m.Ticket)
 }
 
+func (m Mt5Position) GroupTicket() string {
+	return m.PositionTicket
+}
+
+func (m Mt5PositionHistory) GroupTicket() string {
+	return m.PositionTicket
+}
+
 func (sr *SyncResponse) AddJournal(txt string) {
 	if len(sr.JournalF) == 0 {
 		sr.JournalF += txt
@@ -130,6 +169,14 @@ func (m Mt5OrderHistory) WithTicket() string {
 	return fmt.Sprintf("order-history-%s", m.Ticket)
 }
 
+func (m Mt5Order) GroupTicket() string {
+	return m.Ticket
+}
+
+func (m Mt5OrderHistory) GroupTicket() string {
+	return m.Ticket
+}
+
 const (
 	OrderStatePlaced    OrderState = "ORDER_STATE_PLACED"
 	OrderStateFilled    OrderState = "ORDER_STATE_FILLED"
@@ -156,8 +203,20 @@ func (t OrderType) IsLimit() bool {
 }
 
 func (a *Api) Sync(accountID string, req SyncRequest) (SyncResponse, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
 	res := SyncResponse{}
 
+	// while exante is degraded nothing is sent, requests are not appended
+	// to history so they are processed again once the breaker closes
+	if a.exanteApi.CircuitOpen() {
+		res.AddJournal("EXANTE UNAVAILABLE > SKIP SYNC")
+		return res, nil
+	}
+
+	a.processPending(&res)
+
 	// recent position history are responsible for
 	// 1. open a position only if its come from a MARKET order
 	// 2. close a position only if its come from a MARKET order
@@ -165,6 +224,9 @@ func (a *Api) Sync(accountID string, req SyncRequest) (SyncResponse, error) {
 		if !a.isNewRequest(currentMT5OldPosition) {
 			continue
 		}
+		if err := a.recordSnapshot(currentMT5OldPosition.PositionTicket, currentMT5OldPosition); err != nil {
+			return res, err
+		}
 
 		orderIdx := slices.IndexFunc(req.RecentInactiveOrders, func(order Mt5Order) bool {
 			return order.Ticket == currentMT5OldPosition.Ticket
@@ -191,9 +253,18 @@ func (a *Api) Sync(accountID string, req SyncRequest) (SyncResponse, error) {
 
 			_, err = a.placeNewOrder(accountID, originatedMT5Order)
 			if err != nil {
-				return res, err
+				err = a.enqueue(queue.KindPlace, accountID, queuedOrder{Mt5Order: originatedMT5Order}, err)
+				switch {
+				case errors.Is(err, ErrInvalidOrder):
+					res.AddJournal(fmt.Sprintf("[%s] POS(HIST) > ENTRY_IN > REJECTED > %s", currentMT5OldPosition.PositionTicket, err.Error()))
+				case err != nil:
+					return res, err
+				default:
+					res.AddJournal(fmt.Sprintf("[%s] POS(HIST) > ENTRY_IN > QUEUED", currentMT5OldPosition.PositionTicket))
+				}
+			} else {
+				res.AddJournal(fmt.Sprintf("[%s] POS(HIST) > ENTRY_IN > PLACE", currentMT5OldPosition.PositionTicket))
 			}
-			res.AddJournal(fmt.Sprintf("[%s] POS(HIST) > ENTRY_IN > PLACE", currentMT5OldPosition.PositionTicket))
 		}
 
 		// deal entry OUT with market order
@@ -207,22 +278,35 @@ func (a *Api) Sync(accountID string, req SyncRequest) (SyncResponse, error) {
 				continue
 			}
 
+			// closed on the v1 api or by a rollover that didn't open it again,
+			// closing it once more would open a reversed position
+			if group, has := a.mapping(currentMT5OldPosition.PositionTicket); has && (group.Status != orderdb.GroupStatusActive || group.Rolling) {
+				if group.Status == orderdb.GroupStatusActive {
+					if err = a.closeGroup(currentMT5OldPosition.PositionTicket); err != nil {
+						return res, err
+					}
+				}
+				res.AddJournal(fmt.Sprintf("[%s] POS(HIST) > ENTRY_OUT > ALREADY CLOSED", currentMT5OldPosition.PositionTicket))
+				a.appendRequest(currentMT5OldPosition)
+				continue
+			}
+
 			exanteOrders, err := a.findActiveAndFilledOrdersByTicket(currentMT5OldPosition.PositionTicket, accountID)
 			if err != nil {
 				return res, err
 			}
 
-			tpOrder, hasTpOrder := utils.GetTakeProfitOrder(exanteOrders)
+			tpOrder, hasTpOrder := a.takeProfitOrder(currentMT5OldPosition.PositionTicket, exanteOrders)
 			if hasTpOrder {
-				err = a.cancelOrder(tpOrder.OrderID)
+				err = a.cancelOrder(currentMT5OldPosition.PositionTicket, tpOrder.OrderID)
 				if err != nil {
 					return res, err
 				}
 				res.AddJournal(fmt.Sprintf("[%s] POS(HIST) > ENTRY_OUT > CANCEL TP", currentMT5OldPosition.PositionTicket))
 			}
-			slOrder, hasSLOrder := utils.GetStopLossOrder(exanteOrders)
+			slOrder, hasSLOrder := a.stopLossOrder(currentMT5OldPosition.PositionTicket, exanteOrders)
 			if hasSLOrder {
-				err = a.cancelOrder(slOrder.OrderID)
+				err = a.cancelOrder(currentMT5OldPosition.PositionTicket, slOrder.OrderID)
 				if err != nil {
 					return res, err
 				}
@@ -231,14 +315,30 @@ func (a *Api) Sync(accountID string, req SyncRequest) (SyncResponse, error) {
 			}
 
 			// add this clause to avoid opening a order on exante without the previews order from position
-			_, hasParentOrder := utils.GetParentOrder(exanteOrders)
+			exanteParentOrder, hasParentOrder := a.parentOrder(currentMT5OldPosition.PositionTicket, exanteOrders)
 			if hasParentOrder {
-				_, err = a.closePosition(accountID, originatedMT5Order)
+				// a rollover may have moved new orders to another contract
+				heldSymbol := exanteParentOrder.OrderParameters.SymbolId
+				_, err = a.closePosition(accountID, originatedMT5Order, heldSymbol)
 				if err != nil {
-					return res, err
+					// a close exante refuse is reported once, it would fail every sync
+					if err = a.enqueue(queue.KindClose, accountID, queuedOrder{Mt5Order: originatedMT5Order, Exante: heldSymbol, Position: currentMT5OldPosition.PositionTicket}, err); err != nil {
+						res.AddJournal(fmt.Sprintf("[%s] POS(HIST) > ENTRY_OUT > ERROR > %s", currentMT5OldPosition.PositionTicket, err.Error()))
+					} else {
+						res.AddJournal(fmt.Sprintf("[%s] POS(HIST) > ENTRY_OUT > QUEUED", currentMT5OldPosition.PositionTicket))
+					}
+				} else {
+					err = a.closeGroup(currentMT5OldPosition.PositionTicket)
+					if err != nil {
+						return res, err
+					}
+					// the closing order has nothing left to follow either
+					err = a.closeGroup(originatedMT5Order.Ticket)
+					if err != nil {
+						return res, err
+					}
+					res.AddJournal(fmt.Sprintf("[%s] POS(HIST) > ENTRY_OUT > CANCEL", currentMT5OldPosition.PositionTicket))
 				}
-
-				res.AddJournal(fmt.Sprintf("[%s] POS(HIST) > ENTRY_OUT > CANCEL", currentMT5OldPosition.PositionTicket))
 			}
 
 		}
@@ -253,39 +353,54 @@ func (a *Api) Sync(accountID string, req SyncRequest) (SyncResponse, error) {
 		if !a.isNewRequest(currentMT5Position) {
 			continue
 		}
+		if err := a.recordSnapshot(currentMT5Position.PositionTicket, currentMT5Position); err != nil {
+			return res, err
+		}
 
 		exanteOrders, err := a.findActiveAndFilledOrdersByTicket(currentMT5Position.PositionTicket, accountID)
 		if err != nil {
 			return res, err
 		}
-		exanteParentOrder, hasParentOrder := utils.GetParentOrder(exanteOrders)
+		exanteParentOrder, hasParentOrder := a.parentOrder(currentMT5Position.PositionTicket, exanteOrders)
 
 		if !hasParentOrder {
 			continue
 		}
+		// SL/TP wait for the roll to open the position on the next contract
+		if group, _ := a.db.Get(currentMT5Position.PositionTicket); group.Rolling {
+			res.AddJournal(fmt.Sprintf("[%s] POS(ACTIVE) > ROLLING > SKIP", currentMT5Position.PositionTicket))
+			continue
+		}
 
-		ocoGroup := utils.GetOCOGroup(exanteOrders)
+		// prices of a symbol removed from the mapping can't be converted,
+		// the request is handled once it is mapped again
+		exchange, has := a.exchange.GetByMTValue(currentMT5Position.Symbol)
+		if !has {
+			res.AddJournal(fmt.Sprintf("[%s] POS(ACTIVE) > %s NOT MAPPED > SKIP", currentMT5Position.PositionTicket, currentMT5Position.Symbol))
+			continue
+		}
+		ocoGroup := a.ocoGroup(currentMT5Position.PositionTicket, exanteOrders)
 
 		{
-			slOrder, hasSlOrder := utils.GetStopLossOrder(exanteOrders)
+			slOrder, hasSlOrder := a.stopLossOrder(currentMT5Position.PositionTicket, exanteOrders)
 
 			// Stop Loss change
 			if !hasSlOrder && currentMT5Position.StopLoss > 0 {
 				// has to add order
-				_, err = a.placeStopLoss(currentMT5Position.StopLoss, *exanteParentOrder, ocoGroup)
+				_, err = a.placeStopLoss(currentMT5Position.PositionTicket, exchange, currentMT5Position.StopLoss, *exanteParentOrder, ocoGroup)
 				if err != nil {
 					return res, err
 				}
 				res.AddJournal(fmt.Sprintf("[%s] POS(ACTIVE) > PLACE SL", currentMT5Position.PositionTicket))
 			} else if hasSlOrder && currentMT5Position.StopLoss == 0 {
 				// has to remove take profit
-				err = a.cancelOrder(slOrder.OrderID)
+				err = a.cancelOrder(currentMT5Position.PositionTicket, slOrder.OrderID)
 				if err != nil {
 					return res, err
 				}
 				res.AddJournal(fmt.Sprintf("[%s] POS(ACTIVE) > CANCEL SL", currentMT5Position.PositionTicket))
-			} else if hasSlOrder && slOrder.OrderParameters.StopPrice != utils.ConvertNDecimals(currentMT5Position.StopLoss) {
-				err = a.replaceSLOrder(currentMT5Position.StopLoss, slOrder.OrderID)
+			} else if hasSlOrder && slOrder.OrderParameters.StopPrice != a.formatPrice(slOrder.OrderParameters.SymbolId, exchange.Price(currentMT5Position.StopLoss)) {
+				err = a.replaceSLOrder(exchange.Price(currentMT5Position.StopLoss), slOrder.OrderID)
 				if err != nil {
 					return res, err
 				}
@@ -294,25 +409,25 @@ func (a *Api) Sync(accountID string, req SyncRequest) (SyncResponse, error) {
 		}
 
 		{
-			tpOrder, hasTpOrder := utils.GetTakeProfitOrder(exanteOrders)
+			tpOrder, hasTpOrder := a.takeProfitOrder(currentMT5Position.PositionTicket, exanteOrders)
 
 			// Take Profit change
 			if !hasTpOrder && currentMT5Position.TakeProfit > 0 {
 				// has to add order
-				_, err = a.placeTakeProfit(currentMT5Position.TakeProfit, *exanteParentOrder, ocoGroup)
+				_, err = a.placeTakeProfit(currentMT5Position.PositionTicket, exchange, currentMT5Position.TakeProfit, *exanteParentOrder, ocoGroup)
 				if err != nil {
 					return res, err
 				}
 				res.AddJournal(fmt.Sprintf("[%s] POS(ACTIVE) > PLACE TP", currentMT5Position.PositionTicket))
 			} else if hasTpOrder && currentMT5Position.TakeProfit == 0 {
 				// has to remove take profit
-				err = a.cancelOrder(tpOrder.OrderID)
+				err = a.cancelOrder(currentMT5Position.PositionTicket, tpOrder.OrderID)
 				if err != nil {
 					return res, err
 				}
 				res.AddJournal(fmt.Sprintf("[%s] POS(ACTIVE) > CANCEL TP", currentMT5Position.PositionTicket))
-			} else if hasTpOrder && tpOrder.OrderParameters.LimitPrice != utils.ConvertNDecimals(currentMT5Position.TakeProfit) {
-				err = a.replaceTPOrder(currentMT5Position.TakeProfit, tpOrder.OrderID)
+			} else if hasTpOrder && tpOrder.OrderParameters.LimitPrice != a.formatPrice(tpOrder.OrderParameters.SymbolId, exchange.Price(currentMT5Position.TakeProfit)) {
+				err = a.replaceTPOrder(exchange.Price(currentMT5Position.TakeProfit), tpOrder.OrderID)
 				if err != nil {
 					return res, err
 				}
@@ -331,6 +446,9 @@ func (a *Api) Sync(accountID string, req SyncRequest) (SyncResponse, error) {
 		if !a.isNewRequest(currentMT5Order) {
 			continue
 		}
+		if err := a.recordSnapshot(currentMT5Order.Ticket, currentMT5Order); err != nil {
+			return res, err
+		}
 
 		exanteActiveOrders, err := a.findActiveOrdersByTicket(currentMT5Order.Ticket, accountID)
 		if err != nil {
@@ -340,22 +458,39 @@ func (a *Api) Sync(accountID string, req SyncRequest) (SyncResponse, error) {
 		if len(exanteActiveOrders) == 0 {
 			_, err := a.placeNewOrder(accountID, currentMT5Order)
 			if err != nil {
-				return res, err
+				// a rejected order is kept on history, so it isn't sent
+				// again until MT5 changes it, and the rest of the sync goes on
+				err = a.enqueue(queue.KindPlace, accountID, queuedOrder{Mt5Order: currentMT5Order}, err)
+				switch {
+				case errors.Is(err, ErrInvalidOrder):
+					res.AddJournal(fmt.Sprintf("[%s] ORD(ACTIVE) > REJECTED > %s", currentMT5Order.Ticket, err.Error()))
+				case err != nil:
+					return res, err
+				default:
+					res.AddJournal(fmt.Sprintf("[%s] ORD(ACTIVE) > QUEUED", currentMT5Order.Ticket))
+				}
+				a.appendRequest(currentMT5Order)
+				continue
 			}
 			res.AddJournal(fmt.Sprintf("[%s] ORD(ACTIVE) > PLACE ORDER", currentMT5Order.Ticket))
 			a.appendRequest(currentMT5Order)
 			continue
 		}
 
-		ocoGroup := utils.GetOCOGroup(exanteActiveOrders)
-		exanteParentOrder, hasParentOrder := utils.GetParentOrder(exanteActiveOrders)
+		ocoGroup := a.ocoGroup(currentMT5Order.Ticket, exanteActiveOrders)
+		exanteParentOrder, hasParentOrder := a.parentOrder(currentMT5Order.Ticket, exanteActiveOrders)
 		if !hasParentOrder {
 			continue
 		}
+		exchange, has := a.exchange.GetByMTValue(currentMT5Order.Symbol)
+		if !has {
+			res.AddJournal(fmt.Sprintf("[%s] ORD(ACTIVE) > %s NOT MAPPED > SKIP", currentMT5Order.Ticket, currentMT5Order.Symbol))
+			continue
+		}
 
 		{
-			if exanteParentOrder.OrderParameters.LimitPrice != utils.ConvertNDecimals(currentMT5Order.Price) {
-				err = a.replaceTPOrder(currentMT5Order.Price, exanteParentOrder.OrderID)
+			if exanteParentOrder.OrderParameters.LimitPrice != a.formatPrice(exanteParentOrder.OrderParameters.SymbolId, exchange.Price(currentMT5Order.Price)) {
+				err = a.replaceTPOrder(exchange.Price(currentMT5Order.Price), exanteParentOrder.OrderID)
 				if err != nil {
 					return res, err
 				}
@@ -364,25 +499,25 @@ func (a *Api) Sync(accountID string, req SyncRequest) (SyncResponse, error) {
 		}
 
 		{
-			tpOrder, hasTpOrder := utils.GetTakeProfitOrder(exanteActiveOrders)
+			tpOrder, hasTpOrder := a.takeProfitOrder(currentMT5Order.Ticket, exanteActiveOrders)
 
 			// Take profit change
 			if !hasTpOrder && currentMT5Order.TakeProfit > 0 {
 				// has to add order
-				_, err = a.placeTakeProfit(currentMT5Order.TakeProfit, *exanteParentOrder, ocoGroup)
+				_, err = a.placeTakeProfit(currentMT5Order.Ticket, exchange, currentMT5Order.TakeProfit, *exanteParentOrder, ocoGroup)
 				if err != nil {
 					return res, err
 				}
 				res.AddJournal(fmt.Sprintf("[%s] ORD(ACTIVE) > PLACE TP", currentMT5Order.Ticket))
 			} else if hasTpOrder && currentMT5Order.TakeProfit == 0 {
 				// has to remove take profit
-				err = a.cancelOrder(tpOrder.OrderID)
+				err = a.cancelOrder(currentMT5Order.Ticket, tpOrder.OrderID)
 				if err != nil {
 					return res, err
 				}
 				res.AddJournal(fmt.Sprintf("[%s] ORD(ACTIVE) > CANCEL TP", currentMT5Order.Ticket))
-			} else if hasTpOrder && tpOrder.OrderParameters.LimitPrice != utils.ConvertNDecimals(currentMT5Order.TakeProfit) {
-				err = a.replaceTPOrder(currentMT5Order.TakeProfit, tpOrder.OrderID)
+			} else if hasTpOrder && tpOrder.OrderParameters.LimitPrice != a.formatPrice(tpOrder.OrderParameters.SymbolId, exchange.Price(currentMT5Order.TakeProfit)) {
+				err = a.replaceTPOrder(exchange.Price(currentMT5Order.TakeProfit), tpOrder.OrderID)
 				if err != nil {
 					return res, err
 				}
@@ -391,25 +526,25 @@ func (a *Api) Sync(accountID string, req SyncRequest) (SyncResponse, error) {
 		}
 
 		{
-			slOrder, hasSlOrder := utils.GetStopLossOrder(exanteActiveOrders)
+			slOrder, hasSlOrder := a.stopLossOrder(currentMT5Order.Ticket, exanteActiveOrders)
 
 			// Stop Loss change
 			if !hasSlOrder && currentMT5Order.StopLoss > 0 {
 				// has to add order
-				_, err = a.placeStopLoss(currentMT5Order.StopLoss, *exanteParentOrder, ocoGroup)
+				_, err = a.placeStopLoss(currentMT5Order.Ticket, exchange, currentMT5Order.StopLoss, *exanteParentOrder, ocoGroup)
 				if err != nil {
 					return res, err
 				}
 				res.AddJournal(fmt.Sprintf("[%s] ORD(ACTIVE) > PLACE SL", currentMT5Order.Ticket))
 			} else if hasSlOrder && currentMT5Order.StopLoss == 0 {
 				// has to remove take profit
-				err = a.cancelOrder(slOrder.OrderID)
+				err = a.cancelOrder(currentMT5Order.Ticket, slOrder.OrderID)
 				if err != nil {
 					return res, err
 				}
 				res.AddJournal(fmt.Sprintf("[%s] ORD(ACTIVE) > CANCEL SL", currentMT5Order.Ticket))
-			} else if hasSlOrder && slOrder.OrderParameters.LimitPrice != utils.ConvertNDecimals(currentMT5Order.StopLoss) {
-				err = a.replaceSLOrder(currentMT5Order.StopLoss, slOrder.OrderID)
+			} else if hasSlOrder && slOrder.OrderParameters.LimitPrice != a.formatPrice(slOrder.OrderParameters.SymbolId, exchange.Price(currentMT5Order.StopLoss)) {
+				err = a.replaceSLOrder(exchange.Price(currentMT5Order.StopLoss), slOrder.OrderID)
 				if err != nil {
 					return res, err
 				}
@@ -426,18 +561,21 @@ func (a *Api) Sync(accountID string, req SyncRequest) (SyncResponse, error) {
 		if !a.isNewRequest(currentMT5InactiveOrder) {
 			continue
 		}
+		if err := a.recordSnapshot(currentMT5InactiveOrder.Ticket, currentMT5InactiveOrder); err != nil {
+			return res, err
+		}
 
 		exanteActiveOrders, err := a.findActiveOrdersByTicket(currentMT5InactiveOrder.Ticket, accountID)
 		if err != nil {
 			return res, err
 		}
-		exanteParentOrder, hasParentOrder := utils.GetParentOrder(exanteActiveOrders)
+		exanteParentOrder, hasParentOrder := a.parentOrder(currentMT5InactiveOrder.Ticket, exanteActiveOrders)
 		if !hasParentOrder {
 			continue
 		}
 
 		if currentMT5InactiveOrder.State == OrderStateCancelled {
-			err := a.cancelOrder(exanteParentOrder.OrderID)
+			err := a.cancelOrder(currentMT5InactiveOrder.Ticket, exanteParentOrder.OrderID)
 			if err != nil {
 				return res, err
 			}
@@ -450,7 +588,107 @@ func (a *Api) Sync(accountID string, req SyncRequest) (SyncResponse, error) {
 	return res, nil
 }
 
-func (a *Api) cancelOrder(orderID string) error {
+// queuedOrder is the payload of a queued action, Exante is the contract
+// a close must use, empty for the current one, and Position the ticket
+// of the position it closes
+type queuedOrder struct {
+	Mt5Order
+	Exante   string `json:",omitempty"`
+	Position string `json:",omitempty"`
+}
+
+// enqueue keep an order that failed because exante is unavailable to be
+// sent again later, any other error is returned as is
+func (a *Api) enqueue(kind string, accountID string, queued queuedOrder, cause error) error {
+	if !exante.IsTemporary(cause) {
+		return cause
+	}
+
+	action, err := queue.NewAction(kind, queued.Ticket, accountID, queued)
+	if err != nil {
+		return err
+	}
+	action.LastError = cause.Error()
+	return a.pending.Push(action)
+}
+
+// processPending send the queued actions that are due, market orders
+// older than MarketOrderMaxAge are dead lettered since the price moved
+func (a *Api) processPending(res *SyncResponse) {
+	now := time.Now()
+	for _, action := range a.pending.Due(now) {
+		var queued queuedOrder
+		err := json.Unmarshal(action.Payload, &queued)
+		if err != nil {
+			_ = a.pending.Dead(action.ID, err.Error())
+			continue
+		}
+		order := queued.Mt5Order
+
+		if convertOrderType(order.Type) == "market" && now.Sub(action.CreatedAt) > a.MarketOrderMaxAge {
+			_ = a.pending.Dead(action.ID, "market order expired")
+			res.AddJournal(fmt.Sprintf("[%s] QUEUE > %s > EXPIRED", action.Ticket, action.ID))
+			continue
+		}
+
+		// the failed call may have reached exante
+		exanteOrders, err := a.findActiveAndFilledOrdersByTicket(order.Ticket, action.AccountID)
+		if err == nil && len(exanteOrders) == 0 {
+			switch action.Kind {
+			case queue.KindPlace:
+				_, err = a.placeNewOrder(action.AccountID, order)
+			case queue.KindClose:
+				err = a.closeQueuedPosition(action.AccountID, queued, res)
+			default:
+				err = fmt.Errorf("unknown action %s", action.Kind)
+			}
+		}
+
+		switch {
+		case err == nil:
+			_ = a.pending.Done(action.ID)
+			res.AddJournal(fmt.Sprintf("[%s] QUEUE > %s > SENT", action.Ticket, action.ID))
+		case exante.IsTemporary(err):
+			_ = a.pending.Retry(action.ID, err, now)
+			res.AddJournal(fmt.Sprintf("[%s] QUEUE > %s > RETRY", action.Ticket, action.ID))
+		default:
+			_ = a.pending.Dead(action.ID, err.Error())
+			res.AddJournal(fmt.Sprintf("[%s] QUEUE > %s > DEAD", action.Ticket, action.ID))
+		}
+	}
+}
+
+// closeQueuedPosition send a queued close when its position is still open,
+// SL/TP may have been filled while it waited
+func (a *Api) closeQueuedPosition(accountID string, queued queuedOrder, res *SyncResponse) error {
+	if len(queued.Position) > 0 {
+		// also track the fills exante did meanwhile
+		orders, err := a.findActiveAndFilledOrdersByTicket(queued.Position, accountID)
+		if err != nil {
+			return err
+		}
+		group, has := a.mapping(queued.Position)
+		if has && (group.Status != orderdb.GroupStatusActive || group.Rolling || len(orders) > 0 && utils.IsPositionClosed(orders)) {
+			res.AddJournal(fmt.Sprintf("[%s] QUEUE > CLOSE > ALREADY CLOSED", queued.Position))
+			if group.Status != orderdb.GroupStatusActive {
+				return nil
+			}
+			return a.closeGroup(queued.Position)
+		}
+	}
+
+	if _, err := a.closePosition(accountID, queued.Mt5Order, queued.Exante); err != nil {
+		return err
+	}
+	if len(queued.Position) > 0 {
+		if err := a.closeGroup(queued.Position); err != nil {
+			return err
+		}
+	}
+	return a.closeGroup(queued.Ticket)
+}
+
+func (a *Api) cancelOrder(ticket string, orderID string) error {
 	err := a.exanteApi.CancelOrder(orderID)
 	if err != nil {
 		if strings.Contains(err.Error(), "Unable to modify") {
@@ -458,7 +696,7 @@ func (a *Api) cancelOrder(orderID string) error {
 		}
 		return err
 	}
-	return nil
+	return a.untrackOrder(ticket, orderID)
 }
 
 func hasInactiveFilledOrder(ticket string) func(order Mt5Order) bool {
@@ -494,34 +732,35 @@ func (a *Api) replaceUpdatePositionHistory(history Mt5Position, accountID string
 	return err
 }
 
-func (a *Api) isNewRequest(mt5Res Mt5Requests) bool {
-	if val, has := a.history[mt5Res.WithTicket()]; has {
-		return val != utils.Hash(mt5Res)
-	}
-
-	return true
-}
-
-func (a *Api) appendRequest(mt5Res Mt5Requests) {
-	a.history[mt5Res.WithTicket()] = utils.Hash(mt5Res)
-}
-
 func (a *Api) placeNewOrder(accountID string, order Mt5Order) ([]exante.OrderV3, error) {
 	exchange, has := a.exchange.GetByMTValue(order.Symbol)
-	if !has {
+	if !has || !exchange.IsEnabled() {
 		return nil, nil
 	}
+	if len(exchange.Exante) == 0 {
+		return nil, fmt.Errorf("%w: no current contract for %s, update its rollover calendar", ErrInvalidOrder, order.Symbol)
+	}
+
+	orderType := convertOrderType(order.Type)
+	if !exchange.AllowOrderType(orderType) || !a.allowOrderType(exchange.Exante, orderType) {
+		return nil, fmt.Errorf("%w: order type %s not allowed for %s", ErrInvalidOrder, orderType, exchange.Exante)
+	}
+
+	quantity, err := a.exanteQuantity(exchange, order.Volume)
+	if err != nil {
+		return nil, err
+	}
 
 	orders, err := a.exanteApi.PlaceOrderV3(&exante.OrderSentTypeV3{
 		SymbolID:   exchange.Exante,
 		Duration:   "good_till_cancel",
-		OrderType:  convertOrderType(order.Type),
-		Quantity:   utils.Convert5Decimals(order.Volume * exchange.PriceStep),
+		OrderType:  orderType,
+		Quantity:   quantity,
 		Side:       convertOrderSide(order.Type),
-		LimitPrice: utils.ConvertNDecimals(order.Price),
+		LimitPrice: a.formatPrice(exchange.Exante, exchange.Price(order.Price)),
 		Instrument: exchange.Exante,
-		StopLoss:   utils.ConvertNDecimalsOrNil(order.StopLoss),
-		TakeProfit: utils.ConvertNDecimalsOrNil(order.TakeProfit),
+		StopLoss:   a.formatPriceOrNil(exchange.Exante, exchange.Price(order.StopLoss)),
+		TakeProfit: a.formatPriceOrNil(exchange.Exante, exchange.Price(order.TakeProfit)),
 		ClientTag:  order.Ticket,
 		AccountID:  accountID,
 	})
@@ -529,22 +768,32 @@ func (a *Api) placeNewOrder(accountID string, order Mt5Order) ([]exante.OrderV3,
 		return nil, err
 	}
 
-	return orders, nil
+	return orders, a.placeGroup(order.Ticket, orders)
 }
 
-func (a *Api) closePosition(accountID string, order Mt5Order) ([]exante.OrderV3, error) {
+// closePosition close the position of order held on symbolID, the current
+// contract of the exchange when empty
+func (a *Api) closePosition(accountID string, order Mt5Order, symbolID string) ([]exante.OrderV3, error) {
 	exchange, has := a.exchange.GetByMTValue(order.Symbol)
 	if !has {
 		return nil, nil
 	}
+	if len(symbolID) > 0 {
+		exchange.Exante = symbolID
+	}
+
+	quantity, err := a.exanteQuantity(exchange, order.Volume)
+	if err != nil {
+		return nil, err
+	}
 
 	orders, err := a.exanteApi.PlaceOrderV3(&exante.OrderSentTypeV3{
 		SymbolID:   exchange.Exante,
 		Duration:   "good_till_cancel",
 		OrderType:  convertOrderType(order.Type),
-		Quantity:   utils.Convert5Decimals(order.Volume * exchange.PriceStep),
+		Quantity:   quantity,
 		Side:       convertOrderSide(order.Type),
-		LimitPrice: utils.ConvertNDecimals(order.Price),
+		LimitPrice: a.formatPrice(exchange.Exante, exchange.Price(order.Price)),
 		Instrument: exchange.Exante,
 		ClientTag:  order.Ticket,
 		AccountID:  accountID,
@@ -553,10 +802,14 @@ func (a *Api) closePosition(accountID string, order Mt5Order) ([]exante.OrderV3,
 		return nil, err
 	}
 
-	return orders, nil
+	return orders, a.placeGroup(order.Ticket, orders)
 }
 
-func (a *Api) placeStopLoss(price float64, exanteOrder exante.OrderV3, ocoGroup string) (*exante.OrderV3, error) {
+// placeStopLoss place a stop at the MT5 price of exchange for exanteOrder
+func (a *Api) placeStopLoss(ticket string, exchange exchanges.DataExchanges, price float64, exanteOrder exante.OrderV3, ocoGroup string) (*exante.OrderV3, error) {
+	if !exchange.AllowOrderType("stop") {
+		return nil, fmt.Errorf("order type stop not allowed for %s", exanteOrder.OrderParameters.SymbolId)
+	}
 
 	orders, err := a.exanteApi.PlaceOrderV3(&exante.OrderSentTypeV3{
 		SymbolID:       exanteOrder.OrderParameters.SymbolId,
@@ -564,7 +817,7 @@ func (a *Api) placeStopLoss(price float64, exanteOrder exante.OrderV3, ocoGroup
 		OrderType:      "stop",
 		Quantity:       exanteOrder.OrderParameters.Quantity,
 		Side:           utils.GetReverseOrderSide(exanteOrder.OrderParameters.Side),
-		StopPrice:      utils.ConvertNDecimalsOrNil(price),
+		StopPrice:      a.formatPriceOrNil(exanteOrder.OrderParameters.SymbolId, exchange.Price(price)),
 		Instrument:     exanteOrder.OrderParameters.SymbolId,
 		AccountID:      exanteOrder.AccountID,
 		IfDoneParentID: exanteOrder.OrderID,
@@ -580,17 +833,22 @@ func (a *Api) placeStopLoss(price float64, exanteOrder exante.OrderV3, ocoGroup
 		return nil, fmt.Errorf("couldnt create take SL order")
 	}
 
-	return order, nil
+	return order, a.trackOrders(ticket, *order)
 }
 
-func (a *Api) placeTakeProfit(price float64, exanteOrder exante.OrderV3, ocoGroup string) (*exante.OrderV3, error) {
+// placeTakeProfit place a limit at the MT5 price of exchange for exanteOrder
+func (a *Api) placeTakeProfit(ticket string, exchange exchanges.DataExchanges, price float64, exanteOrder exante.OrderV3, ocoGroup string) (*exante.OrderV3, error) {
+	if !exchange.AllowOrderType("limit") {
+		return nil, fmt.Errorf("order type limit not allowed for %s", exanteOrder.OrderParameters.SymbolId)
+	}
+
 	orders, err := a.exanteApi.PlaceOrderV3(&exante.OrderSentTypeV3{
 		SymbolID:       exanteOrder.OrderParameters.SymbolId,
 		Duration:       "good_till_cancel",
 		OrderType:      "limit",
 		Quantity:       exanteOrder.OrderParameters.Quantity,
 		Side:           utils.GetReverseOrderSide(exanteOrder.OrderParameters.Side),
-		LimitPrice:     utils.ConvertNDecimals(price),
+		LimitPrice:     a.formatPrice(exanteOrder.OrderParameters.SymbolId, exchange.Price(price)),
 		Instrument:     exanteOrder.OrderParameters.SymbolId,
 		AccountID:      exanteOrder.AccountID,
 		IfDoneParentID: exanteOrder.OrderID,
@@ -606,53 +864,75 @@ func (a *Api) placeTakeProfit(price float64, exanteOrder exante.OrderV3, ocoGrou
 		return nil, fmt.Errorf("couldnt create take profit order")
 	}
 
-	return order, nil
+	return order, a.trackOrders(ticket, *order)
 }
 
+// replaceTPOrder replace the limit price of orderID, an order exante is
+// unable to modify is left to the next sync
 func (a *Api) replaceTPOrder(price float64, orderID string) error {
+	return ignoreUnableToModify(a.replaceLimitPrice(price, orderID))
+}
+
+// replaceSLOrder replace the stop price of orderID, an order exante is
+// unable to modify is left to the next sync
+func (a *Api) replaceSLOrder(price float64, orderID string) error {
+	return ignoreUnableToModify(a.replaceStopPrice(price, orderID))
+}
+
+func (a *Api) replaceLimitPrice(price float64, orderID string) error {
 	tpOrder, err := a.exanteApi.GetOrder(orderID)
 	if err != nil {
 		return err
 	}
 
-	_, err = a.exanteApi.ReplaceOrder(tpOrder.OrderID, exante.ReplaceOrderPayload{
+	replaced, err := a.exanteApi.ReplaceOrder(tpOrder.OrderID, exante.ReplaceOrderPayload{
 		Action: "replace",
 		Parameters: exante.ReplaceOrderParameters{
 			Quantity:   tpOrder.OrderParameters.Quantity,
-			LimitPrice: utils.ConvertNDecimals(price),
+			LimitPrice: a.formatPrice(tpOrder.OrderParameters.SymbolId, price),
 		},
 	})
 	if err != nil {
-		if strings.Contains(err.Error(), "Unable to modify order") {
-			return nil
-		}
-		return err
+		return unableToModify(err)
 	}
 
-	return nil
+	return a.refreshOrder(replaced)
 }
 
-func (a *Api) replaceSLOrder(price float64, orderID string) error {
+func (a *Api) replaceStopPrice(price float64, orderID string) error {
 	slOrder, err := a.exanteApi.GetOrder(orderID)
 	if err != nil {
 		return err
 	}
 
-	_, err = a.exanteApi.ReplaceOrder(slOrder.OrderID, exante.ReplaceOrderPayload{
+	replaced, err := a.exanteApi.ReplaceOrder(slOrder.OrderID, exante.ReplaceOrderPayload{
 		Action: "replace",
 		Parameters: exante.ReplaceOrderParameters{
 			Quantity:  slOrder.OrderParameters.Quantity,
-			StopPrice: utils.ConvertNDecimals(price),
+			StopPrice: a.formatPrice(slOrder.OrderParameters.SymbolId, price),
 		},
 	})
 	if err != nil {
-		if strings.Contains(err.Error(), "Unable to modify order") {
-			return nil
-		}
-		return err
+		return unableToModify(err)
 	}
 
-	return nil
+	return a.refreshOrder(replaced)
+}
+
+// unableToModify wrap the exante refusal to modify an order, filled or
+// cancelled meanwhile, with ErrTicketConflict
+func unableToModify(err error) error {
+	if strings.Contains(err.Error(), "Unable to modify order") {
+		return fmt.Errorf("%w: %s", ErrTicketConflict, err.Error())
+	}
+	return err
+}
+
+func ignoreUnableToModify(err error) error {
+	if errors.Is(err, ErrTicketConflict) {
+		return nil
+	}
+	return err
 }
 
 func (a *Api) replaceMainOrder(mt5Order Mt5Order, exanteOrder exante.OrderV3) error {
@@ -660,7 +940,7 @@ func (a *Api) replaceMainOrder(mt5Order Mt5Order, exanteOrder exante.OrderV3) er
 		Action: "replace",
 		Parameters: exante.ReplaceOrderParameters{
 			Quantity:   exanteOrder.OrderParameters.Quantity,
-			LimitPrice: utils.Convert5Decimals(mt5Order.Price),
+			LimitPrice: a.formatPrice(exanteOrder.OrderParameters.SymbolId, mt5Order.Price),
 		},
 	})
 	if err != nil {
@@ -673,52 +953,296 @@ func (a *Api) replaceMainOrder(mt5Order Mt5Order, exanteOrder exante.OrderV3) er
 	return nil
 }
 
+// formatPrice round price to the instrument tick size, when the
+// instrument is unknown the price is sent as it came from MT5
+func (a *Api) formatPrice(symbolID string, price float64) string {
+	instrument, err := a.exanteApi.GetInstrument(symbolID)
+	if err != nil {
+		return utils.ConvertNDecimals(price)
+	}
+	return instrument.RoundPrice(price)
+}
+
+func (a *Api) formatPriceOrNil(symbolID string, price float64) *string {
+	if price > 0 {
+		valS := a.formatPrice(symbolID, price)
+		return &valS
+	}
+	return nil
+}
+
+// formatQuantity round down quantity to the instrument lot size
+func (a *Api) formatQuantity(symbolID string, quantity float64) (string, error) {
+	instrument, err := a.exanteApi.GetInstrument(symbolID)
+	if err != nil {
+		return utils.Convert5Decimals(quantity), nil
+	}
+	return instrument.RoundQuantity(quantity)
+}
+
+// exanteQuantity convert a MT5 volume with the exchange contract
+// and round it to the instrument lot size
+func (a *Api) exanteQuantity(exchange exchanges.DataExchanges, volume float64) (string, error) {
+	quantity, err := exchange.Quantity(volume)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrInvalidOrder, err.Error())
+	}
+	formatted, err := a.formatQuantity(exchange.Exante, quantity)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrInvalidOrder, err.Error())
+	}
+	return formatted, nil
+}
+
+func (a *Api) allowOrderType(symbolID string, orderType string) bool {
+	instrument, err := a.exanteApi.GetInstrument(symbolID)
+	if err != nil {
+		return true
+	}
+	return instrument.AllowOrderType(orderType)
+}
+
 func (a *Api) findActiveOrdersByTicket(ticket string, accountID string) ([]exante.OrderV3, error) {
+	return a.findOrdersByTicket(ticket, accountID, exante.WorkingStatus, exante.PendingStatus)
+}
+
+func (a *Api) findActiveAndFilledOrdersByTicket(ticket string, accountID string) ([]exante.OrderV3, error) {
+	return a.findOrdersByTicket(ticket, accountID, exante.WorkingStatus, exante.PendingStatus, exante.FilledStatus)
+}
+
+func (a *Api) findFilledOrdersByTicket(ticket string, accountID string) ([]exante.OrderV3, error) {
+	return a.findOrdersByTicket(ticket, accountID, exante.FilledStatus)
+}
+
+// findOrdersByTicket return the orders of ticket with one of statuses, orders
+// are matched by the ids on orderdb. Tickets synced before being on orderdb
+// are matched by client tag once and saved.
+func (a *Api) findOrdersByTicket(ticket string, accountID string, statuses ...exante.Status) ([]exante.OrderV3, error) {
 	orders, err := a.exanteApi.GetOrdersByLimitV3(100, accountID)
 	if err != nil {
 		return nil, err
 	}
 
+	group, mapped := a.mapping(ticket)
+
+	ticketOrders := make([]exante.OrderV3, 0)
 	returnOrders := make([]exante.OrderV3, 0)
 	for _, order := range orders {
-		if order.ClientTag == ticket && (order.OrderState.Status == exante.WorkingStatus || order.OrderState.Status == exante.PendingStatus) {
+		if mapped && !group.Has(order.OrderID) || !mapped && order.ClientTag != ticket {
+			continue
+		}
+		ticketOrders = append(ticketOrders, order)
+		if slices.Contains(statuses, order.OrderState.Status) {
 			returnOrders = append(returnOrders, order)
 		}
 	}
 
+	if !mapped && len(ticketOrders) > 0 {
+		err = a.placeGroup(ticket, ticketOrders)
+		if err != nil {
+			return nil, err
+		}
+		group, _ = a.mapping(ticket)
+	}
+
+	err = a.trackStatus(ticket, group, ticketOrders)
+	if err != nil {
+		return nil, err
+	}
+
 	return returnOrders, nil
 }
 
-func (a *Api) findActiveAndFilledOrdersByTicket(ticket string, accountID string) ([]exante.OrderV3, error) {
-	orders, err := a.exanteApi.GetOrdersByLimitV3(100, accountID)
+// mapping return the group of ticket when it has exante orders
+func (a *Api) mapping(ticket string) (orderdb.OrderGroup, bool) {
+	group, has := a.db.Get(ticket)
+	return group, has && len(group.Order.ID) > 0
+}
+
+func (a *Api) parentOrder(ticket string, orders []exante.OrderV3) (*exante.OrderV3, bool) {
+	group, has := a.mapping(ticket)
+	if !has {
+		return utils.GetParentOrder(orders)
+	}
+	return findOrderByID(orders, group.Order.ID)
+}
+
+func (a *Api) stopLossOrder(ticket string, orders []exante.OrderV3) (*exante.OrderV3, bool) {
+	group, has := a.mapping(ticket)
+	if !has {
+		return utils.GetStopLossOrder(orders)
+	}
+	if group.StopLoss == nil {
+		return nil, false
+	}
+	return findOrderByID(orders, group.StopLoss.ID)
+}
+
+func (a *Api) takeProfitOrder(ticket string, orders []exante.OrderV3) (*exante.OrderV3, bool) {
+	group, has := a.mapping(ticket)
+	if !has {
+		return utils.GetTakeProfitOrder(orders)
+	}
+	if group.TakeProfit == nil {
+		return nil, false
+	}
+	return findOrderByID(orders, group.TakeProfit.ID)
+}
+
+// ocoGroup return the OCO group of ticket, SL and TP placed on
+// different syncs share the group stored on orderdb
+func (a *Api) ocoGroup(ticket string, orders []exante.OrderV3) string {
+	group, has := a.mapping(ticket)
+	if !has || len(group.OcoGroup) == 0 {
+		return utils.GetOCOGroup(orders)
+	}
+	return group.OcoGroup
+}
+
+func findOrderByID(orders []exante.OrderV3, orderID string) (*exante.OrderV3, bool) {
+	idx := slices.IndexFunc(orders, func(order exante.OrderV3) bool {
+		return order.OrderID == orderID
+	})
+	if idx == -1 {
+		return nil, false
+	}
+	return &orders[idx], true
+}
+
+// recordSnapshot append the MT5 state of ticket to its log. A request
+// skipped without being appended to history is new on every sync, its
+// snapshot is only recorded the first time.
+func (a *Api) recordSnapshot(ticket string, snapshot Mt5Requests) error {
+	b, err := json.Marshal(snapshot)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	returnOrders := make([]exante.OrderV3, 0)
+	events, err := a.db.Events(ticket)
+	if err != nil {
+		return err
+	}
+	for _, e := range events {
+		if e.Type == orderdb.EventSnapshotSeen && bytes.Equal(e.Snapshot, b) {
+			return nil
+		}
+	}
+
+	_, err = a.db.Append(ticket, orderdb.Event{Type: orderdb.EventSnapshotSeen, Snapshot: b})
+	return err
+}
+
+// placeGroup start the group of ticket again with the orders of a new placement
+func (a *Api) placeGroup(ticket string, orders []exante.OrderV3) error {
+	_, err := a.db.Append(ticket, orderEvents(orderdb.OrderGroup{}, orders)...)
+	return err
+}
+
+// trackOrders append orders to the ticket log, known ids are replaced and
+// new ones are placed with their role on the exante structure
+func (a *Api) trackOrders(ticket string, orders ...exante.OrderV3) error {
+	group, _ := a.db.Get(ticket)
+	_, err := a.db.Append(ticket, orderEvents(group, orders)...)
+	return err
+}
+
+// trackStatus append the fills and cancels exante did on the ticket orders
+func (a *Api) trackStatus(ticket string, group orderdb.OrderGroup, orders []exante.OrderV3) error {
+	events := make([]orderdb.Event, 0)
 	for _, order := range orders {
-		if order.ClientTag == ticket && (order.OrderState.Status == exante.WorkingStatus || order.OrderState.Status == exante.PendingStatus || order.OrderState.Status == exante.FilledStatus) {
-			returnOrders = append(returnOrders, order)
+		stored, has := storedOrder(group, order.OrderID)
+		if !has {
+			continue
+		}
+		switch {
+		case order.OrderState.Status == exante.FilledStatus && stored.Status != orderdb.OrderStatusFilled:
+			events = append(events, orderdb.Event{Type: orderdb.EventFilled, OrderID: order.OrderID})
+		case order.OrderState.Status == exante.CancelledStatus && stored.Status != orderdb.OrderStatusCancelled:
+			events = append(events, orderdb.Event{Type: orderdb.EventCancelled, OrderID: order.OrderID})
 		}
 	}
+	if len(events) == 0 {
+		return nil
+	}
 
-	return returnOrders, nil
+	_, err := a.db.Append(ticket, events...)
+	return err
 }
 
-func (a *Api) findFilledOrdersByTicket(ticket string, accountID string) ([]exante.OrderV3, error) {
-	orders, err := a.exanteApi.GetOrdersByLimitV3(100, accountID)
-	if err != nil {
-		return nil, err
+// refreshOrder update the stored copy of an order already mapped to its ticket
+func (a *Api) refreshOrder(order *exante.OrderV3) error {
+	if order == nil {
+		return nil
+	}
+	group, has := a.db.Get(order.ClientTag)
+	if !has || !group.Has(order.OrderID) {
+		return nil
+	}
+	return a.trackOrders(order.ClientTag, *order)
+}
+
+// untrackOrder remove a cancelled SL/TP from the group of ticket,
+// cancelling the parent cancel the whole group
+func (a *Api) untrackOrder(ticket string, orderID string) error {
+	group, has := a.db.Get(ticket)
+	if !has || !group.Has(orderID) {
+		return nil
 	}
 
-	returnOrders := make([]exante.OrderV3, 0)
+	_, err := a.db.Append(ticket, orderdb.Event{Type: orderdb.EventCancelled, OrderID: orderID})
+	return err
+}
+
+func (a *Api) closeGroup(ticket string) error {
+	if _, has := a.db.Get(ticket); !has {
+		return nil
+	}
+
+	_, err := a.db.Append(ticket, orderdb.Event{Type: orderdb.EventClosed})
+	return err
+}
+
+func storedOrder(group orderdb.OrderGroup, orderID string) (orderdb.OrderDB, bool) {
+	switch {
+	case group.Order.ID == orderID:
+		return group.Order, true
+	case group.StopLoss != nil && group.StopLoss.ID == orderID:
+		return *group.StopLoss, true
+	case group.TakeProfit != nil && group.TakeProfit.ID == orderID:
+		return *group.TakeProfit, true
+	}
+	return orderdb.OrderDB{}, false
+}
+
+// orderEvents return the events of orders on group, a new parent comes
+// first since it start the group again
+func orderEvents(group orderdb.OrderGroup, orders []exante.OrderV3) []orderdb.Event {
+	events := make([]orderdb.Event, 0)
+	hasParent := len(group.Order.ID) > 0
+
 	for _, order := range orders {
-		if order.ClientTag == ticket && order.OrderState.Status == exante.FilledStatus {
-			returnOrders = append(returnOrders, order)
+		if len(order.OrderParameters.OcoGroup) > 0 || group.Has(order.OrderID) || hasParent {
+			continue
 		}
+		ocoGroup := utils.GetOCOGroup(orders)
+		events = append(events, orderdb.Event{Type: orderdb.EventPlaced, Role: orderdb.RoleParent, Order: utils.ConvertExOrderToDB(order), OcoGroup: ocoGroup})
+		hasParent = true
 	}
 
-	return returnOrders, nil
+	for _, order := range orders {
+		dbOrder := utils.ConvertExOrderToDB(order)
+		switch {
+		case group.Has(order.OrderID):
+			events = append(events, orderdb.Event{Type: orderdb.EventReplaced, Order: dbOrder})
+		case len(order.OrderParameters.OcoGroup) == 0:
+			// parent, already added
+		case order.OrderParameters.OrderType == "limit":
+			events = append(events, orderdb.Event{Type: orderdb.EventPlaced, Role: orderdb.RoleTakeProfit, Order: dbOrder, OcoGroup: order.OrderParameters.OcoGroup})
+		default:
+			events = append(events, orderdb.Event{Type: orderdb.EventPlaced, Role: orderdb.RoleStopLoss, Order: dbOrder, OcoGroup: order.OrderParameters.OcoGroup})
+		}
+	}
+	return events
 }
 
 func convertOrderType(ot OrderType) string {
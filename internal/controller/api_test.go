@@ -3,10 +3,14 @@ package controller
 import (
 	"github.com/danielsussa/mt5-to-exante/internal/exante"
 	"github.com/danielsussa/mt5-to-exante/internal/exchanges"
+	"github.com/danielsussa/mt5-to-exante/internal/orderdb"
+	"github.com/danielsussa/mt5-to-exante/internal/queue"
 	"github.com/danielsussa/mt5-to-exante/internal/utils"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestApi(t *testing.T) {
@@ -26,7 +30,7 @@ func TestApi(t *testing.T) {
 	t.Run("new position was created with TP/SL should have 2 active orders on EXANTE", func(t *testing.T) {
 
 		exanteMock := exante.NewMock(make([]exante.OrderV3, 0))
-		c := New(exanteMock, exchange)
+		c := New(exanteMock, orderdb.NewNoDisk(), &exchange, queue.NewNoDisk())
 
 		{ // the program started with a recent position, and a recent order is visible
 			_, err := c.Sync("acc-1", SyncRequest{
@@ -104,7 +108,7 @@ func TestApi(t *testing.T) {
 	t.Run("new order, add stops and cancel order", func(t *testing.T) {
 
 		exanteMock := exante.NewMock(make([]exante.OrderV3, 0))
-		c := New(exanteMock, exchange)
+		c := New(exanteMock, orderdb.NewNoDisk(), &exchange, queue.NewNoDisk())
 
 		{ // the program started with a recent order
 			_, err := c.Sync("acc-1", SyncRequest{
@@ -164,7 +168,7 @@ func TestApi(t *testing.T) {
 	t.Run("new order, change order's price", func(t *testing.T) {
 
 		exanteMock := exante.NewMock(make([]exante.OrderV3, 0))
-		c := New(exanteMock, exchange)
+		c := New(exanteMock, orderdb.NewNoDisk(), &exchange, queue.NewNoDisk())
 
 		{ // the program started with a recent order
 			_, err := c.Sync("acc-1", SyncRequest{
@@ -199,7 +203,7 @@ func TestApi(t *testing.T) {
 	t.Run("new order and become a position", func(t *testing.T) {
 
 		exanteMock := exante.NewMock(make([]exante.OrderV3, 0))
-		c := New(exanteMock, exchange)
+		c := New(exanteMock, orderdb.NewNoDisk(), &exchange, queue.NewNoDisk())
 
 		{ // the program started with a recent order
 			_, err := c.Sync("acc-1", SyncRequest{
@@ -242,7 +246,7 @@ func TestApi(t *testing.T) {
 				ClientTag: "1234",
 			},
 		})
-		c := New(exanteMock, exchange)
+		c := New(exanteMock, orderdb.NewNoDisk(), &exchange, queue.NewNoDisk())
 		{ // should not add another order on exante
 			_, err := c.Sync("acc-1", SyncRequest{
 				ActivePositions: []Mt5Position{
@@ -285,7 +289,7 @@ func TestApi(t *testing.T) {
 				ClientTag: "1234",
 			},
 		})
-		c := New(exanteMock, exchange)
+		c := New(exanteMock, orderdb.NewNoDisk(), &exchange, queue.NewNoDisk())
 		{
 			_, err := c.Sync("acc-1", SyncRequest{
 				ActivePositions: []Mt5Position{
@@ -344,7 +348,7 @@ func TestApi(t *testing.T) {
 			},
 		}
 		exanteMock := exante.NewMock(exanteOrders)
-		c := New(exanteMock, exchange)
+		c := New(exanteMock, orderdb.NewNoDisk(), &exchange, queue.NewNoDisk())
 
 		{ // the status is filled on EXANTE but remains the same in MT5, shouldnt do anything
 
@@ -385,7 +389,7 @@ func TestApi(t *testing.T) {
 				ClientTag: "1234",
 			},
 		})
-		c := New(exanteMock, exchange)
+		c := New(exanteMock, orderdb.NewNoDisk(), &exchange, queue.NewNoDisk())
 		{ // should not add another order on exante
 			_, err := c.Sync("acc-1", SyncRequest{
 				ActivePositions: []Mt5Position{
@@ -432,7 +436,7 @@ func TestApi(t *testing.T) {
 				ClientTag: "1234",
 			},
 		})
-		c := New(exanteMock, exchange)
+		c := New(exanteMock, orderdb.NewNoDisk(), &exchange, queue.NewNoDisk())
 		{ // should not add another order on exante
 			_, err := c.Sync("acc-1", SyncRequest{
 				ActivePositions: []Mt5Position{},
@@ -466,7 +470,7 @@ func TestApi(t *testing.T) {
 				ClientTag: "",
 			},
 		})
-		c := New(exanteMock, exchange)
+		c := New(exanteMock, orderdb.NewNoDisk(), &exchange, queue.NewNoDisk())
 		{ // should not add another order on exante
 			_, err := c.Sync("acc-1", SyncRequest{
 				ActivePositions: []Mt5Position{},
@@ -483,7 +487,7 @@ func TestApi(t *testing.T) {
 
 	t.Run("open a position and closes soon", func(t *testing.T) {
 		exanteMock := exante.NewMock([]exante.OrderV3{})
-		c := New(exanteMock, exchange)
+		c := New(exanteMock, orderdb.NewNoDisk(), &exchange, queue.NewNoDisk())
 		{ // should open a new position
 			_, err := c.Sync("acc-1", SyncRequest{
 				ActivePositions: []Mt5Position{
@@ -539,7 +543,7 @@ func TestApi(t *testing.T) {
 
 	t.Run("open a position with SL and add TP later", func(t *testing.T) {
 		exanteMock := exante.NewMock([]exante.OrderV3{})
-		c := New(exanteMock, exchange)
+		c := New(exanteMock, orderdb.NewNoDisk(), &exchange, queue.NewNoDisk())
 		{ // should open a new position
 			_, err := c.Sync("acc-1", SyncRequest{
 				ActivePositions: []Mt5Position{
@@ -605,7 +609,7 @@ func TestApi(t *testing.T) {
 				ClientTag: "1234",
 			},
 		})
-		c := New(exanteMock, exchange)
+		c := New(exanteMock, orderdb.NewNoDisk(), &exchange, queue.NewNoDisk())
 		{ // should only change take profit
 			_, err := c.Sync("acc-1", SyncRequest{
 				ActivePositions: []Mt5Position{
@@ -624,7 +628,7 @@ func TestApi(t *testing.T) {
 
 	t.Run("has a open position on MT5 but doesn't have on exante, shouldn't do anything on EXANTE", func(t *testing.T) {
 		exanteMock := exante.NewMock([]exante.OrderV3{})
-		c := New(exanteMock, exchange)
+		c := New(exanteMock, orderdb.NewNoDisk(), &exchange, queue.NewNoDisk())
 		{ // should not add another order on exante
 			_, err := c.Sync("acc-1", SyncRequest{
 				RecentInactivePositions: []Mt5PositionHistory{
@@ -641,4 +645,892 @@ func TestApi(t *testing.T) {
 			assert.Len(t, allOrders, 0)
 		}
 	})
+
+	t.Run("unchanged request skipped by sync should not grow the ticket log", func(t *testing.T) {
+		exanteMock := exante.NewMock([]exante.OrderV3{})
+		db := orderdb.NewNoDisk()
+		c := New(exanteMock, db, &exchange, queue.NewNoDisk())
+
+		// no exante parent order and no originating MT5 order, both are skipped
+		req := SyncRequest{
+			ActivePositions: []Mt5Position{
+				{PositionTicket: "1234", Ticket: "1234", Symbol: "EURUSD", Volume: 1, Price: 1.2},
+			},
+			RecentInactivePositions: []Mt5PositionHistory{
+				{PositionTicket: "1234", Ticket: "1235", Symbol: "EURUSD", Volume: 1, Price: 1.2, Entry: DealEntryIn},
+			},
+		}
+		_, err := c.Sync("acc-1", req)
+		assert.NoError(t, err)
+		events, err := db.Events("1234")
+		assert.NoError(t, err)
+		assert.Len(t, events, 2)
+
+		_, err = c.Sync("acc-1", req)
+		assert.NoError(t, err)
+		again, err := db.Events("1234")
+		assert.NoError(t, err)
+		assert.Len(t, again, len(events))
+	})
+
+	t.Run("new order should be rounded to instrument tick and lot size", func(t *testing.T) {
+		exanteMock := exante.NewMock(make([]exante.OrderV3, 0))
+		exanteMock.GetInstrumentFunc = func(symbolID string) (*exante.Instrument, error) {
+			return &exante.Instrument{SymbolID: symbolID, TickSize: "0.0005", LotSize: "0.1", MinQuantity: "0.1"}, nil
+		}
+		c := New(exanteMock, orderdb.NewNoDisk(), &exchange, queue.NewNoDisk())
+		{
+			_, err := c.Sync("acc-1", SyncRequest{
+				ActiveOrders: []Mt5Order{
+					{Symbol: "EURUSD", Ticket: "1234", Volume: 1.27, Type: OrderTypeBuyLimit, Price: 1.23461, State: OrderStatePlaced},
+				},
+			})
+			assert.NoError(t, err)
+			activeOrder, _ := c.exanteApi.GetActiveOrdersV3()
+			assert.Len(t, activeOrder, 1)
+			assert.Equal(t, "1.2345", activeOrder[0].OrderParameters.LimitPrice)
+			assert.Equal(t, "1.2", activeOrder[0].OrderParameters.Quantity)
+		}
+		{ // quantity lower than minimum should not be sent
+			res, err := c.Sync("acc-1", SyncRequest{
+				ActiveOrders: []Mt5Order{
+					{Symbol: "EURUSD", Ticket: "1235", Volume: 0.05, Type: OrderTypeBuyLimit, Price: 1.2, State: OrderStatePlaced},
+				},
+			})
+			assert.NoError(t, err)
+			assert.Contains(t, res.JournalF, "[1235] ORD(ACTIVE) > REJECTED")
+			assert.Equal(t, 1, exanteMock.TotalPlaceOrderV3)
+		}
+	})
+
+	t.Run("orders should follow the symbol contract", func(t *testing.T) {
+		disabled := false
+		contractExchange := exchanges.Api{
+			Data: exchanges.Data{
+				Exchanges: []exchanges.DataExchanges{
+					{
+						Exante:     "US500.INDEX",
+						MetaTrader: "US500",
+						Contract: exchanges.Contract{
+							ContractSize:    10,
+							PriceMultiplier: 0.1,
+							PriceOffset:     1,
+							QuantityStep:    5,
+							MaxQuantity:     100,
+							OrderTypes:      []string{"limit", "stop"},
+						},
+					},
+					{Exante: "BTC.USD", MetaTrader: "BTCUSD", PriceStep: 1, Contract: exchanges.Contract{Enabled: &disabled}},
+				},
+			},
+		}
+		exanteMock := exante.NewMock(make([]exante.OrderV3, 0))
+		c := New(exanteMock, orderdb.NewNoDisk(), &contractExchange, queue.NewNoDisk())
+
+		order := Mt5Order{Symbol: "US500", Ticket: "1234", Volume: 1.27, Type: OrderTypeBuyLimit, Price: 5000, State: OrderStatePlaced}
+		{
+			_, err := c.Sync("acc-1", SyncRequest{ActiveOrders: []Mt5Order{order}})
+			assert.NoError(t, err)
+			activeOrder, _ := c.exanteApi.GetActiveOrdersV3()
+			assert.Len(t, activeOrder, 1)
+			assert.Equal(t, "501", activeOrder[0].OrderParameters.LimitPrice)
+			assert.Equal(t, "10", activeOrder[0].OrderParameters.Quantity)
+		}
+		{ // SL price is converted
+			order.StopLoss = 4900
+			_, err := c.Sync("acc-1", SyncRequest{ActiveOrders: []Mt5Order{order}})
+			assert.NoError(t, err)
+			group, _ := c.db.Get("1234")
+			assert.NotNil(t, group.StopLoss)
+			stopLoss, _ := c.exanteApi.GetOrder(group.StopLoss.ID)
+			assert.Equal(t, "491", stopLoss.OrderParameters.StopPrice)
+		}
+		{ // rejected orders are journaled and don't block the orders after them
+			rejected := SyncRequest{ActiveOrders: []Mt5Order{
+				{Symbol: "US500", Ticket: "1235", Volume: 20, Type: OrderTypeBuyLimit, Price: 5000, State: OrderStatePlaced},
+				{Symbol: "US500", Ticket: "1236", Volume: 1, Type: OrderTypeBuy, Price: 5000, State: OrderStatePlaced},
+				{Symbol: "US500", Ticket: "1238", Volume: 1, Type: OrderTypeBuyLimit, Price: 4000, State: OrderStatePlaced},
+			}}
+			res, err := c.Sync("acc-1", rejected)
+			assert.NoError(t, err)
+			assert.Contains(t, res.JournalF, "[1235] ORD(ACTIVE) > REJECTED")
+			assert.Contains(t, res.JournalF, "above the maximum")
+			assert.Contains(t, res.JournalF, "[1236] ORD(ACTIVE) > REJECTED")
+			assert.Contains(t, res.JournalF, "not allowed")
+			assert.Contains(t, res.JournalF, "[1238] ORD(ACTIVE) > PLACE ORDER")
+
+			// not sent again until MT5 changes them
+			res, err = c.Sync("acc-1", rejected)
+			assert.NoError(t, err)
+			assert.NotContains(t, res.JournalF, "REJECTED")
+		}
+		{ // disabled symbol is ignored
+			_, err := c.Sync("acc-1", SyncRequest{ActiveOrders: []Mt5Order{
+				{Symbol: "BTCUSD", Ticket: "1237", Volume: 1, Type: OrderTypeBuyLimit, Price: 50000, State: OrderStatePlaced},
+			}})
+			assert.NoError(t, err)
+		}
+		assert.Equal(t, 3, exanteMock.TotalPlaceOrderV3)
+	})
+
+	t.Run("response dropped after placing order should not duplicate it on next sync", func(t *testing.T) {
+		exanteMock := exante.NewMock(make([]exante.OrderV3, 0))
+		exanteMock.Faults = exante.NewFaults(1)
+		exanteMock.Faults.Set(exante.EndpointPlaceOrder, exante.Fault{DropResponse: true})
+		c := New(exanteMock, orderdb.NewNoDisk(), &exchange, queue.NewNoDisk())
+
+		req := SyncRequest{
+			ActiveOrders: []Mt5Order{
+				{Symbol: "EURUSD", Ticket: "1234", Volume: 1, Type: OrderTypeBuyLimit, Price: 1.2, State: OrderStatePlaced},
+			},
+		}
+		{
+			_, err := c.Sync("acc-1", req)
+			assert.Error(t, err)
+			activeOrder, _ := c.exanteApi.GetActiveOrdersV3()
+			assert.Len(t, activeOrder, 1)
+		}
+		{
+			exanteMock.Faults.Clear(exante.EndpointPlaceOrder)
+			_, err := c.Sync("acc-1", req)
+			assert.NoError(t, err)
+			activeOrder, _ := c.exanteApi.GetActiveOrdersV3()
+			assert.Len(t, activeOrder, 1)
+			assert.Equal(t, 1, exanteMock.TotalPlaceOrderV3)
+		}
+	})
+
+	t.Run("unable to modify order on cancel should not fail sync", func(t *testing.T) {
+		exanteMock := exante.NewMock(make([]exante.OrderV3, 0))
+		exanteMock.Faults = exante.NewFaults(1)
+		c := New(exanteMock, orderdb.NewNoDisk(), &exchange, queue.NewNoDisk())
+		{
+			_, err := c.Sync("acc-1", SyncRequest{
+				ActiveOrders: []Mt5Order{
+					{Symbol: "EURUSD", Ticket: "1234", Volume: 1, Type: OrderTypeBuyLimit, Price: 1.2, State: OrderStatePlaced},
+				},
+			})
+			assert.NoError(t, err)
+		}
+		{
+			exanteMock.Faults.Set(exante.EndpointCancelOrder, exante.Fault{UnableToModify: true})
+			_, err := c.Sync("acc-1", SyncRequest{
+				RecentInactiveOrders: []Mt5Order{
+					{Symbol: "EURUSD", Ticket: "1234", Volume: 1, Type: OrderTypeBuyLimit, Price: 1.2, State: OrderStateCancelled},
+				},
+			})
+			assert.NoError(t, err)
+		}
+	})
+
+	t.Run("exante internal error should be returned", func(t *testing.T) {
+		exanteMock := exante.NewMock(make([]exante.OrderV3, 0))
+		exanteMock.Faults = exante.NewFaults(1)
+		exanteMock.Faults.Set(exante.EndpointListOrders, exante.Fault{ServerErrRate: 1})
+		c := New(exanteMock, orderdb.NewNoDisk(), &exchange, queue.NewNoDisk())
+
+		_, err := c.Sync("acc-1", SyncRequest{
+			ActiveOrders: []Mt5Order{
+				{Symbol: "EURUSD", Ticket: "1234", Volume: 1, Type: OrderTypeBuyLimit, Price: 1.2, State: OrderStatePlaced},
+			},
+		})
+		assert.ErrorContains(t, err, "internal server error")
+		assert.Equal(t, 0, exanteMock.TotalPlaceOrderV3)
+	})
+
+	t.Run("circuit breaker open should keep requests pending", func(t *testing.T) {
+		exanteMock := exante.NewMock(make([]exante.OrderV3, 0))
+		circuitOpen := true
+		exanteMock.CircuitOpenFunc = func() bool {
+			return circuitOpen
+		}
+		c := New(exanteMock, orderdb.NewNoDisk(), &exchange, queue.NewNoDisk())
+
+		req := SyncRequest{
+			ActiveOrders: []Mt5Order{
+				{Symbol: "EURUSD", Ticket: "1234", Volume: 1, Type: OrderTypeBuyLimit, Price: 1.2, State: OrderStatePlaced},
+			},
+		}
+		{
+			res, err := c.Sync("acc-1", req)
+			assert.NoError(t, err)
+			assert.Contains(t, res.JournalF, "EXANTE UNAVAILABLE")
+			assert.Equal(t, 0, exanteMock.TotalCalls)
+		}
+		{ // breaker closed, the same request is processed
+			circuitOpen = false
+			_, err := c.Sync("acc-1", req)
+			assert.NoError(t, err)
+			assert.Equal(t, 1, exanteMock.TotalPlaceOrderV3)
+		}
+	})
+	t.Run("order failed with exante unavailable should be queued and sent later", func(t *testing.T) {
+		exanteMock := exante.NewMock(make([]exante.OrderV3, 0))
+		exanteMock.Faults = exante.NewFaults(1)
+		exanteMock.Faults.Set(exante.EndpointPlaceOrder, exante.Fault{ServerErrRate: 1})
+		pending := queue.NewNoDisk()
+		pending.BaseBackoff = 0
+		c := New(exanteMock, orderdb.NewNoDisk(), &exchange, pending)
+
+		req := SyncRequest{
+			ActiveOrders: []Mt5Order{
+				{Symbol: "EURUSD", Ticket: "1234", Volume: 1, Type: OrderTypeBuyLimit, Price: 1.2, State: OrderStatePlaced},
+			},
+		}
+		{
+			res, err := c.Sync("acc-1", req)
+			assert.NoError(t, err)
+			assert.Contains(t, res.JournalF, "QUEUED")
+			assert.Len(t, pending.Pending(), 1)
+		}
+		{ // still failing, retried on next sync
+			_, err := c.Sync("acc-1", req)
+			assert.NoError(t, err)
+			assert.Equal(t, 1, pending.Pending()[0].Attempts)
+		}
+		{
+			exanteMock.Faults.Clear(exante.EndpointPlaceOrder)
+			res, err := c.Sync("acc-1", req)
+			assert.NoError(t, err)
+			assert.Contains(t, res.JournalF, "SENT")
+			assert.Len(t, pending.Pending(), 0)
+			activeOrder, _ := c.exanteApi.GetActiveOrdersV3()
+			assert.Len(t, activeOrder, 1)
+		}
+	})
+
+	t.Run("queued close should not be sent when the SL filled meanwhile", func(t *testing.T) {
+		parentOrderId := uuid.NewString()
+		orders := []exante.OrderV3{
+			{
+				AccountID:       "acc-1",
+				OrderState:      exante.OrderState{Status: exante.FilledStatus},
+				OrderParameters: exante.OrderParameters{Side: "buy", SymbolId: "EUR/USD"},
+				OrderID:         parentOrderId,
+				ClientTag:       "1234",
+			},
+			{
+				AccountID:  "acc-1",
+				OrderState: exante.OrderState{Status: exante.WorkingStatus},
+				OrderParameters: exante.OrderParameters{
+					IfDoneParentID: parentOrderId,
+					Side:           "sell",
+					OrderType:      "stop",
+					OcoGroup:       uuid.NewString(),
+				},
+				OrderID:   uuid.NewString(),
+				ClientTag: "1234",
+			},
+		}
+		exanteMock := exante.NewMock(orders)
+		exanteMock.Faults = exante.NewFaults(1)
+		exanteMock.Faults.Set(exante.EndpointPlaceOrder, exante.Fault{ServerErrRate: 1})
+		exanteMock.Faults.Set(exante.EndpointCancelOrder, exante.Fault{UnableToModify: true})
+		pending := queue.NewNoDisk()
+		pending.BaseBackoff = 0
+		c := New(exanteMock, orderdb.NewNoDisk(), &exchange, pending)
+
+		res, err := c.Sync("acc-1", SyncRequest{
+			RecentInactiveOrders: []Mt5Order{
+				{Symbol: "EURUSD", Ticket: "1235", Volume: 1, Type: OrderTypeSell, Price: 1.2, State: OrderStateFilled},
+			},
+			RecentInactivePositions: []Mt5PositionHistory{
+				{Symbol: "EURUSD", Ticket: "1235", PositionTicket: "1234", Volume: 1, Price: 1.2, Entry: DealEntryOut, Reason: DealReasonClient},
+			},
+		})
+		assert.NoError(t, err)
+		assert.Contains(t, res.JournalF, "QUEUED")
+
+		orders[1].OrderState.Status = exante.FilledStatus
+		exanteMock.Faults.Clear(exante.EndpointPlaceOrder)
+		placed := exanteMock.TotalPlaceOrderV3
+		res, err = c.Sync("acc-1", SyncRequest{})
+		assert.NoError(t, err)
+		assert.Contains(t, res.JournalF, "ALREADY CLOSED")
+		assert.Equal(t, placed, exanteMock.TotalPlaceOrderV3, "no reversed position")
+		assert.Len(t, pending.Pending(), 0)
+		group, _ := c.db.Get("1234")
+		assert.Equal(t, orderdb.GroupStatusClosed, group.Status)
+	})
+
+	t.Run("queued close should close the group once sent", func(t *testing.T) {
+		exanteMock := exante.NewMock([]exante.OrderV3{
+			{
+				AccountID:       "acc-1",
+				OrderState:      exante.OrderState{Status: exante.FilledStatus},
+				OrderParameters: exante.OrderParameters{Side: "buy", SymbolId: "EUR/USD"},
+				OrderID:         uuid.NewString(),
+				ClientTag:       "1234",
+			},
+		})
+		exanteMock.Faults = exante.NewFaults(1)
+		exanteMock.Faults.Set(exante.EndpointPlaceOrder, exante.Fault{ServerErrRate: 1})
+		pending := queue.NewNoDisk()
+		pending.BaseBackoff = 0
+		c := New(exanteMock, orderdb.NewNoDisk(), &exchange, pending)
+
+		_, err := c.Sync("acc-1", SyncRequest{
+			RecentInactiveOrders: []Mt5Order{
+				{Symbol: "EURUSD", Ticket: "1235", Volume: 1, Type: OrderTypeSell, Price: 1.2, State: OrderStateFilled},
+			},
+			RecentInactivePositions: []Mt5PositionHistory{
+				{Symbol: "EURUSD", Ticket: "1235", PositionTicket: "1234", Volume: 1, Price: 1.2, Entry: DealEntryOut, Reason: DealReasonClient},
+			},
+		})
+		assert.NoError(t, err)
+		assert.Len(t, pending.Pending(), 1)
+
+		exanteMock.Faults.Clear(exante.EndpointPlaceOrder)
+		res, err := c.Sync("acc-1", SyncRequest{})
+		assert.NoError(t, err)
+		assert.Contains(t, res.JournalF, "SENT")
+		group, _ := c.db.Get("1234")
+		assert.Equal(t, orderdb.GroupStatusClosed, group.Status)
+	})
+
+	t.Run("queued market order older than max age should be dead lettered", func(t *testing.T) {
+		exanteMock := exante.NewMock(make([]exante.OrderV3, 0))
+		exanteMock.Faults = exante.NewFaults(1)
+		exanteMock.Faults.Set(exante.EndpointPlaceOrder, exante.Fault{ServerErrRate: 1})
+		pending := queue.NewNoDisk()
+		c := New(exanteMock, orderdb.NewNoDisk(), &exchange, pending)
+		c.MarketOrderMaxAge = 0
+
+		_, err := c.Sync("acc-1", SyncRequest{
+			ActiveOrders: []Mt5Order{
+				{Symbol: "EURUSD", Ticket: "1234", Volume: 1, Type: OrderTypeBuy, Price: 1.2, State: OrderStatePlaced},
+			},
+		})
+		assert.NoError(t, err)
+
+		exanteMock.Faults.Clear(exante.EndpointPlaceOrder)
+		res, err := c.Sync("acc-1", SyncRequest{})
+		assert.NoError(t, err)
+		assert.Contains(t, res.JournalF, "EXPIRED")
+		assert.Len(t, pending.DeadLetters(), 1)
+		assert.Equal(t, 1, exanteMock.TotalPlaceOrderV3)
+
+		assert.NoError(t, pending.Replay("place-1234"))
+		c.MarketOrderMaxAge = time.Minute
+		_, err = c.Sync("acc-1", SyncRequest{})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, exanteMock.TotalPlaceOrderV3)
+	})
+	t.Run("orderdb should keep the ticket orders and OCO group across syncs", func(t *testing.T) {
+		exanteMock := exante.NewMock(make([]exante.OrderV3, 0))
+		db := orderdb.NewNoDisk()
+		c := New(exanteMock, db, &exchange, queue.NewNoDisk())
+
+		order := Mt5Order{Symbol: "EURUSD", Ticket: "1234", Volume: 1, Type: OrderTypeBuyLimit, Price: 1.2, State: OrderStatePlaced}
+		{
+			_, err := c.Sync("acc-1", SyncRequest{ActiveOrders: []Mt5Order{order}})
+			assert.NoError(t, err)
+		}
+
+		// an order with the same client tag placed outside the bridge is ignored
+		_, err := exanteMock.PlaceOrderV3(&exante.OrderSentTypeV3{
+			AccountID: "acc-1", OrderType: "limit", LimitPrice: "1.1", Quantity: "1", Side: "buy", ClientTag: "1234",
+		})
+		assert.NoError(t, err)
+
+		{ // add SL
+			order.StopLoss = 1
+			_, err := c.Sync("acc-1", SyncRequest{ActiveOrders: []Mt5Order{order}})
+			assert.NoError(t, err)
+		}
+		{ // add TP
+			order.TakeProfit = 2
+			_, err := c.Sync("acc-1", SyncRequest{ActiveOrders: []Mt5Order{order}})
+			assert.NoError(t, err)
+		}
+
+		group, has := db.Get("1234")
+		assert.True(t, has)
+		assert.Equal(t, orderdb.GroupStatusActive, group.Status)
+		assert.NotNil(t, group.StopLoss)
+		assert.NotNil(t, group.TakeProfit)
+
+		orders, _ := exanteMock.GetOrdersByLimitV3(100, "acc-1")
+		for _, o := range orders {
+			if o.OrderID == group.StopLoss.ID || o.OrderID == group.TakeProfit.ID {
+				assert.Equal(t, group.OcoGroup, o.OrderParameters.OcoGroup)
+			}
+		}
+		assert.Equal(t, "1.2", group.Order.Price)
+
+		{ // cancel
+			order.State = OrderStateCancelled
+			_, err := c.Sync("acc-1", SyncRequest{RecentInactiveOrders: []Mt5Order{order}})
+			assert.NoError(t, err)
+			group, _ = db.Get("1234")
+			assert.Equal(t, orderdb.GroupStatusCancelled, group.Status)
+		}
+
+		events, err := db.Events("1234")
+		assert.NoError(t, err)
+		types := make([]orderdb.EventType, 0)
+		for _, e := range events {
+			types = append(types, e.Type)
+		}
+		assert.Equal(t, []orderdb.EventType{
+			orderdb.EventSnapshotSeen, orderdb.EventPlaced,
+			orderdb.EventSnapshotSeen, orderdb.EventPlaced,
+			orderdb.EventSnapshotSeen, orderdb.EventPlaced,
+			orderdb.EventSnapshotSeen, orderdb.EventCancelled,
+		}, types)
+		replayed := orderdb.Replay(events)
+		assert.Equal(t, group.Order, replayed.Order)
+		assert.Equal(t, group.OcoGroup, replayed.OcoGroup)
+		assert.Equal(t, group.StopLoss, replayed.StopLoss)
+		assert.Equal(t, group.TakeProfit, replayed.TakeProfit)
+		assert.Equal(t, group.Status, replayed.Status)
+	})
+
+	t.Run("compaction should forget closed tickets and archive their groups", func(t *testing.T) {
+		exanteMock := exante.NewMock([]exante.OrderV3{})
+		db := orderdb.NewNoDisk()
+		c := New(exanteMock, db, &exchange, queue.NewNoDisk())
+
+		inactiveOrders := []Mt5Order{
+			{Symbol: "EURUSD", Ticket: "1234", Volume: 1, Type: OrderTypeBuy, Price: 1.2, State: OrderStateFilled},
+			{Symbol: "EURUSD", Ticket: "1235", Volume: 1, Type: OrderTypeSell, Price: 1.2, State: OrderStateFilled},
+		}
+		{ // open
+			_, err := c.Sync("acc-1", SyncRequest{
+				ActivePositions:      []Mt5Position{{Symbol: "EURUSD", Ticket: "1234", PositionTicket: "1234", Volume: 1, Price: 1.2}},
+				RecentInactiveOrders: inactiveOrders[:1],
+				RecentInactivePositions: []Mt5PositionHistory{
+					{Symbol: "EURUSD", Ticket: "1234", PositionTicket: "1234", Volume: 1, Price: 1.2, Entry: DealEntryIn},
+				},
+			})
+			assert.NoError(t, err)
+		}
+
+		now := time.Now()
+		res, err := c.Compact(now.Add(48 * time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, 0, res.Evicted, "position is still open on exante")
+
+		{ // close
+			_, err := c.Sync("acc-1", SyncRequest{
+				RecentInactiveOrders: inactiveOrders,
+				RecentInactivePositions: []Mt5PositionHistory{
+					{Symbol: "EURUSD", Ticket: "1234", PositionTicket: "1234", Volume: 1, Price: 1.2, Entry: DealEntryIn},
+					{Symbol: "EURUSD", Ticket: "1235", PositionTicket: "1234", Volume: 1, Price: 1.2, Entry: DealEntryOut},
+				},
+			})
+			assert.NoError(t, err)
+		}
+
+		res, err = c.Compact(now)
+		assert.NoError(t, err)
+		assert.Equal(t, Compaction{Archived: []string{}}, res, "MT5 still reports the deals")
+
+		res, err = c.Compact(now.Add(48 * time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, 3, res.Evicted)
+		assert.Len(t, res.Archived, 0)
+		assert.Len(t, c.history, 0)
+
+		res, err = c.Compact(now.Add(31 * 24 * time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"1234", "1235"}, res.Archived)
+		assert.Len(t, db.List(), 0)
+
+		archived, has, err := db.Archived("1234")
+		assert.NoError(t, err)
+		assert.True(t, has)
+		assert.Equal(t, orderdb.GroupStatusClosed, archived.Group.Status)
+		assert.NotEmpty(t, archived.Events)
+	})
+
+	t.Run("roll should move the position and its SL to the current contract", func(t *testing.T) {
+		now := time.Now()
+		futureExchange := exchanges.Api{
+			Data: exchanges.Data{
+				Exchanges: []exchanges.DataExchanges{
+					{
+						MetaTrader: "US500",
+						PriceStep:  1,
+						Rollover: &exchanges.Rollover{
+							RollDays: 5,
+							Contracts: []exchanges.FutureContract{
+								{Exante: "ES.CME.M", Expiry: now.AddDate(0, 0, 100).Format(time.DateOnly)},
+								{Exante: "ES.CME.H", Expiry: now.AddDate(0, 0, 10).Format(time.DateOnly)},
+							},
+						},
+					},
+				},
+			},
+		}
+		exanteMock := exante.NewMock([]exante.OrderV3{})
+		c := New(exanteMock, orderdb.NewNoDisk(), &futureExchange, queue.NewNoDisk())
+
+		position := SyncRequest{
+			ActivePositions:      []Mt5Position{{Symbol: "US500", Ticket: "1234", PositionTicket: "1234", Volume: 1, StopLoss: 4900, Price: 5000}},
+			RecentInactiveOrders: []Mt5Order{{Symbol: "US500", Ticket: "1234", Volume: 1, Type: OrderTypeBuy, Price: 5000, State: OrderStateFilled}},
+			RecentInactivePositions: []Mt5PositionHistory{
+				{Symbol: "US500", Ticket: "1234", PositionTicket: "1234", Volume: 1, StopLoss: 4900, Price: 5000, Entry: DealEntryIn},
+			},
+		}
+		_, err := c.Sync("acc-1", position)
+		assert.NoError(t, err)
+		group, _ := c.db.Get("1234")
+		assert.Equal(t, "ES.CME.H", group.Order.Symbol)
+		assert.NotNil(t, group.StopLoss)
+
+		rolled, err := c.Roll("acc-1", now)
+		assert.NoError(t, err)
+		assert.Len(t, rolled, 0, "roll date not reached")
+
+		rolled, err = c.Roll("acc-1", now.AddDate(0, 0, 6))
+		assert.NoError(t, err)
+		assert.Equal(t, []Rolled{{Ticket: "1234", From: "ES.CME.H", To: "ES.CME.M", Position: true}}, rolled)
+
+		group, _ = c.db.Get("1234")
+		assert.Equal(t, "ES.CME.M", group.Order.Symbol)
+		assert.Nil(t, group.StopLoss)
+
+		_, err = c.Sync("acc-1", position)
+		assert.NoError(t, err)
+		group, _ = c.db.Get("1234")
+		assert.NotNil(t, group.StopLoss)
+		assert.Equal(t, "ES.CME.M", group.StopLoss.Symbol)
+		activeOrders, _ := c.exanteApi.GetActiveOrdersV3()
+		assert.Len(t, activeOrders, 1)
+	})
+
+	t.Run("roll and sync running concurrently should not leave orders on the old contract", func(t *testing.T) {
+		now := time.Now()
+		futureExchange := exchanges.Api{
+			Data: exchanges.Data{
+				Exchanges: []exchanges.DataExchanges{
+					{
+						MetaTrader: "US500",
+						PriceStep:  1,
+						Rollover: &exchanges.Rollover{
+							RollDays: 5,
+							Contracts: []exchanges.FutureContract{
+								{Exante: "ES.CME.M", Expiry: now.AddDate(0, 0, 100).Format(time.DateOnly)},
+								{Exante: "ES.CME.H", Expiry: now.AddDate(0, 0, 10).Format(time.DateOnly)},
+							},
+						},
+					},
+				},
+			},
+		}
+		exanteMock := exante.NewMock([]exante.OrderV3{})
+		c := New(exanteMock, orderdb.NewNoDisk(), &futureExchange, queue.NewNoDisk())
+
+		position := SyncRequest{
+			ActivePositions:      []Mt5Position{{Symbol: "US500", Ticket: "1234", PositionTicket: "1234", Volume: 1, StopLoss: 4900, Price: 5000}},
+			RecentInactiveOrders: []Mt5Order{{Symbol: "US500", Ticket: "1234", Volume: 1, Type: OrderTypeBuy, Price: 5000, State: OrderStateFilled}},
+			RecentInactivePositions: []Mt5PositionHistory{
+				{Symbol: "US500", Ticket: "1234", PositionTicket: "1234", Volume: 1, StopLoss: 4900, Price: 5000, Entry: DealEntryIn},
+			},
+		}
+		_, err := c.Sync("acc-1", position)
+		assert.NoError(t, err)
+		exanteMock.Faults = exante.NewFaults(1)
+		exanteMock.Faults.Set(exante.EndpointListOrders, exante.Fault{Latency: time.Millisecond})
+
+		start := make(chan struct{})
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			<-start
+			_, err := c.Roll("acc-1", now.AddDate(0, 0, 6))
+			assert.NoError(t, err)
+		}()
+		go func() {
+			defer wg.Done()
+			<-start
+			moved := position
+			moved.ActivePositions = []Mt5Position{{Symbol: "US500", Ticket: "1234", PositionTicket: "1234", Volume: 1, StopLoss: 4950, Price: 5000}}
+			_, err := c.Sync("acc-1", moved)
+			assert.NoError(t, err)
+		}()
+		close(start)
+		wg.Wait()
+
+		group, _ := c.db.Get("1234")
+		assert.Equal(t, "ES.CME.M", group.Order.Symbol)
+		activeOrders, _ := c.exanteApi.GetActiveOrdersV3()
+		for _, order := range activeOrders {
+			assert.Equal(t, "ES.CME.M", order.OrderParameters.SymbolId)
+		}
+	})
+
+	t.Run("roll failing to open should resume from the open leg", func(t *testing.T) {
+		now := time.Now()
+		futureExchange := exchanges.Api{
+			Data: exchanges.Data{
+				Exchanges: []exchanges.DataExchanges{
+					{
+						MetaTrader: "US500",
+						PriceStep:  1,
+						Rollover: &exchanges.Rollover{
+							RollDays: 5,
+							Contracts: []exchanges.FutureContract{
+								{Exante: "ES.CME.H", Expiry: now.AddDate(0, 0, 10).Format(time.DateOnly)},
+								{Exante: "ES.CME.M", Expiry: now.AddDate(0, 0, 100).Format(time.DateOnly)},
+							},
+						},
+					},
+				},
+			},
+		}
+		exanteMock := exante.NewMock([]exante.OrderV3{})
+		c := New(exanteMock, orderdb.NewNoDisk(), &futureExchange, queue.NewNoDisk())
+
+		position := SyncRequest{
+			ActivePositions:      []Mt5Position{{Symbol: "US500", Ticket: "1234", PositionTicket: "1234", Volume: 1, Price: 5000}},
+			RecentInactiveOrders: []Mt5Order{{Symbol: "US500", Ticket: "1234", Volume: 1, Type: OrderTypeBuy, Price: 5000, State: OrderStateFilled}},
+			RecentInactivePositions: []Mt5PositionHistory{
+				{Symbol: "US500", Ticket: "1234", PositionTicket: "1234", Volume: 1, Price: 5000, Entry: DealEntryIn},
+			},
+		}
+		_, err := c.Sync("acc-1", position)
+		assert.NoError(t, err)
+
+		placeOrder := exanteMock.PlaceOrderV3Func
+		exanteMock.PlaceOrderV3Func = func(req *exante.OrderSentTypeV3) ([]exante.OrderV3, error) {
+			if req.SymbolID == "ES.CME.M" {
+				return nil, exante.ErrInternalServer
+			}
+			return placeOrder(req)
+		}
+		_, err = c.Roll("acc-1", now.AddDate(0, 0, 6))
+		assert.Error(t, err)
+		group, _ := c.db.Get("1234")
+		assert.True(t, group.Rolling)
+		assert.Equal(t, "ES.CME.H", group.Order.Symbol)
+
+		// MT5 still report the position, SL/TP wait for the roll
+		_, err = c.Sync("acc-1", SyncRequest{ActivePositions: []Mt5Position{{Symbol: "US500", Ticket: "1234", PositionTicket: "1234", Volume: 1, StopLoss: 4900, Price: 5000}}})
+		assert.NoError(t, err)
+
+		exanteMock.PlaceOrderV3Func = placeOrder
+		rolled, err := c.Roll("acc-1", now.AddDate(0, 0, 6))
+		assert.NoError(t, err)
+		assert.Equal(t, []Rolled{{Ticket: "1234", From: "ES.CME.H", To: "ES.CME.M", Position: true}}, rolled)
+
+		closeLegs := 0
+		allOrders, _ := c.exanteApi.GetOrdersByLimitV3(100, "acc-1")
+		for _, order := range allOrders {
+			if order.OrderParameters.SymbolId == "ES.CME.H" && order.OrderParameters.Side == "sell" {
+				closeLegs++
+				assert.Equal(t, "1234-roll-close", order.ClientTag)
+			}
+		}
+		assert.Equal(t, 1, closeLegs, "the contract must be closed once")
+
+		group, _ = c.db.Get("1234")
+		assert.False(t, group.Rolling)
+		assert.Equal(t, "ES.CME.M", group.Order.Symbol)
+	})
+
+	t.Run("orders placed through the api should be modified, cancelled and closed", func(t *testing.T) {
+		exanteMock := exante.NewMock([]exante.OrderV3{})
+		exanteMock.Faults = exante.NewFaults(1)
+		c := New(exanteMock, orderdb.NewNoDisk(), &exchange, queue.NewNoDisk())
+
+		_, err := c.PlaceOrder("acc-1", OrderRequest{Ticket: "api-1", Symbol: "GBPUSD", Side: "buy", OrderType: "limit", Volume: 1, Price: 1.2})
+		assert.ErrorIs(t, err, ErrInvalidOrder)
+
+		group, err := c.PlaceOrder("acc-1", OrderRequest{Ticket: "api-1", Symbol: "EURUSD", Side: "buy", OrderType: "limit", Volume: 1, Price: 1.1})
+		assert.NoError(t, err)
+		assert.Equal(t, "EUR/USD", group.Order.Symbol)
+		_, err = c.PlaceOrder("acc-1", OrderRequest{Ticket: "api-1", Symbol: "EURUSD", Side: "buy", OrderType: "limit", Volume: 1, Price: 1.1})
+		assert.ErrorIs(t, err, ErrTicketConflict)
+
+		stopLoss := 1.05
+		group, err = c.ModifyOrder("acc-1", "api-1", ModifyRequest{StopLoss: &stopLoss})
+		assert.NoError(t, err)
+		assert.NotNil(t, group.StopLoss)
+
+		exanteMock.Faults.Set(exante.EndpointReplaceOrder, exante.Fault{UnableToModify: true})
+		price := 1.12
+		_, err = c.ModifyOrder("acc-1", "api-1", ModifyRequest{Price: &price})
+		assert.ErrorIs(t, err, ErrTicketConflict, "exante refusing the change should be returned")
+		exanteMock.Faults.Set(exante.EndpointReplaceOrder, exante.Fault{})
+
+		_, err = c.ClosePosition("acc-1", "api-1")
+		assert.ErrorIs(t, err, ErrTicketConflict, "pending orders are cancelled")
+		group, err = c.CancelOrder("acc-1", "api-1")
+		assert.NoError(t, err)
+		assert.Equal(t, orderdb.GroupStatusCancelled, group.Status)
+		_, err = c.CancelOrder("acc-1", "api-1")
+		assert.ErrorIs(t, err, ErrTicketNotFound)
+
+		_, err = c.PlaceOrder("acc-1", OrderRequest{Ticket: "api-2", Symbol: "EURUSD", Side: "sell", OrderType: "market", Volume: 1})
+		assert.NoError(t, err)
+		_, err = c.CancelOrder("acc-1", "api-2")
+		assert.ErrorIs(t, err, ErrTicketConflict, "positions are closed")
+		group, err = c.ClosePosition("acc-1", "api-2")
+		assert.NoError(t, err)
+		assert.Equal(t, orderdb.GroupStatusClosed, group.Status)
+		_, err = c.ClosePosition("acc-1", "api-2")
+		assert.ErrorIs(t, err, ErrTicketNotFound, "a closed position must not be closed twice")
+	})
+
+	t.Run("SL/TP of a symbol removed from the mapping should not be sent", func(t *testing.T) {
+		exanteMock := exante.NewMock([]exante.OrderV3{})
+		c := New(exanteMock, orderdb.NewNoDisk(), &exchange, queue.NewNoDisk())
+
+		_, err := c.PlaceOrder("acc-1", OrderRequest{Ticket: "api-1", Symbol: "EURUSD", Side: "buy", OrderType: "limit", Volume: 1, Price: 1.1})
+		assert.NoError(t, err)
+		_, err = c.Sync("acc-1", SyncRequest{
+			ActiveOrders: []Mt5Order{{Symbol: "EURUSD", Ticket: "1234", Volume: 1, Type: OrderTypeBuyLimit, Price: 1.1, State: OrderStatePlaced}},
+			RecentInactiveOrders: []Mt5Order{
+				{Symbol: "EURUSD", Ticket: "1235", Volume: 1, Type: OrderTypeBuy, Price: 1.1, State: OrderStateFilled},
+			},
+			RecentInactivePositions: []Mt5PositionHistory{
+				{Symbol: "EURUSD", Ticket: "1235", PositionTicket: "1235", Volume: 1, Price: 1.1, Entry: DealEntryIn},
+			},
+		})
+		assert.NoError(t, err)
+		placed := exanteMock.TotalPlaceOrderV3
+
+		c.exchange = &exchanges.Api{}
+		res, err := c.Sync("acc-1", SyncRequest{
+			ActiveOrders:    []Mt5Order{{Symbol: "EURUSD", Ticket: "1234", Volume: 1, Type: OrderTypeBuyLimit, Price: 1.1, StopLoss: 1.05, State: OrderStatePlaced}},
+			ActivePositions: []Mt5Position{{Symbol: "EURUSD", Ticket: "1235", PositionTicket: "1235", Volume: 1, Price: 1.1, TakeProfit: 1.2}},
+		})
+		assert.NoError(t, err)
+		assert.Contains(t, res.JournalF, "[1234] ORD(ACTIVE) > EURUSD NOT MAPPED > SKIP")
+		assert.Contains(t, res.JournalF, "[1235] POS(ACTIVE) > EURUSD NOT MAPPED > SKIP")
+		assert.Equal(t, placed, exanteMock.TotalPlaceOrderV3)
+
+		stopLoss := 1.05
+		_, err = c.ModifyOrder("acc-1", "api-1", ModifyRequest{StopLoss: &stopLoss})
+		assert.ErrorIs(t, err, ErrInvalidOrder)
+		group, _ := c.db.Get("api-1")
+		assert.Nil(t, group.StopLoss)
+	})
+
+	t.Run("close refused as invalid should be reported once without failing the sync", func(t *testing.T) {
+		contractExchange := exchanges.Api{
+			Data: exchanges.Data{
+				Exchanges: []exchanges.DataExchanges{
+					{Exante: "US500.INDEX", MetaTrader: "US500", PriceStep: 1, Contract: exchanges.Contract{MaxQuantity: 10}},
+				},
+			},
+		}
+		exanteMock := exante.NewMock([]exante.OrderV3{})
+		c := New(exanteMock, orderdb.NewNoDisk(), &contractExchange, queue.NewNoDisk())
+
+		_, err := c.Sync("acc-1", SyncRequest{
+			RecentInactiveOrders: []Mt5Order{
+				{Symbol: "US500", Ticket: "1234", Volume: 5, Type: OrderTypeBuy, Price: 5000, State: OrderStateFilled},
+			},
+			RecentInactivePositions: []Mt5PositionHistory{
+				{Symbol: "US500", Ticket: "1234", PositionTicket: "1234", Volume: 5, Price: 5000, Entry: DealEntryIn},
+			},
+		})
+		assert.NoError(t, err)
+
+		closing := SyncRequest{
+			RecentInactiveOrders: []Mt5Order{
+				{Symbol: "US500", Ticket: "1235", Volume: 20, Type: OrderTypeSell, Price: 5000, State: OrderStateFilled},
+			},
+			RecentInactivePositions: []Mt5PositionHistory{
+				{Symbol: "US500", Ticket: "1235", PositionTicket: "1234", Volume: 20, Price: 5000, Entry: DealEntryOut},
+			},
+		}
+		res, err := c.Sync("acc-1", closing)
+		assert.NoError(t, err)
+		assert.Contains(t, res.JournalF, "[1234] POS(HIST) > ENTRY_OUT > ERROR")
+		assert.Contains(t, res.JournalF, "above the maximum")
+
+		res, err = c.Sync("acc-1", closing)
+		assert.NoError(t, err)
+		assert.NotContains(t, res.JournalF, "ENTRY_OUT")
+		assert.Equal(t, 1, exanteMock.TotalPlaceOrderV3)
+	})
+
+	t.Run("position closed through the api should not be closed again by the MT5 ENTRY_OUT", func(t *testing.T) {
+		exanteMock := exante.NewMock([]exante.OrderV3{})
+		c := New(exanteMock, orderdb.NewNoDisk(), &exchange, queue.NewNoDisk())
+
+		position := SyncRequest{
+			ActivePositions:      []Mt5Position{{Symbol: "EURUSD", Ticket: "1234", PositionTicket: "1234", Volume: 1, Price: 1.2}},
+			RecentInactiveOrders: []Mt5Order{{Symbol: "EURUSD", Ticket: "1234", Volume: 1, Type: OrderTypeBuy, Price: 1.2, State: OrderStateFilled}},
+			RecentInactivePositions: []Mt5PositionHistory{
+				{Symbol: "EURUSD", Ticket: "1234", PositionTicket: "1234", Volume: 1, Price: 1.2, Entry: DealEntryIn},
+			},
+		}
+		_, err := c.Sync("acc-1", position)
+		assert.NoError(t, err)
+
+		_, err = c.ClosePosition("acc-1", "1234")
+		assert.NoError(t, err)
+		placed := exanteMock.TotalPlaceOrderV3
+
+		_, err = c.Sync("acc-1", SyncRequest{
+			RecentInactiveOrders: []Mt5Order{
+				{Symbol: "EURUSD", Ticket: "1234", Volume: 1, Type: OrderTypeBuy, Price: 1.2, State: OrderStateFilled},
+				{Symbol: "EURUSD", Ticket: "1235", Volume: 1, Type: OrderTypeSell, Price: 1.2, State: OrderStateFilled},
+			},
+			RecentInactivePositions: []Mt5PositionHistory{
+				{Symbol: "EURUSD", Ticket: "1235", PositionTicket: "1234", Volume: 1, Price: 1.2, Entry: DealEntryOut, Reason: DealReasonClient},
+			},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, placed, exanteMock.TotalPlaceOrderV3, "no reversed position")
+		group, _ := c.db.Get("1234")
+		assert.Equal(t, orderdb.GroupStatusClosed, group.Status)
+	})
+	t.Run("sync and api closing the same position concurrently should close it once", func(t *testing.T) {
+		for i := 0; i < 5; i++ {
+			exanteMock := exante.NewMock([]exante.OrderV3{})
+			c := New(exanteMock, orderdb.NewNoDisk(), &exchange, queue.NewNoDisk())
+
+			_, err := c.Sync("acc-1", SyncRequest{
+				ActivePositions:      []Mt5Position{{Symbol: "EURUSD", Ticket: "1234", PositionTicket: "1234", Volume: 1, Price: 1.2}},
+				RecentInactiveOrders: []Mt5Order{{Symbol: "EURUSD", Ticket: "1234", Volume: 1, Type: OrderTypeBuy, Price: 1.2, State: OrderStateFilled}},
+				RecentInactivePositions: []Mt5PositionHistory{
+					{Symbol: "EURUSD", Ticket: "1234", PositionTicket: "1234", Volume: 1, Price: 1.2, Entry: DealEntryIn},
+				},
+			})
+			assert.NoError(t, err)
+			placed := exanteMock.TotalPlaceOrderV3
+			// slow lookups so both reach exante while the other one is running
+			exanteMock.Faults = exante.NewFaults(1)
+			exanteMock.Faults.Set(exante.EndpointListOrders, exante.Fault{Latency: time.Millisecond})
+
+			start := make(chan struct{})
+			var wg sync.WaitGroup
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				<-start
+				_, err := c.Sync("acc-1", SyncRequest{
+					RecentInactiveOrders: []Mt5Order{
+						{Symbol: "EURUSD", Ticket: "1234", Volume: 1, Type: OrderTypeBuy, Price: 1.2, State: OrderStateFilled},
+						{Symbol: "EURUSD", Ticket: "1235", Volume: 1, Type: OrderTypeSell, Price: 1.2, State: OrderStateFilled},
+					},
+					RecentInactivePositions: []Mt5PositionHistory{
+						{Symbol: "EURUSD", Ticket: "1235", PositionTicket: "1234", Volume: 1, Price: 1.2, Entry: DealEntryOut, Reason: DealReasonClient},
+					},
+				})
+				assert.NoError(t, err)
+			}()
+			go func() {
+				defer wg.Done()
+				<-start
+				_, err := c.ClosePosition("acc-1", "1234")
+				if err != nil {
+					assert.ErrorIs(t, err, ErrTicketNotFound)
+				}
+			}()
+			close(start)
+			wg.Wait()
+
+			assert.Equal(t, placed+1, exanteMock.TotalPlaceOrderV3, "a single reverse order")
+			group, _ := c.db.Get("1234")
+			assert.Equal(t, orderdb.GroupStatusClosed, group.Status)
+		}
+	})
 }
@@ -0,0 +1,151 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/danielsussa/mt5-to-exante/internal/utils"
+)
+
+// HistoryFile keep the requests already processed, inside the SDK folder
+const HistoryFile = ".history.json"
+
+const (
+	defaultRetention   = 30 * 24 * time.Hour
+	defaultHistoryIdle = 24 * time.Hour
+)
+
+// historyEntry is a processed request, Ticket is the group it belongs
+// to and SeenAt the last sync MT5 reported it
+type historyEntry struct {
+	Hash   string    `json:"hash"`
+	Ticket string    `json:"ticket"`
+	SeenAt time.Time `json:"seenAt"`
+}
+
+// Compaction is what Compact removed
+type Compaction struct {
+	Archived []string
+	Evicted  int
+}
+
+// isNewRequest return true if mt5Res changed since it was processed,
+// it also mark mt5Res as reported by MT5 now
+func (a *Api) isNewRequest(mt5Res Mt5Requests) bool {
+	a.historyMu.Lock()
+	defer a.historyMu.Unlock()
+
+	entry, has := a.history[mt5Res.WithTicket()]
+	if !has {
+		return true
+	}
+	entry.SeenAt = time.Now()
+	a.history[mt5Res.WithTicket()] = entry
+	return entry.Hash != utils.Hash(mt5Res)
+}
+
+func (a *Api) appendRequest(mt5Res Mt5Requests) {
+	a.historyMu.Lock()
+	defer a.historyMu.Unlock()
+
+	a.history[mt5Res.WithTicket()] = historyEntry{
+		Hash:   utils.Hash(mt5Res),
+		Ticket: mt5Res.GroupTicket(),
+		SeenAt: time.Now(),
+	}
+}
+
+// forget drop the requests of the group ticket, the next sync process
+// them again
+func (a *Api) forget(ticket string) {
+	a.historyMu.Lock()
+	defer a.historyMu.Unlock()
+
+	for key, entry := range a.history {
+		if entry.Ticket == ticket {
+			delete(a.history, key)
+		}
+	}
+}
+
+// Compact move the groups done for Retention to the orderdb archive and
+// forget the requests closed on both sides: MT5 didn't report them for
+// HistoryIdle and their group is done on exante
+func (a *Api) Compact(now time.Time) (Compaction, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	res := Compaction{}
+
+	a.historyMu.Lock()
+	for key, entry := range a.history {
+		if now.Sub(entry.SeenAt) < a.HistoryIdle {
+			continue
+		}
+		if group, has := a.db.Get(entry.Ticket); has && !group.Done() {
+			continue
+		}
+		delete(a.history, key)
+		res.Evicted++
+	}
+	a.historyMu.Unlock()
+
+	archived, err := a.db.Archive(now.Add(-a.Retention))
+	res.Archived = archived
+	return res, err
+}
+
+// SaveHistory write the processed requests to path, through a temp file
+// so a crash never leaves it half written
+func (a *Api) SaveHistory(path string) error {
+	a.historyMu.Lock()
+	defer a.historyMu.Unlock()
+
+	b, err := json.Marshal(a.history)
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	err = os.WriteFile(tmp, b, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// LoadHistory read the processed requests saved by SaveHistory, a missing file is empty
+func (a *Api) LoadHistory(path string) error {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	history := make(map[string]historyEntry)
+	err = json.Unmarshal(b, &history)
+	if err != nil {
+		// files written before retention only kept the hash
+		hashes := make(map[string]string)
+		if json.Unmarshal(b, &hashes) != nil {
+			return fmt.Errorf("cannot read history %s: %s", path, err.Error())
+		}
+		now := time.Now()
+		for key, hash := range hashes {
+			history[key] = historyEntry{Hash: hash, Ticket: key[strings.LastIndex(key, "-")+1:], SeenAt: now}
+		}
+	}
+
+	a.historyMu.Lock()
+	defer a.historyMu.Unlock()
+	for key, entry := range history {
+		a.history[key] = entry
+	}
+	return nil
+}
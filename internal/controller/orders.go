@@ -0,0 +1,303 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/danielsussa/mt5-to-exante/internal/exante"
+	"github.com/danielsussa/mt5-to-exante/internal/orderdb"
+	"github.com/danielsussa/mt5-to-exante/internal/utils"
+)
+
+var (
+	// ErrTicketNotFound is returned for tickets without exante orders
+	ErrTicketNotFound = errors.New("ticket not found")
+	// ErrTicketConflict is returned when the ticket state doesn't allow the change
+	ErrTicketConflict = errors.New("ticket conflict")
+	// ErrInvalidOrder is returned when the mapping or the symbol contract reject an order
+	ErrInvalidOrder = errors.New("invalid order")
+)
+
+// OrderSides and OrderTypes accepted by PlaceOrder
+var (
+	OrderSides = []string{"buy", "sell"}
+	OrderTypes = []string{"market", "limit"}
+)
+
+// OrderRequest is an order sent by a client other than the EA, with the
+// MT5 symbol, volume and prices so it goes through the same mapping
+type OrderRequest struct {
+	Ticket     string
+	Symbol     string
+	Side       string
+	OrderType  string
+	Volume     float64
+	Price      float64
+	StopLoss   float64
+	TakeProfit float64
+}
+
+// ModifyRequest change an order, nil fields are kept and a zero SL/TP
+// cancel it. Price can only change while the order is pending.
+type ModifyRequest struct {
+	Price      *float64
+	StopLoss   *float64
+	TakeProfit *float64
+}
+
+// PlaceOrder place req on exante as the EA would place a MT5 order of
+// the same ticket
+func (a *Api) PlaceOrder(accountID string, req OrderRequest) (orderdb.OrderGroup, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	exchange, has := a.exchange.GetByMTValue(req.Symbol)
+	if !has || !exchange.IsEnabled() {
+		return orderdb.OrderGroup{}, fmt.Errorf("%w: symbol %s is not mapped or disabled", ErrInvalidOrder, req.Symbol)
+	}
+
+	// a response dropped after exante placed the ticket is found by client tag
+	existing, err := a.findActiveAndFilledOrdersByTicket(req.Ticket, accountID)
+	if err != nil {
+		return orderdb.OrderGroup{}, err
+	}
+	if len(existing) > 0 {
+		return orderdb.OrderGroup{}, fmt.Errorf("%w: ticket %s already placed", ErrTicketConflict, req.Ticket)
+	}
+
+	order := Mt5Order{
+		Symbol:     req.Symbol,
+		Ticket:     req.Ticket,
+		Volume:     req.Volume,
+		Type:       mt5OrderType(req.Side, req.OrderType),
+		TakeProfit: req.TakeProfit,
+		StopLoss:   req.StopLoss,
+		Price:      req.Price,
+		State:      OrderStatePlaced,
+	}
+	orders, err := a.placeNewOrder(accountID, order)
+	if err != nil {
+		return orderdb.OrderGroup{}, err
+	}
+	if len(orders) == 0 {
+		return orderdb.OrderGroup{}, fmt.Errorf("no order registered by exante for %s", req.Ticket)
+	}
+	// ModifyOrder read the symbol back from it
+	if err = a.recordSnapshot(req.Ticket, order); err != nil {
+		return orderdb.OrderGroup{}, err
+	}
+
+	group, _ := a.db.Get(req.Ticket)
+	return group, nil
+}
+
+// ModifyOrder change the price and SL/TP of ticket, prices are MT5 prices
+// of the symbol the ticket was placed with
+func (a *Api) ModifyOrder(accountID string, ticket string, req ModifyRequest) (orderdb.OrderGroup, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	order, has := a.lastSnapshot(ticket)
+	if !has {
+		return orderdb.OrderGroup{}, fmt.Errorf("%w: %s", ErrTicketNotFound, ticket)
+	}
+	exchange, has := a.exchange.GetByMTValue(order.Symbol)
+	if !has {
+		return orderdb.OrderGroup{}, fmt.Errorf("%w: symbol %s is not mapped", ErrInvalidOrder, order.Symbol)
+	}
+
+	orders, parent, err := a.activeOrders(accountID, ticket)
+	if err != nil {
+		return orderdb.OrderGroup{}, err
+	}
+	ocoGroup := a.ocoGroup(ticket, orders)
+
+	if req.Price != nil {
+		if parent.OrderState.Status == exante.FilledStatus {
+			return orderdb.OrderGroup{}, fmt.Errorf("%w: %s is filled, its price cannot change", ErrTicketConflict, ticket)
+		}
+		if parent.OrderParameters.LimitPrice != a.formatPrice(parent.OrderParameters.SymbolId, exchange.Price(*req.Price)) {
+			if err = a.replaceLimitPrice(exchange.Price(*req.Price), parent.OrderID); err != nil {
+				return orderdb.OrderGroup{}, err
+			}
+		}
+		order.Price = *req.Price
+	}
+
+	if req.StopLoss != nil {
+		slOrder, hasSlOrder := a.stopLossOrder(ticket, orders)
+		switch {
+		case !hasSlOrder && *req.StopLoss > 0:
+			_, err = a.placeStopLoss(ticket, exchange, *req.StopLoss, *parent, ocoGroup)
+		case hasSlOrder && *req.StopLoss == 0:
+			err = a.cancelOrder(ticket, slOrder.OrderID)
+		case hasSlOrder && slOrder.OrderParameters.StopPrice != a.formatPrice(slOrder.OrderParameters.SymbolId, exchange.Price(*req.StopLoss)):
+			err = a.replaceStopPrice(exchange.Price(*req.StopLoss), slOrder.OrderID)
+		}
+		if err != nil {
+			return orderdb.OrderGroup{}, err
+		}
+		order.StopLoss = *req.StopLoss
+	}
+
+	if req.TakeProfit != nil {
+		tpOrder, hasTpOrder := a.takeProfitOrder(ticket, orders)
+		switch {
+		case !hasTpOrder && *req.TakeProfit > 0:
+			_, err = a.placeTakeProfit(ticket, exchange, *req.TakeProfit, *parent, ocoGroup)
+		case hasTpOrder && *req.TakeProfit == 0:
+			err = a.cancelOrder(ticket, tpOrder.OrderID)
+		case hasTpOrder && tpOrder.OrderParameters.LimitPrice != a.formatPrice(tpOrder.OrderParameters.SymbolId, exchange.Price(*req.TakeProfit)):
+			err = a.replaceLimitPrice(exchange.Price(*req.TakeProfit), tpOrder.OrderID)
+		}
+		if err != nil {
+			return orderdb.OrderGroup{}, err
+		}
+		order.TakeProfit = *req.TakeProfit
+	}
+
+	if err = a.recordSnapshot(ticket, order); err != nil {
+		return orderdb.OrderGroup{}, err
+	}
+	group, _ := a.db.Get(ticket)
+	return group, nil
+}
+
+// CancelOrder cancel the pending order of ticket with its SL/TP,
+// positions must be closed instead
+func (a *Api) CancelOrder(accountID string, ticket string) (orderdb.OrderGroup, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	_, parent, err := a.activeOrders(accountID, ticket)
+	if err != nil {
+		return orderdb.OrderGroup{}, err
+	}
+	if parent.OrderState.Status == exante.FilledStatus {
+		return orderdb.OrderGroup{}, fmt.Errorf("%w: %s is a position, close it instead", ErrTicketConflict, ticket)
+	}
+
+	if err = a.cancelOrder(ticket, parent.OrderID); err != nil {
+		return orderdb.OrderGroup{}, err
+	}
+	group, _ := a.db.Get(ticket)
+	return group, nil
+}
+
+// ClosePosition cancel the SL/TP of the ticket position and close it on
+// the contract it is held. The group is closed, so the ENTRY_OUT a MT5
+// ticket reports later doesn't close it again.
+func (a *Api) ClosePosition(accountID string, ticket string) (orderdb.OrderGroup, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	orders, parent, err := a.activeOrders(accountID, ticket)
+	if err != nil {
+		return orderdb.OrderGroup{}, err
+	}
+	if utils.IsPositionClosed(orders) {
+		return orderdb.OrderGroup{}, fmt.Errorf("%w: %s has no open position", ErrTicketNotFound, ticket)
+	}
+	if parent.OrderState.Status != exante.FilledStatus {
+		return orderdb.OrderGroup{}, fmt.Errorf("%w: %s is a pending order, cancel it instead", ErrTicketConflict, ticket)
+	}
+
+	if slOrder, has := a.stopLossOrder(ticket, orders); has {
+		if err = a.cancelOrder(ticket, slOrder.OrderID); err != nil {
+			return orderdb.OrderGroup{}, err
+		}
+	}
+	if tpOrder, has := a.takeProfitOrder(ticket, orders); has {
+		if err = a.cancelOrder(ticket, tpOrder.OrderID); err != nil {
+			return orderdb.OrderGroup{}, err
+		}
+	}
+
+	_, err = a.exanteApi.PlaceOrderV3(&exante.OrderSentTypeV3{
+		AccountID:  parent.AccountID,
+		Instrument: parent.OrderParameters.SymbolId,
+		Side:       utils.GetReverseOrderSide(parent.OrderParameters.Side),
+		Quantity:   parent.OrderParameters.Quantity,
+		Duration:   "good_till_cancel",
+		OrderType:  "market",
+		SymbolID:   parent.OrderParameters.SymbolId,
+		ClientTag:  fmt.Sprintf("%s-close", ticket),
+	})
+	if err != nil {
+		return orderdb.OrderGroup{}, err
+	}
+
+	if err = a.closeGroup(ticket); err != nil {
+		return orderdb.OrderGroup{}, err
+	}
+	group, _ := a.db.Get(ticket)
+	return group, nil
+}
+
+// TicketOrders return the group of ticket and its exante orders, the
+// fills and cancels exante did are tracked on the way
+func (a *Api) TicketOrders(accountID string, ticket string) (orderdb.OrderGroup, []exante.OrderV3, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	orders, err := a.findOrdersByTicket(ticket, accountID, exante.WorkingStatus, exante.PendingStatus, exante.FilledStatus, exante.CancelledStatus)
+	if err != nil {
+		return orderdb.OrderGroup{}, nil, err
+	}
+	group, has := a.db.Get(ticket)
+	if !has {
+		return orderdb.OrderGroup{}, nil, fmt.Errorf("%w: %s", ErrTicketNotFound, ticket)
+	}
+	return group, orders, nil
+}
+
+// activeOrders return the orders of ticket and its parent, tickets whose
+// group was closed or cancelled are not found
+func (a *Api) activeOrders(accountID string, ticket string) ([]exante.OrderV3, *exante.OrderV3, error) {
+	if group, has := a.mapping(ticket); has && group.Status != orderdb.GroupStatusActive {
+		return nil, nil, fmt.Errorf("%w: %s is %s", ErrTicketNotFound, ticket, group.Status)
+	}
+
+	orders, err := a.findActiveAndFilledOrdersByTicket(ticket, accountID)
+	if err != nil {
+		return nil, nil, err
+	}
+	parent, has := a.parentOrder(ticket, orders)
+	if !has {
+		return nil, nil, fmt.Errorf("%w: %s has no active order", ErrTicketNotFound, ticket)
+	}
+	return orders, parent, nil
+}
+
+// lastSnapshot return the last MT5 state recorded for ticket, positions
+// are read as orders since only the symbol and prices are needed
+func (a *Api) lastSnapshot(ticket string) (Mt5Order, bool) {
+	events, err := a.db.Events(ticket)
+	if err != nil {
+		return Mt5Order{}, false
+	}
+	for idx := len(events) - 1; idx >= 0; idx-- {
+		e := events[idx]
+		if e.Type != orderdb.EventSnapshotSeen {
+			continue
+		}
+		var order Mt5Order
+		if json.Unmarshal(e.Snapshot, &order) == nil && len(order.Symbol) > 0 {
+			return order, true
+		}
+	}
+	return Mt5Order{}, false
+}
+
+func mt5OrderType(side string, orderType string) OrderType {
+	switch {
+	case side == "buy" && orderType == "market":
+		return OrderTypeBuy
+	case side == "buy":
+		return OrderTypeBuyLimit
+	case orderType == "market":
+		return OrderTypeSell
+	}
+	return OrderTypeSellLimit
+}
@@ -0,0 +1,138 @@
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/danielsussa/mt5-to-exante/internal/exante"
+	"github.com/danielsussa/mt5-to-exante/internal/orderdb"
+	"github.com/danielsussa/mt5-to-exante/internal/utils"
+)
+
+// Rolled is a ticket moved from a contract past its roll date to the
+// current contract of its exchange
+type Rolled struct {
+	Ticket   string
+	From     string
+	To       string
+	Position bool
+}
+
+// Roll move the tickets of accountID held on a contract past its roll date
+// to the current contract. Positions are closed and opened again with the
+// same quantity, pending orders are cancelled. SL/TP and pending orders are
+// placed again from MT5 on the next sync, with the current contract.
+// Sync waits for the whole roll, so it never acts on a ticket half rolled.
+func (a *Api) Roll(accountID string, now time.Time) ([]Rolled, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	groups := a.db.List()
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Ticket < groups[j].Ticket })
+
+	rolled := make([]Rolled, 0)
+	for _, group := range groups {
+		if group.Status != orderdb.GroupStatusActive || len(group.Order.ID) == 0 {
+			continue
+		}
+		if len(group.Order.AccountId) > 0 && group.Order.AccountId != accountID {
+			continue
+		}
+		to, has := a.exchange.RollTo(group.Order.Symbol, now)
+		if !has {
+			continue
+		}
+
+		r, has, err := a.rollGroup(accountID, group.Ticket, to)
+		if err != nil {
+			return rolled, fmt.Errorf("cannot roll %s from %s to %s: %s", group.Ticket, group.Order.Symbol, to, err.Error())
+		}
+		if has {
+			rolled = append(rolled, r)
+		}
+	}
+	return rolled, nil
+}
+
+// rollGroup close the position of ticket on its contract and open it on
+// to. The close leg is recorded on the group first, a roll that failed to
+// open is resumed from the open leg instead of closing the contract again.
+func (a *Api) rollGroup(accountID string, ticket string, to string) (Rolled, bool, error) {
+	group, _ := a.db.Get(ticket)
+	orders, err := a.findActiveAndFilledOrdersByTicket(ticket, accountID)
+	if err != nil {
+		return Rolled{}, false, err
+	}
+	parent, has := a.parentOrder(ticket, orders)
+	if !has {
+		return Rolled{}, false, nil
+	}
+	r := Rolled{Ticket: ticket, From: parent.OrderParameters.SymbolId, To: to}
+
+	if !group.Rolling {
+		if slOrder, has := a.stopLossOrder(ticket, orders); has {
+			if err = a.cancelOrder(ticket, slOrder.OrderID); err != nil {
+				return r, false, err
+			}
+		}
+		if tpOrder, has := a.takeProfitOrder(ticket, orders); has {
+			if err = a.cancelOrder(ticket, tpOrder.OrderID); err != nil {
+				return r, false, err
+			}
+		}
+
+		if parent.OrderState.Status != exante.FilledStatus {
+			if err = a.cancelOrder(ticket, parent.OrderID); err != nil {
+				return r, false, err
+			}
+			a.forget(ticket)
+			return r, true, nil
+		}
+
+		closed, err := a.exanteApi.PlaceOrderV3(&exante.OrderSentTypeV3{
+			AccountID:  parent.AccountID,
+			Instrument: r.From,
+			Side:       utils.GetReverseOrderSide(parent.OrderParameters.Side),
+			Quantity:   parent.OrderParameters.Quantity,
+			Duration:   "good_till_cancel",
+			OrderType:  "market",
+			SymbolID:   r.From,
+			ClientTag:  rollTag(ticket, "close"),
+		})
+		if err != nil {
+			return r, false, err
+		}
+		rolling := orderdb.Event{Type: orderdb.EventRolling}
+		if len(closed) > 0 {
+			rolling.OrderID = closed[0].OrderID
+		}
+		if _, err = a.db.Append(ticket, rolling); err != nil {
+			return r, false, err
+		}
+	}
+
+	r.Position = true
+	opened, err := a.exanteApi.PlaceOrderV3(&exante.OrderSentTypeV3{
+		AccountID:  parent.AccountID,
+		Instrument: r.To,
+		Side:       parent.OrderParameters.Side,
+		Quantity:   parent.OrderParameters.Quantity,
+		Duration:   "good_till_cancel",
+		OrderType:  "market",
+		SymbolID:   r.To,
+		ClientTag:  rollTag(ticket, "open"),
+	})
+	if err != nil {
+		return r, false, fmt.Errorf("%s closed but not opened again, the next roll opens it: %s", r.From, err.Error())
+	}
+
+	a.forget(ticket)
+	return r, true, a.placeGroup(ticket, opened)
+}
+
+// rollTag is the client tag of a roll leg, so the legs are not taken for
+// orders of the ticket when its orders are matched by tag
+func rollTag(ticket string, leg string) string {
+	return fmt.Sprintf("%s-roll-%s", ticket, leg)
+}
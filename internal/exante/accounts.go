@@ -0,0 +1,124 @@
+package exante
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	AccountStatusFull      = "Full"
+	AccountStatusReadOnly  = "ReadOnly"
+	AccountStatusCloseOnly = "CloseOnly"
+	AccountStatusBlocked   = "Blocked"
+)
+
+// placeholders left in the sample env files
+var accountPlaceholders = []string{"", "ID_HERE", "INSERT_VALUE"}
+
+// CanTrade return true when the account accepts new orders
+func (u UserAccount) CanTrade() bool {
+	return u.Status == AccountStatusFull
+}
+
+// ParseAccountAliases parse aliases in the format "main=ABC1234.001,hedge=ABC1234.002"
+func ParseAccountAliases(s string) (map[string]string, error) {
+	aliases := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if len(pair) == 0 {
+			continue
+		}
+
+		alias, accountID, found := strings.Cut(pair, "=")
+		alias = strings.TrimSpace(alias)
+		accountID = strings.TrimSpace(accountID)
+		if !found || len(alias) == 0 || len(accountID) == 0 {
+			return nil, fmt.Errorf("invalid account alias %q, expected alias=ACCOUNT_ID", pair)
+		}
+		if _, has := aliases[alias]; has {
+			return nil, fmt.Errorf("duplicated account alias %q", alias)
+		}
+		aliases[alias] = accountID
+	}
+	return aliases, nil
+}
+
+// SelectAccount find the account matching selector, which can be an account id
+// or an alias. An empty selector pick the only tradable account, if there is one.
+func SelectAccount(accounts UserAccounts, selector string, aliases map[string]string) (UserAccount, error) {
+	selector = strings.TrimSpace(selector)
+	for _, placeholder := range accountPlaceholders {
+		if selector == placeholder {
+			return selectOnlyAccount(accounts)
+		}
+	}
+
+	accountID := selector
+	if aliased, has := aliases[selector]; has {
+		accountID = aliased
+	}
+
+	for _, account := range accounts {
+		if account.AccountID != accountID {
+			continue
+		}
+		if !account.CanTrade() {
+			return UserAccount{}, fmt.Errorf("account %s cannot trade, status: %s", accountID, account.Status)
+		}
+		return account, nil
+	}
+
+	if accountID == selector && len(aliases) > 0 {
+		return UserAccount{}, fmt.Errorf("%s is neither an account nor an alias of ACCOUNT_ALIASES, available: %s", selector, accounts.ids())
+	}
+	return UserAccount{}, fmt.Errorf("account %s not found, available: %s", accountID, accounts.ids())
+}
+
+// DiscoverAccount validate accountID, an account id or an alias of
+// aliases as set on ACCOUNT_ID and ACCOUNT_ALIASES, against the accounts
+// available on exante
+func (a Api) DiscoverAccount(accountID string, aliases string) (UserAccount, error) {
+	parsed, err := ParseAccountAliases(aliases)
+	if err != nil {
+		return UserAccount{}, fmt.Errorf("ACCOUNT_ALIASES: %s", err.Error())
+	}
+
+	accounts, err := a.GetUserAccounts()
+	if err != nil {
+		return UserAccount{}, fmt.Errorf("cannot list exante accounts to check ACCOUNT_ID: %s", err.Error())
+	}
+	if accounts == nil {
+		accounts = &UserAccounts{}
+	}
+
+	account, err := SelectAccount(*accounts, accountID, parsed)
+	if err != nil {
+		return UserAccount{}, fmt.Errorf("ACCOUNT_ID %q: %s", accountID, err.Error())
+	}
+	return account, nil
+}
+
+func selectOnlyAccount(accounts UserAccounts) (UserAccount, error) {
+	tradable := make(UserAccounts, 0)
+	for _, account := range accounts {
+		if account.CanTrade() {
+			tradable = append(tradable, account)
+		}
+	}
+
+	switch len(tradable) {
+	case 0:
+		return UserAccount{}, fmt.Errorf("no tradable account found, available: %s", accounts.ids())
+	case 1:
+		return tradable[0], nil
+	}
+	return UserAccount{}, fmt.Errorf("more than one tradable account, set ACCOUNT_ID to one of: %s", tradable.ids())
+}
+
+func (u UserAccounts) ids() string {
+	ids := make([]string, 0, len(u))
+	for _, account := range u {
+		ids = append(ids, fmt.Sprintf("%s (%s)", account.AccountID, account.Status))
+	}
+	return strings.Join(ids, ", ")
+}
@@ -0,0 +1,79 @@
+package exante
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectAccount(t *testing.T) {
+	accounts := UserAccounts{
+		{AccountID: "ABC1234.001", Status: AccountStatusFull},
+		{AccountID: "ABC1234.002", Status: AccountStatusFull},
+		{AccountID: "ABC1234.003", Status: AccountStatusReadOnly},
+	}
+	aliases, err := ParseAccountAliases("main=ABC1234.001, hedge=ABC1234.002,old=ABC1234.003,gone=ABC1234.009")
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		selector string
+		expected string
+		err      string
+	}{
+		{name: "account id", selector: "ABC1234.002", expected: "ABC1234.002"},
+		{name: "alias", selector: "main", expected: "ABC1234.001"},
+		{name: "alias with spaces", selector: " hedge ", expected: "ABC1234.002"},
+		{name: "unknown alias", selector: "prod", err: "prod is neither an account nor an alias"},
+		{name: "alias of a missing account", selector: "gone", err: "account ABC1234.009 not found"},
+		{name: "read only account", selector: "old", err: "cannot trade"},
+		{name: "placeholder with many tradable accounts", selector: "ID_HERE", err: "more than one tradable account"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			account, err := SelectAccount(accounts, tt.selector, aliases)
+			if len(tt.err) > 0 {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, account.AccountID)
+		})
+	}
+
+	t.Run("empty selector should pick the only tradable account", func(t *testing.T) {
+		account, err := SelectAccount(accounts[1:], "", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "ABC1234.002", account.AccountID)
+	})
+
+	t.Run("invalid aliases should fail", func(t *testing.T) {
+		_, err := ParseAccountAliases("main")
+		assert.Error(t, err)
+		_, err = ParseAccountAliases("main=A,main=B")
+		assert.ErrorContains(t, err, "duplicated")
+	})
+}
+
+func TestDiscoverAccount(t *testing.T) {
+	fake := NewFakeServer()
+	defer fake.Close()
+	fake.SetAccounts(UserAccounts{
+		{AccountID: "ABC1234.001", Status: AccountStatusFull},
+		{AccountID: "ABC1234.002", Status: AccountStatusFull},
+	})
+	api := fake.NewApi()
+
+	t.Run("alias should resolve to its account", func(t *testing.T) {
+		account, err := api.DiscoverAccount("hedge", "main=ABC1234.001,hedge=ABC1234.002")
+		assert.NoError(t, err)
+		assert.Equal(t, "ABC1234.002", account.AccountID)
+	})
+
+	t.Run("errors should name the setting", func(t *testing.T) {
+		_, err := api.DiscoverAccount("", "")
+		assert.ErrorContains(t, err, `ACCOUNT_ID "": more than one tradable account`)
+		_, err = api.DiscoverAccount("main", "main")
+		assert.ErrorContains(t, err, "ACCOUNT_ALIASES: invalid account alias")
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/go-resty/resty/v2"
 	"github.com/peterbourgon/diskv/v3"
 	"net/http"
+	"net/url"
 	"time"
 )
 
@@ -24,6 +25,15 @@ func (e ErrorResponse) Error() string {
 	return fmt.Sprintf("error: %s", e.Message)
 }
 
+var ErrInternalServer = errors.New("internal server error")
+
+// IsTemporary return true when err is caused by exante being unavailable
+// and the same call may succeed later
+func IsTemporary(err error) bool {
+	var urlErr *url.Error
+	return errors.Is(err, ErrInternalServer) || IsCircuitOpen(err) || errors.As(err, &urlErr)
+}
+
 type ReplaceOrderResponse struct {
 	OrderId string
 }
@@ -36,6 +46,9 @@ type Api struct {
 	cli           *resty.Client
 	d             *diskv.Diskv
 	jwt           string
+	instruments   *instrumentCache
+	breaker       *CircuitBreaker
+	streamBackoff time.Duration
 }
 
 func NewApi(baseUrl, appID, cliID, sharedKey string) *Api {
@@ -63,14 +76,26 @@ func NewApi(baseUrl, appID, cliID, sharedKey string) *Api {
 		CacheSizeMax: 1024 * 1024,
 	})
 
-	return &Api{
+	api := &Api{
 		BaseURL:       baseUrl,
 		ApplicationID: appID,
 		ClientID:      cliID,
 		SharedKey:     sharedKey,
 		cli:           client,
 		d:             d,
+		instruments:   newInstrumentCache(),
+		breaker:       NewCircuitBreaker(defaultBreakerThreshold, defaultBreakerCooldown),
+		streamBackoff: defaultStreamBackoff,
 	}
+	api.setCircuitBreaker(api.breaker)
+
+	return api
+}
+
+// SetTransport replace the http transport of the client, it allows
+// recording and replaying exante sessions
+func (a Api) SetTransport(rt http.RoundTripper) {
+	a.cli.SetTransport(rt)
 }
 
 var Scopes = []string{
@@ -144,11 +169,8 @@ func (a Api) CancelOrder(orderID string) error {
 		return err
 	}
 
-	//res, _ := httputil.DumpRequest(resp.Request.RawRequest, true)
-	//fmt.Print(string(res))
-
 	if resp.StatusCode() >= http.StatusInternalServerError {
-		return fmt.Errorf("internal server error")
+		return ErrInternalServer
 	}
 
 	if resp.IsError() {
@@ -175,10 +197,17 @@ type OrderSentTypeV3 struct {
 	TakeProfit     *string `json:"takeProfit,omitempty"`
 	StopLoss       *string `json:"stopLoss,omitempty"`
 	StopPrice      *string `json:"stopPrice,omitempty"`
+	PriceDistance  string  `json:"priceDistance,omitempty"`
+	PartQuantity   string  `json:"partQuantity,omitempty"`
+	PlaceInterval  string  `json:"placeInterval,omitempty"`
+	GttExpiration  string  `json:"gttExpiration,omitempty"`
 	SymbolID       string  `json:"symbolId"`
 }
 
 func (a Api) PlaceOrderV3(req *OrderSentTypeV3) ([]OrderV3, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
 
 	var result []OrderV3
 	var errRes []ErrorResponse
@@ -195,7 +224,7 @@ func (a Api) PlaceOrderV3(req *OrderSentTypeV3) ([]OrderV3, error) {
 	}
 
 	if resp.StatusCode() >= http.StatusInternalServerError {
-		return nil, fmt.Errorf("internal server error")
+		return nil, ErrInternalServer
 	}
 
 	if resp.IsError() {
@@ -283,7 +312,7 @@ func (a Api) GetActiveOrdersV3() (OrdersV3, error) {
 	}
 
 	if resp.StatusCode() >= http.StatusInternalServerError {
-		return nil, fmt.Errorf("internal server error")
+		return nil, ErrInternalServer
 	}
 
 	if resp.IsError() {
@@ -314,7 +343,7 @@ func (a Api) GetOrdersByLimitV3(limit int, accountID string) (OrdersV3, error) {
 	}
 
 	if resp.StatusCode() >= http.StatusInternalServerError {
-		return nil, fmt.Errorf("internal server error")
+		return nil, ErrInternalServer
 	}
 
 	if resp.IsError() {
@@ -343,7 +372,7 @@ func (a Api) GetOrder(orderID string) (*OrderV3, error) {
 	}
 
 	if resp.StatusCode() >= http.StatusInternalServerError {
-		return nil, fmt.Errorf("internal server error")
+		return nil, ErrInternalServer
 	}
 
 	if resp.IsError() {
@@ -373,7 +402,7 @@ func (a Api) ReplaceOrder(orderID string, req ReplaceOrderPayload) (*OrderV3, er
 	}
 
 	if resp.StatusCode() >= http.StatusInternalServerError {
-		return nil, fmt.Errorf("internal server error")
+		return nil, ErrInternalServer
 	}
 
 	if resp.IsError() {
@@ -408,7 +437,7 @@ func (a Api) GetUserAccounts() (*UserAccounts, error) {
 	}
 
 	if resp.StatusCode() >= http.StatusInternalServerError {
-		return nil, fmt.Errorf("internal server error")
+		return nil, ErrInternalServer
 	}
 
 	if resp.IsError() {
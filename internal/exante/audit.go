@@ -0,0 +1,110 @@
+package exante
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/danielsussa/mt5-to-exante/internal/audit"
+	"github.com/go-resty/resty/v2"
+)
+
+type AuditLogger interface {
+	Append(e audit.Entry) error
+}
+
+// SetAuditLog write every mutating call (place, replace and cancel)
+// to log, with credentials redacted
+func (a Api) SetAuditLog(log AuditLogger) {
+	a.cli.OnAfterResponse(func(c *resty.Client, resp *resty.Response) error {
+		if entry, ok := auditEntry(resp.Request, resp, nil); ok {
+			appendAudit(log, entry)
+		}
+		return nil
+	})
+	a.cli.OnError(func(req *resty.Request, err error) {
+		if respErr, ok := err.(*resty.ResponseError); ok {
+			// responses are already logged by OnAfterResponse
+			if respErr.Response != nil && respErr.Response.RawResponse != nil {
+				return
+			}
+			err = respErr.Err
+		}
+		if entry, ok := auditEntry(req, nil, err); ok {
+			appendAudit(log, entry)
+		}
+	})
+}
+
+func appendAudit(log AuditLogger, entry audit.Entry) {
+	if err := log.Append(entry); err != nil {
+		fmt.Println("cannot write audit log: ", err.Error())
+	}
+}
+
+func auditEntry(req *resty.Request, resp *resty.Response, err error) (audit.Entry, bool) {
+	if req == nil || req.Method != http.MethodPost {
+		return audit.Entry{}, false
+	}
+
+	path := req.URL
+	if idx := strings.Index(path, "/trade/3.0/orders"); idx > -1 {
+		path = path[idx:]
+	} else {
+		return audit.Entry{}, false
+	}
+
+	reqBody, _ := json.Marshal(req.Body)
+	entry := audit.Entry{
+		Time:        req.Time,
+		Method:      req.Method,
+		URL:         path,
+		RequestBody: redactBody(reqBody),
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+
+	switch body := req.Body.(type) {
+	case *OrderSentTypeV3:
+		entry.Operation = audit.OperationPlace
+		entry.Ticket = body.ClientTag
+	case CancelOrderPayload:
+		entry.Operation = audit.OperationCancel
+	case ReplaceOrderPayload:
+		entry.Operation = audit.OperationReplace
+	default:
+		entry.Operation = "unknown"
+	}
+
+	if resp != nil {
+		entry.StatusCode = resp.StatusCode()
+		entry.LatencyMs = resp.Time().Milliseconds()
+		entry.ResponseBody = redactBody(resp.Body())
+		if len(entry.Ticket) == 0 {
+			entry.Ticket = clientTagFromBody(resp.Body())
+		}
+	}
+	if err != nil {
+		entry.Error = err.Error()
+		entry.LatencyMs = time.Since(entry.Time).Milliseconds()
+	}
+
+	return entry, true
+}
+
+// clientTagFromBody find the MT5 ticket in an order or list of orders
+func clientTagFromBody(b []byte) string {
+	var order OrderV3
+	if err := json.Unmarshal(b, &order); err == nil {
+		return order.ClientTag
+	}
+
+	var orders []OrderV3
+	if err := json.Unmarshal(b, &orders); err == nil && len(orders) > 0 {
+		return orders[0].ClientTag
+	}
+	return ""
+}
@@ -0,0 +1,155 @@
+package exante
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"
+	BreakerOpen     BreakerState = "open"
+	BreakerHalfOpen BreakerState = "half-open"
+
+	defaultBreakerThreshold = 5
+	defaultBreakerCooldown  = 30 * time.Second
+)
+
+// CircuitOpenError is returned without calling exante while the breaker is open
+type CircuitOpenError struct {
+	Until time.Time
+}
+
+func (e CircuitOpenError) Error() string {
+	return fmt.Sprintf("exante circuit breaker is open until %s", e.Until.Format(time.RFC3339))
+}
+
+func IsCircuitOpen(err error) bool {
+	var circuitErr CircuitOpenError
+	return errors.As(err, &circuitErr)
+}
+
+// CircuitBreaker open after Threshold consecutive failures, while open every
+// call fails right away. After Cooldown a single call is let through
+// (half-open), its result close or open the breaker again.
+type CircuitBreaker struct {
+	Threshold int
+	Cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	trial    bool
+}
+
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		Threshold: threshold,
+		Cooldown:  cooldown,
+		state:     BreakerClosed,
+	}
+}
+
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerOpen && time.Since(b.openedAt) >= b.Cooldown {
+		return BreakerHalfOpen
+	}
+	return b.state
+}
+
+// Allow return CircuitOpenError when the call must not reach exante
+func (b *CircuitBreaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if time.Since(b.openedAt) < b.Cooldown {
+			return CircuitOpenError{Until: b.openedAt.Add(b.Cooldown)}
+		}
+		b.state = BreakerHalfOpen
+		b.trial = true
+		return nil
+	case BreakerHalfOpen:
+		// only one trial call at a time
+		if b.trial {
+			return CircuitOpenError{Until: time.Now().Add(b.Cooldown)}
+		}
+		b.trial = true
+	}
+	return nil
+}
+
+func (b *CircuitBreaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = BreakerClosed
+	b.failures = 0
+	b.trial = false
+}
+
+func (b *CircuitBreaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.trial = false
+	if b.state == BreakerHalfOpen || b.failures >= b.Threshold {
+		b.state = BreakerOpen
+		b.openedAt = time.Now()
+	}
+}
+
+// isBreakerFailure tell if the response means exante is degraded,
+// business errors (4xx) don't count
+func isBreakerFailure(resp *resty.Response) bool {
+	return resp.StatusCode() >= http.StatusInternalServerError || resp.StatusCode() == http.StatusTooManyRequests
+}
+
+func (a Api) setCircuitBreaker(b *CircuitBreaker) {
+	a.cli.OnBeforeRequest(func(c *resty.Client, req *resty.Request) error {
+		return b.Allow()
+	})
+	a.cli.OnAfterResponse(func(c *resty.Client, resp *resty.Response) error {
+		if isBreakerFailure(resp) {
+			b.Failure()
+		} else {
+			b.Success()
+		}
+		return nil
+	})
+	a.cli.OnError(func(req *resty.Request, err error) {
+		var respErr *resty.ResponseError
+		if errors.As(err, &respErr) {
+			if respErr.Response != nil && respErr.Response.RawResponse != nil {
+				return
+			}
+			err = respErr.Err
+		}
+		if IsCircuitOpen(err) {
+			return
+		}
+		b.Failure()
+	})
+}
+
+// BreakerState return the state of the exante circuit breaker
+func (a Api) BreakerState() BreakerState {
+	return a.breaker.State()
+}
+
+// CircuitOpen return true when exante calls are being short-circuited
+func (a Api) CircuitOpen() bool {
+	return a.breaker.State() == BreakerOpen
+}
@@ -0,0 +1,216 @@
+package exante
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Cassette is a list of http interactions recorded from exante,
+// credentials are redacted before being written to disk
+type Cassette struct {
+	Version      int           `json:"version"`
+	Interactions []Interaction `json:"interactions"`
+}
+
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+type RecordedRequest struct {
+	Method  string      `json:"method"`
+	URL     string      `json:"url"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    string      `json:"body,omitempty"`
+}
+
+type RecordedResponse struct {
+	StatusCode int         `json:"statusCode"`
+	Headers    http.Header `json:"headers,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+const (
+	cassetteVersion = 1
+	redacted        = "REDACTED"
+)
+
+var (
+	jwtRegex        = regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`)
+	redactedHeaders = []string{"Authorization", "Cookie", "Set-Cookie"}
+)
+
+func LoadCassette(path string) (*Cassette, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var c Cassette
+	err = json.Unmarshal(b, &c)
+	if err != nil {
+		return nil, fmt.Errorf("error to read cassette %s: %s", path, err.Error())
+	}
+	if c.Version != cassetteVersion {
+		return nil, fmt.Errorf("cassette %s has version %d, expected %d", path, c.Version, cassetteVersion)
+	}
+	return &c, nil
+}
+
+func (c *Cassette) Save(path string) error {
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0644)
+}
+
+func redactHeaders(h http.Header) http.Header {
+	clean := h.Clone()
+	for _, key := range redactedHeaders {
+		if len(clean.Get(key)) > 0 {
+			clean.Set(key, redacted)
+		}
+	}
+	return clean
+}
+
+func redactBody(b []byte) string {
+	return jwtRegex.ReplaceAllString(string(b), redacted)
+}
+
+// RecordingTransport forward requests to Base and append every
+// interaction to the cassette written to Path on Close. Streamed
+// responses are recorded without their body, it is never complete.
+type RecordingTransport struct {
+	Base http.RoundTripper
+	Path string
+
+	mu       sync.Mutex
+	cassette Cassette
+}
+
+func NewRecordingTransport(path string) *RecordingTransport {
+	return &RecordingTransport{
+		Base:     http.DefaultTransport,
+		Path:     path,
+		cassette: Cassette{Version: cassetteVersion, Interactions: make([]Interaction, 0)},
+	}
+}
+
+func (t *RecordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		reqBody, err = io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	resp, err := t.Base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var respBody []byte
+	if !isStream(req, resp) {
+		respBody, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(respBody))
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.cassette.Interactions = append(t.cassette.Interactions, Interaction{
+		Request: RecordedRequest{
+			Method:  req.Method,
+			URL:     req.URL.RequestURI(),
+			Headers: redactHeaders(req.Header),
+			Body:    redactBody(reqBody),
+		},
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Headers:    redactHeaders(resp.Header),
+			Body:       redactBody(respBody),
+		},
+	})
+	return resp, nil
+}
+
+// Close write the recorded interactions to Path
+func (t *RecordingTransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.cassette.Save(t.Path)
+}
+
+// isStream return true for responses that stay open, as the quote feed
+func isStream(req *http.Request, resp *http.Response) bool {
+	for _, contentType := range []string{req.Header.Get("Accept"), resp.Header.Get("Content-Type")} {
+		if strings.Contains(contentType, "stream") {
+			return true
+		}
+	}
+	return false
+}
+
+// ReplayTransport serve responses from a cassette, each recorded
+// interaction is served once, in the recorded order, for requests
+// with the same method and url
+type ReplayTransport struct {
+	mu       sync.Mutex
+	cassette *Cassette
+	used     []bool
+}
+
+func NewReplayTransport(path string) (*ReplayTransport, error) {
+	c, err := LoadCassette(path)
+	if err != nil {
+		return nil, err
+	}
+	return &ReplayTransport{cassette: c, used: make([]bool, len(c.Interactions))}, nil
+}
+
+func (t *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for idx, interaction := range t.cassette.Interactions {
+		if t.used[idx] || interaction.Request.Method != req.Method || interaction.Request.URL != req.URL.RequestURI() {
+			continue
+		}
+		t.used[idx] = true
+
+		header := interaction.Response.Headers.Clone()
+		if header == nil {
+			header = http.Header{}
+		}
+		return &http.Response{
+			StatusCode:    interaction.Response.StatusCode,
+			Status:        fmt.Sprintf("%d %s", interaction.Response.StatusCode, http.StatusText(interaction.Response.StatusCode)),
+			Header:        header,
+			Body:          io.NopCloser(bytes.NewReader([]byte(interaction.Response.Body))),
+			ContentLength: int64(len(interaction.Response.Body)),
+			Request:       req,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("no recorded interaction for %s %s", req.Method, req.URL.RequestURI())
+}
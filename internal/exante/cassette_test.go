@@ -0,0 +1,118 @@
+package exante
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCassette(t *testing.T) {
+	t.Run("replay recorded orders session", func(t *testing.T) {
+		replay, err := NewReplayTransport("testdata/orders.cassette.json")
+		assert.NoError(t, err)
+
+		api := NewApi("https://api-demo.exante.eu", "app", "client", "key")
+		api.cli.SetRetryCount(0)
+		api.SetTransport(replay)
+
+		tp := "1.0985"
+		sl := "1.0885"
+		orders, err := api.PlaceOrderV3(&OrderSentTypeV3{
+			AccountID:  "ABC1234.001",
+			SymbolID:   "EUR/USD.E.FX",
+			Instrument: "EUR/USD.E.FX",
+			Side:       "buy",
+			Quantity:   "10000",
+			Duration:   "good_till_cancel",
+			OrderType:  "limit",
+			LimitPrice: "1.0925",
+			TakeProfit: &tp,
+			StopLoss:   &sl,
+			ClientTag:  "48811902",
+		})
+		assert.NoError(t, err)
+		assert.Len(t, orders, 3)
+		assert.Equal(t, WorkingStatus, orders[0].OrderState.Status)
+		assert.Equal(t, "", orders[0].OrderParameters.OcoGroup)
+		assert.Equal(t, "1.0985", orders[1].OrderParameters.LimitPrice)
+		assert.Equal(t, "1.0885", orders[2].OrderParameters.StopPrice)
+		assert.Equal(t, orders[0].OrderID, orders[2].OrderParameters.IfDoneParentID)
+
+		replaced, err := api.ReplaceOrder(orders[0].OrderID, ReplaceOrderPayload{
+			Action:     "replace",
+			Parameters: ReplaceOrderParameters{Quantity: "10000", LimitPrice: "1.0915"},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "1.0915", replaced.OrderParameters.LimitPrice)
+
+		listed, err := api.GetOrdersByLimitV3(100, "ABC1234.001")
+		assert.NoError(t, err)
+		assert.Len(t, listed, 3)
+		assert.Equal(t, FilledStatus, listed[0].OrderState.Status)
+		assert.Equal(t, "1.0914", listed[0].OrderState.Fills[0].Price)
+
+		_, err = api.ReplaceOrder(orders[0].OrderID, ReplaceOrderPayload{
+			Action:     "replace",
+			Parameters: ReplaceOrderParameters{Quantity: "10000", LimitPrice: "1.0905"},
+		})
+		assert.ErrorContains(t, err, "Unable to modify order")
+	})
+
+	t.Run("record redact credentials", func(t *testing.T) {
+		fake := NewFakeServer()
+		defer fake.Close()
+
+		path := filepath.Join(t.TempDir(), "session.json")
+		recorder := NewRecordingTransport(path)
+
+		api := fake.NewApi()
+		api.SetTransport(recorder)
+		_, err := api.GetOrdersByLimitV3(10, FakeAccountID)
+		assert.NoError(t, err)
+		_, err = os.Stat(path)
+		assert.True(t, os.IsNotExist(err), "written once on close")
+		assert.NoError(t, recorder.Close())
+
+		b, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.False(t, strings.Contains(string(b), "Bearer"))
+		assert.True(t, strings.Contains(string(b), redacted))
+
+		cassette, err := LoadCassette(path)
+		assert.NoError(t, err)
+		assert.Len(t, cassette.Interactions, 1)
+	})
+
+	t.Run("record should not wait for streamed bodies", func(t *testing.T) {
+		done := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/x-json-stream")
+			_, _ = w.Write([]byte(`{"symbolId":"EUR/USD.E.FX"}` + "\n"))
+			w.(http.Flusher).Flush()
+			<-done
+		}))
+		defer server.Close()
+		defer close(done)
+
+		recorder := NewRecordingTransport(filepath.Join(t.TempDir(), "session.json"))
+		cli := &http.Client{Transport: recorder, Timeout: 2 * time.Second}
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/md/3.0/feed/EUR%2FUSD.E.FX", nil)
+		req.Header.Set("Accept", "application/x-json-stream")
+		resp, err := cli.Do(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+
+		line, err := bufio.NewReader(resp.Body).ReadString('\n')
+		assert.NoError(t, err)
+		assert.Contains(t, line, "EUR/USD.E.FX")
+		assert.Len(t, recorder.cassette.Interactions, 1)
+		assert.Empty(t, recorder.cassette.Interactions[0].Response.Body)
+	})
+}
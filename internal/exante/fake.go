@@ -0,0 +1,790 @@
+package exante
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FakeServer is a stateful in memory implementation of the exante
+// trade 3.0 and md 3.0 routes used by Api. Orders are filled by the
+// price feed set with SetPrice, filled orders activate their if-done
+// children and cancel the other orders of the same OCO group.
+type FakeServer struct {
+	*httptest.Server
+
+	mu           sync.Mutex
+	accounts     UserAccounts
+	orders       []OrderV3
+	prices       map[string]float64
+	triggered    map[string]bool
+	candles      map[string]OHLCs
+	symbols      map[string]fakeSymbol
+	transactions Transactions
+	subscribers  map[string][]chan Quote
+	faults       *Faults
+	done         chan struct{}
+}
+
+type fakeSymbol struct {
+	symbol   SymbolV3
+	spec     SymbolSpecification
+	schedule SymbolSchedule
+}
+
+const FakeAccountID = "FAKE001.001"
+
+func NewFakeServer() *FakeServer {
+	f := &FakeServer{
+		accounts:    UserAccounts{{Status: "Full", AccountID: FakeAccountID}},
+		orders:      make([]OrderV3, 0),
+		prices:      make(map[string]float64),
+		triggered:   make(map[string]bool),
+		candles:     make(map[string]OHLCs),
+		symbols:     make(map[string]fakeSymbol),
+		subscribers: make(map[string][]chan Quote),
+		faults:      NewFaults(time.Now().UnixNano()),
+		done:        make(chan struct{}),
+	}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.route))
+	return f
+}
+
+// NewApi return a client pointing to the fake server
+func (f *FakeServer) NewApi() *Api {
+	api := NewApi(f.URL, "fake-app", "fake-client", "fake-shared-key")
+	api.cli.SetRetryCount(0)
+	return api
+}
+
+func (f *FakeServer) Close() {
+	close(f.done)
+	f.Server.Close()
+}
+
+// SetAccounts replace the accounts returned by /md/3.0/accounts
+func (f *FakeServer) SetAccounts(accounts UserAccounts) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.accounts = accounts
+}
+
+// SetFault inject fault on every call to endpoint
+func (f *FakeServer) SetFault(endpoint Endpoint, fault Fault) {
+	f.faults.Set(endpoint, fault)
+}
+
+func (f *FakeServer) ClearFault(endpoint Endpoint) {
+	f.faults.Clear(endpoint)
+}
+
+// AddSymbol register the metadata returned by the symbols routes
+func (f *FakeServer) AddSymbol(symbol SymbolV3, spec SymbolSpecification, schedule SymbolSchedule) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.symbols[symbol.SymbolID] = fakeSymbol{symbol: symbol, spec: spec, schedule: schedule}
+}
+
+// Orders return a copy of every order known by the fake server
+func (f *FakeServer) Orders() []OrderV3 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]OrderV3{}, f.orders...)
+}
+
+// SetPrice move the price of a symbol, filling every working
+// order crossed by the new price
+func (f *FakeServer) SetPrice(symbolID string, price float64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.prices[symbolID] = price
+	now := time.Now()
+	f.candles[symbolID] = append(f.candles[symbolID], OHLC{
+		Timestamp: now.UnixMilli(),
+		Open:      formatFakeFloat(price),
+		High:      formatFakeFloat(price),
+		Low:       formatFakeFloat(price),
+		Close:     formatFakeFloat(price),
+		Volume:    "0",
+	})
+
+	quote := f.quote(symbolID)
+	for _, sub := range f.subscribers[symbolID] {
+		select {
+		case sub <- quote:
+		default:
+		}
+	}
+
+	// a fill can activate children crossed by the same price
+	for filled := true; filled; {
+		filled = false
+		for idx := range f.orders {
+			order := f.orders[idx]
+			if order.OrderParameters.SymbolId != symbolID || order.OrderState.Status != WorkingStatus {
+				continue
+			}
+			if f.cross(idx, price) {
+				f.fill(idx, price)
+				filled = true
+			}
+		}
+	}
+}
+
+// cross must be called with f.mu locked, it return true when the order at
+// idx fills at price. A stop_limit crossed by its stop waits as a limit.
+func (f *FakeServer) cross(idx int, price float64) bool {
+	order := f.orders[idx]
+	if order.OrderParameters.OrderType == "stop_limit" && crossed(order.OrderParameters, "stop", price) {
+		f.triggered[order.OrderID] = true
+	}
+	if f.triggered[order.OrderID] {
+		return crossed(order.OrderParameters, "limit", price)
+	}
+	return crossed(order.OrderParameters, order.OrderParameters.OrderType, price)
+}
+
+func crossed(params OrderParameters, orderType string, price float64) bool {
+	limit, _ := strconv.ParseFloat(params.LimitPrice, 64)
+	stop, _ := strconv.ParseFloat(params.StopPrice, 64)
+
+	switch orderType {
+	case "market":
+		return true
+	case "limit":
+		if params.Side == "buy" {
+			return price <= limit
+		}
+		return price >= limit
+	case "stop":
+		if params.Side == "buy" {
+			return price >= stop
+		}
+		return price <= stop
+	}
+	return false
+}
+
+// fill must be called with f.mu locked
+func (f *FakeServer) fill(idx int, price float64) {
+	now := time.Now()
+	order := &f.orders[idx]
+	order.OrderState.Status = FilledStatus
+	order.OrderState.LastUpdate = now.Format(time.RFC3339)
+	order.OrderState.Fills = append(order.OrderState.Fills, OrderFill{
+		Quantity: order.OrderParameters.Quantity,
+		Price:    formatFakeFloat(price),
+		Time:     now.Format(time.RFC3339),
+		Position: 0,
+	})
+
+	amount := order.OrderParameters.Quantity
+	if order.OrderParameters.Side == "sell" {
+		amount = "-" + amount
+	}
+	f.transactions = append(f.transactions, Transaction{
+		ID:            int64(len(f.transactions) + 1),
+		UUID:          uuid.NewString(),
+		AccountID:     order.AccountID,
+		SymbolID:      order.OrderParameters.SymbolId,
+		Asset:         order.OrderParameters.SymbolId,
+		Amount:        amount,
+		OperationType: OperationTrade,
+		Timestamp:     now.UnixMilli(),
+		OrderID:       order.OrderID,
+	})
+
+	orderID := order.OrderID
+	ocoGroup := order.OrderParameters.OcoGroup
+	for i := range f.orders {
+		other := &f.orders[i]
+		if other.OrderParameters.IfDoneParentID == orderID && other.OrderState.Status == PendingStatus {
+			other.OrderState.Status = WorkingStatus
+			other.OrderState.LastUpdate = now.Format(time.RFC3339)
+		}
+		if len(ocoGroup) > 0 && other.OrderID != orderID && other.OrderParameters.OcoGroup == ocoGroup && isActive(other.OrderState.Status) {
+			other.OrderState.Status = CancelledStatus
+			other.OrderState.LastUpdate = now.Format(time.RFC3339)
+		}
+	}
+}
+
+func isActive(status Status) bool {
+	return status == WorkingStatus || status == PendingStatus || status == PlacingStatus
+}
+
+// quote must be called with f.mu locked
+func (f *FakeServer) quote(symbolID string) Quote {
+	price := formatFakeFloat(f.prices[symbolID])
+	return Quote{
+		SymbolID:  symbolID,
+		Timestamp: time.Now().UnixMilli(),
+		Bid:       []QuoteLevel{{Price: price, Size: "1000000"}},
+		Ask:       []QuoteLevel{{Price: price, Size: "1000000"}},
+	}
+}
+
+func formatFakeFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func (f *FakeServer) route(w http.ResponseWriter, r *http.Request) {
+	path := strings.Split(strings.Trim(r.URL.EscapedPath(), "/"), "/")
+	for idx, p := range path {
+		if unescaped, err := url.PathUnescape(p); err == nil {
+			path[idx] = unescaped
+		}
+	}
+
+	if len(path) < 3 {
+		writeFakeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	outcome := f.faults.decide(fakeEndpoint(r, path))
+	time.Sleep(outcome.latency)
+	switch {
+	case outcome.serverErr:
+		writeFakeError(w, http.StatusServiceUnavailable, "service unavailable")
+		return
+	case outcome.rateLimited:
+		w.Header().Set("Retry-After", strconv.Itoa(int(outcome.retryAfter.Seconds())))
+		writeFakeError(w, http.StatusTooManyRequests, rateLimitMsg)
+		return
+	case outcome.unableToModify:
+		writeFakeError(w, http.StatusBadRequest, unableToModifyOrderMsg)
+		return
+	case len(outcome.reject) > 0:
+		writeFakeError(w, http.StatusBadRequest, outcome.reject)
+		return
+	case outcome.dropResponse:
+		// apply the call and close the connection without answering
+		f.dispatch(httptest.NewRecorder(), r, path)
+		panic(http.ErrAbortHandler)
+	}
+
+	f.dispatch(w, r, path)
+}
+
+// fakeEndpoint map a request to its Endpoint, cancel and replace
+// share the same route and are told apart by the body action
+func fakeEndpoint(r *http.Request, path []string) Endpoint {
+	switch {
+	case path[0] == "trade" && path[2] == "orders":
+		switch {
+		case len(path) == 3 && r.Method == http.MethodPost:
+			return EndpointPlaceOrder
+		case len(path) == 3:
+			return EndpointListOrders
+		case path[3] == "active":
+			return EndpointActiveOrders
+		case r.Method == http.MethodGet:
+			return EndpointGetOrder
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		var req ReplaceOrderPayload
+		_ = json.Unmarshal(body, &req)
+		if req.Action == "cancel" {
+			return EndpointCancelOrder
+		}
+		return EndpointReplaceOrder
+	case path[2] == "accounts":
+		return EndpointAccounts
+	case path[2] == "feed":
+		return EndpointQuote
+	case path[2] == "ohlc":
+		return EndpointOHLC
+	case path[2] == "symbols":
+		return EndpointSymbols
+	case path[2] == "summary":
+		return EndpointSummary
+	case path[2] == "transactions":
+		return EndpointTransactions
+	}
+	return EndpointUnknown
+}
+
+func (f *FakeServer) dispatch(w http.ResponseWriter, r *http.Request, path []string) {
+	switch {
+	case path[0] == "trade" && path[2] == "orders":
+		f.routeOrders(w, r, path[3:])
+	case path[0] == "md" && path[2] == "accounts":
+		f.handleAccounts(w, r)
+	case path[0] == "md" && path[2] == "feed" && len(path) == 5 && path[4] == "last":
+		f.handleLastQuote(w, r, path[3])
+	case path[0] == "md" && path[2] == "feed" && len(path) == 4:
+		f.handleStream(w, r, path[3])
+	case path[0] == "md" && path[2] == "ohlc" && len(path) == 5:
+		f.handleOHLC(w, r, path[3])
+	case path[0] == "md" && path[2] == "symbols" && len(path) == 3:
+		f.handleSymbols(w, r)
+	case path[0] == "md" && path[2] == "symbols" && len(path) >= 4:
+		f.handleSymbol(w, r, path[3], path[4:])
+	case path[0] == "md" && path[2] == "summary" && len(path) == 5:
+		f.handleSummary(w, r, path[3], path[4])
+	case path[0] == "md" && path[2] == "transactions":
+		f.handleTransactions(w, r)
+	default:
+		writeFakeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+func (f *FakeServer) routeOrders(w http.ResponseWriter, r *http.Request, path []string) {
+	switch {
+	case len(path) == 0 && r.Method == http.MethodPost:
+		f.handlePlaceOrder(w, r)
+	case len(path) == 0 && r.Method == http.MethodGet:
+		f.handleListOrders(w, r)
+	case len(path) == 1 && path[0] == "active" && r.Method == http.MethodGet:
+		f.handleActiveOrders(w, r)
+	case len(path) == 1 && r.Method == http.MethodGet:
+		f.handleGetOrder(w, r, path[0])
+	case len(path) == 1 && r.Method == http.MethodPost:
+		f.handleModifyOrder(w, r, path[0])
+	default:
+		writeFakeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+func (f *FakeServer) handlePlaceOrder(w http.ResponseWriter, r *http.Request) {
+	var req OrderSentTypeV3
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeFakeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	symbolID := req.SymbolID
+	if len(symbolID) == 0 {
+		symbolID = req.Instrument
+	}
+
+	status := WorkingStatus
+	if len(req.IfDoneParentID) > 0 {
+		idx := f.indexOf(req.IfDoneParentID)
+		if idx == -1 {
+			writeFakeError(w, http.StatusBadRequest, "parent order not found")
+			return
+		}
+		if f.orders[idx].OrderState.Status != FilledStatus {
+			status = PendingStatus
+		}
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	parent := OrderV3{
+		OrderID:   uuid.NewString(),
+		PlaceTime: now,
+		AccountID: req.AccountID,
+		ClientTag: req.ClientTag,
+		OrderState: OrderState{
+			Status:     status,
+			LastUpdate: now,
+		},
+		OrderParameters: OrderParameters{
+			Side:           req.Side,
+			Duration:       req.Duration,
+			Quantity:       req.Quantity,
+			Instrument:     symbolID,
+			SymbolId:       symbolID,
+			OrderType:      req.OrderType,
+			OcoGroup:       req.OcoGroup,
+			IfDoneParentID: req.IfDoneParentID,
+			LimitPrice:     req.LimitPrice,
+			PriceDistance:  req.PriceDistance,
+			PartQuantity:   req.PartQuantity,
+			PlaceInterval:  req.PlaceInterval,
+		},
+	}
+	if req.StopPrice != nil {
+		parent.OrderParameters.StopPrice = *req.StopPrice
+	}
+
+	placed := []OrderV3{parent}
+
+	if req.TakeProfit != nil || req.StopLoss != nil {
+		ocoGroup := uuid.NewString()
+		child := func(orderType string) OrderV3 {
+			return OrderV3{
+				OrderID:   uuid.NewString(),
+				PlaceTime: now,
+				AccountID: req.AccountID,
+				ClientTag: req.ClientTag,
+				OrderState: OrderState{
+					Status:     PendingStatus,
+					LastUpdate: now,
+				},
+				OrderParameters: OrderParameters{
+					Side:           reverseSide(req.Side),
+					Duration:       req.Duration,
+					Quantity:       req.Quantity,
+					Instrument:     symbolID,
+					SymbolId:       symbolID,
+					OrderType:      orderType,
+					OcoGroup:       ocoGroup,
+					IfDoneParentID: parent.OrderID,
+				},
+			}
+		}
+		if req.TakeProfit != nil {
+			tp := child("limit")
+			tp.OrderParameters.LimitPrice = *req.TakeProfit
+			placed = append(placed, tp)
+		}
+		if req.StopLoss != nil {
+			sl := child("stop")
+			sl.OrderParameters.StopPrice = *req.StopLoss
+			placed = append(placed, sl)
+		}
+	}
+
+	f.orders = append(f.orders, placed...)
+
+	// crossed prices are filled right away, market orders on a symbol
+	// without price wait for SetPrice
+	if price, has := f.prices[symbolID]; has {
+		idx := f.indexOf(parent.OrderID)
+		if f.orders[idx].OrderState.Status == WorkingStatus && f.cross(idx, price) {
+			f.fill(idx, price)
+		}
+	}
+
+	result := make([]OrderV3, 0, len(placed))
+	for _, order := range placed {
+		result = append(result, f.orders[f.indexOf(order.OrderID)])
+	}
+	writeFakeJSON(w, http.StatusCreated, result)
+}
+
+func reverseSide(side string) string {
+	if side == "buy" {
+		return "sell"
+	}
+	return "buy"
+}
+
+// indexOf must be called with f.mu locked
+func (f *FakeServer) indexOf(orderID string) int {
+	for idx, order := range f.orders {
+		if order.OrderID == orderID {
+			return idx
+		}
+	}
+	return -1
+}
+
+func (f *FakeServer) handleListOrders(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 50
+	}
+	accountID := r.URL.Query().Get("accountId")
+
+	// newest orders first, like exante
+	result := make(OrdersV3, 0)
+	for idx := len(f.orders) - 1; idx >= 0 && len(result) < limit; idx-- {
+		if len(accountID) == 0 || f.orders[idx].AccountID == accountID {
+			result = append(result, f.orders[idx])
+		}
+	}
+	writeFakeJSON(w, http.StatusOK, result)
+}
+
+func (f *FakeServer) handleActiveOrders(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	result := make(OrdersV3, 0)
+	for _, order := range f.orders {
+		if isActive(order.OrderState.Status) {
+			result = append(result, order)
+		}
+	}
+	writeFakeJSON(w, http.StatusOK, result)
+}
+
+func (f *FakeServer) handleGetOrder(w http.ResponseWriter, r *http.Request, orderID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	idx := f.indexOf(orderID)
+	if idx == -1 {
+		writeFakeError(w, http.StatusNotFound, "order not found")
+		return
+	}
+	writeFakeJSON(w, http.StatusOK, f.orders[idx])
+}
+
+func (f *FakeServer) handleModifyOrder(w http.ResponseWriter, r *http.Request, orderID string) {
+	var req ReplaceOrderPayload
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeFakeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	idx := f.indexOf(orderID)
+	if idx == -1 {
+		writeFakeError(w, http.StatusNotFound, "order not found")
+		return
+	}
+	if !isActive(f.orders[idx].OrderState.Status) {
+		writeFakeError(w, http.StatusBadRequest, "Unable to modify order")
+		return
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	switch req.Action {
+	case "cancel":
+		for i := range f.orders {
+			if i == idx || (f.orders[i].OrderParameters.IfDoneParentID == orderID && isActive(f.orders[i].OrderState.Status)) {
+				f.orders[i].OrderState.Status = CancelledStatus
+				f.orders[i].OrderState.LastUpdate = now
+			}
+		}
+	case "replace":
+		params := &f.orders[idx].OrderParameters
+		if len(req.Parameters.Quantity) > 0 {
+			params.Quantity = req.Parameters.Quantity
+		}
+		if len(req.Parameters.LimitPrice) > 0 {
+			params.LimitPrice = req.Parameters.LimitPrice
+		}
+		if len(req.Parameters.StopPrice) > 0 {
+			params.StopPrice = req.Parameters.StopPrice
+		}
+		if len(req.Parameters.PriceDistance) > 0 {
+			params.PriceDistance = req.Parameters.PriceDistance
+		}
+		f.orders[idx].CurrentModificationID = uuid.NewString()
+		f.orders[idx].OrderState.LastUpdate = now
+	default:
+		writeFakeError(w, http.StatusBadRequest, fmt.Sprintf("unknown action %s", req.Action))
+		return
+	}
+
+	writeFakeJSON(w, http.StatusAccepted, f.orders[idx])
+}
+
+func (f *FakeServer) handleAccounts(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	writeFakeJSON(w, http.StatusOK, f.accounts)
+}
+
+func (f *FakeServer) handleLastQuote(w http.ResponseWriter, r *http.Request, symbolID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, has := f.prices[symbolID]; !has {
+		writeFakeJSON(w, http.StatusOK, []Quote{})
+		return
+	}
+	writeFakeJSON(w, http.StatusOK, []Quote{f.quote(symbolID)})
+}
+
+func (f *FakeServer) handleStream(w http.ResponseWriter, r *http.Request, symbolID string) {
+	quotes := make(chan Quote, 16)
+
+	f.mu.Lock()
+	f.subscribers[symbolID] = append(f.subscribers[symbolID], quotes)
+	f.mu.Unlock()
+
+	defer func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		subs := f.subscribers[symbolID]
+		for idx, sub := range subs {
+			if sub == quotes {
+				f.subscribers[symbolID] = append(subs[:idx], subs[idx+1:]...)
+				break
+			}
+		}
+	}()
+
+	w.Header().Set("Content-Type", "application/x-json-stream")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	if flusher != nil {
+		flusher.Flush()
+	}
+
+	enc := json.NewEncoder(w)
+	for {
+		select {
+		case quote := <-quotes:
+			if err := enc.Encode(quote); err != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		case <-r.Context().Done():
+			return
+		case <-f.done:
+			return
+		}
+	}
+}
+
+func (f *FakeServer) handleOHLC(w http.ResponseWriter, r *http.Request, symbolID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	candles := f.candles[symbolID]
+	if size, err := strconv.Atoi(r.URL.Query().Get("size")); err == nil && size < len(candles) {
+		candles = candles[len(candles)-size:]
+	}
+
+	// newest candles first, like exante
+	result := make(OHLCs, 0, len(candles))
+	for idx := len(candles) - 1; idx >= 0; idx-- {
+		result = append(result, candles[idx])
+	}
+	writeFakeJSON(w, http.StatusOK, result)
+}
+
+func (f *FakeServer) handleSymbols(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	symbols := make([]SymbolV3, 0, len(f.symbols))
+	for _, symbol := range f.symbols {
+		symbols = append(symbols, symbol.symbol)
+	}
+	sort.Slice(symbols, func(i, j int) bool { return symbols[i].SymbolID < symbols[j].SymbolID })
+	writeFakeJSON(w, http.StatusOK, symbols)
+}
+
+func (f *FakeServer) handleSymbol(w http.ResponseWriter, r *http.Request, symbolID string, path []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	symbol, has := f.symbols[symbolID]
+	if !has {
+		writeFakeError(w, http.StatusNotFound, "symbol not found")
+		return
+	}
+
+	switch {
+	case len(path) == 0:
+		writeFakeJSON(w, http.StatusOK, symbol.symbol)
+	case path[0] == "specification":
+		writeFakeJSON(w, http.StatusOK, symbol.spec)
+	case path[0] == "schedule":
+		writeFakeJSON(w, http.StatusOK, symbol.schedule)
+	default:
+		writeFakeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+func (f *FakeServer) handleSummary(w http.ResponseWriter, r *http.Request, accountID string, currency string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	quantities := make(map[string]float64)
+	for _, order := range f.orders {
+		if order.AccountID != accountID || order.OrderState.Status != FilledStatus {
+			continue
+		}
+		qty, _ := strconv.ParseFloat(order.OrderParameters.Quantity, 64)
+		if order.OrderParameters.Side == "sell" {
+			qty = -qty
+		}
+		quantities[order.OrderParameters.SymbolId] += qty
+	}
+
+	symbols := make([]string, 0, len(quantities))
+	for symbolID := range quantities {
+		symbols = append(symbols, symbolID)
+	}
+	sort.Strings(symbols)
+
+	positions := make([]SummaryPosition, 0)
+	for _, symbolID := range symbols {
+		if quantities[symbolID] == 0 {
+			continue
+		}
+		positions = append(positions, SummaryPosition{
+			ID:        symbolID,
+			SymbolID:  symbolID,
+			AccountID: accountID,
+			Currency:  currency,
+			Quantity:  formatFakeFloat(quantities[symbolID]),
+			Price:     formatFakeFloat(f.prices[symbolID]),
+		})
+	}
+
+	writeFakeJSON(w, http.StatusOK, AccountSummary{
+		Account:   accountID,
+		Currency:  currency,
+		Timestamp: time.Now().UnixMilli(),
+		Positions: positions,
+	})
+}
+
+func (f *FakeServer) handleTransactions(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	query := r.URL.Query()
+	operationTypes := strings.Split(query.Get("operationType"), ",")
+
+	result := make(Transactions, 0)
+	for _, t := range f.transactions {
+		if accountID := query.Get("accountId"); len(accountID) > 0 && t.AccountID != accountID {
+			continue
+		}
+		if symbolID := query.Get("symbolId"); len(symbolID) > 0 && t.SymbolID != symbolID {
+			continue
+		}
+		if len(query.Get("operationType")) > 0 && !slices.Contains(operationTypes, t.OperationType) {
+			continue
+		}
+		result = append(result, t)
+	}
+
+	offset, _ := strconv.Atoi(query.Get("offset"))
+	if offset > len(result) {
+		offset = len(result)
+	}
+	result = result[offset:]
+	if limit, err := strconv.Atoi(query.Get("limit")); err == nil && limit < len(result) {
+		result = result[:limit]
+	}
+
+	writeFakeJSON(w, http.StatusOK, result)
+}
+
+func writeFakeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeFakeError(w http.ResponseWriter, status int, message string) {
+	writeFakeJSON(w, status, []ErrorResponse{{Message: message}})
+}
@@ -0,0 +1,272 @@
+package exante
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/danielsussa/mt5-to-exante/internal/audit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFakeServer(t *testing.T) {
+	t.Run("limit order with TP/SL, fill parent then TP should cancel SL", func(t *testing.T) {
+		fake := NewFakeServer()
+		defer fake.Close()
+		api := fake.NewApi()
+
+		tp := "1.3"
+		sl := "1.1"
+		orders, err := api.PlaceOrderV3(&OrderSentTypeV3{
+			AccountID:  FakeAccountID,
+			SymbolID:   "EUR/USD.E.FX",
+			Instrument: "EUR/USD.E.FX",
+			Side:       "buy",
+			Quantity:   "10000",
+			Duration:   "good_till_cancel",
+			OrderType:  "limit",
+			LimitPrice: "1.2",
+			TakeProfit: &tp,
+			StopLoss:   &sl,
+			ClientTag:  "1234",
+		})
+		assert.NoError(t, err)
+		assert.Len(t, orders, 3)
+		assert.Equal(t, WorkingStatus, orders[0].OrderState.Status)
+		assert.Equal(t, PendingStatus, orders[1].OrderState.Status)
+		assert.Equal(t, PendingStatus, orders[2].OrderState.Status)
+		assert.Equal(t, orders[1].OrderParameters.OcoGroup, orders[2].OrderParameters.OcoGroup)
+
+		fake.SetPrice("EUR/USD.E.FX", 1.25)
+		parent, err := api.GetOrder(orders[0].OrderID)
+		assert.NoError(t, err)
+		assert.Equal(t, WorkingStatus, parent.OrderState.Status)
+
+		fake.SetPrice("EUR/USD.E.FX", 1.2)
+		active, err := api.GetActiveOrdersV3()
+		assert.NoError(t, err)
+		assert.Len(t, active, 2)
+		for _, order := range active {
+			assert.Equal(t, WorkingStatus, order.OrderState.Status)
+		}
+
+		fake.SetPrice("EUR/USD.E.FX", 1.31)
+		active, err = api.GetActiveOrdersV3()
+		assert.NoError(t, err)
+		assert.Len(t, active, 0)
+
+		all, err := api.GetOrdersByLimitV3(100, FakeAccountID)
+		assert.NoError(t, err)
+		assert.Len(t, all, 3)
+		tpOrder, _ := api.GetOrder(orders[1].OrderID)
+		assert.Equal(t, FilledStatus, tpOrder.OrderState.Status)
+		slOrder, _ := api.GetOrder(orders[2].OrderID)
+		assert.Equal(t, CancelledStatus, slOrder.OrderState.Status)
+
+		summary, err := api.GetAccountSummary(FakeAccountID, "USD")
+		assert.NoError(t, err)
+		assert.Len(t, summary.Positions, 0)
+
+		transactions, err := api.GetAllTransactions(TransactionsFilter{AccountID: FakeAccountID})
+		assert.NoError(t, err)
+		assert.Len(t, transactions, 2)
+	})
+
+	t.Run("cancel and replace", func(t *testing.T) {
+		fake := NewFakeServer()
+		defer fake.Close()
+		api := fake.NewApi()
+
+		orders, err := api.PlaceOrderV3(&OrderSentTypeV3{
+			AccountID:  FakeAccountID,
+			SymbolID:   "BTC.USD",
+			Side:       "sell",
+			Quantity:   "1",
+			Duration:   "good_till_cancel",
+			OrderType:  "limit",
+			LimitPrice: "50000",
+		})
+		assert.NoError(t, err)
+
+		replaced, err := api.ReplaceOrder(orders[0].OrderID, ReplaceOrderPayload{
+			Action:     "replace",
+			Parameters: ReplaceOrderParameters{Quantity: "1", LimitPrice: "51000"},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "51000", replaced.OrderParameters.LimitPrice)
+
+		assert.NoError(t, api.CancelOrder(orders[0].OrderID))
+		err = api.CancelOrder(orders[0].OrderID)
+		assert.ErrorContains(t, err, "Unable to modify order")
+	})
+
+	t.Run("market order on a symbol without price should wait for one", func(t *testing.T) {
+		fake := NewFakeServer()
+		defer fake.Close()
+		api := fake.NewApi()
+
+		orders, err := api.PlaceOrderV3(&OrderSentTypeV3{
+			AccountID: FakeAccountID,
+			SymbolID:  "EUR/USD.E.FX",
+			Side:      "buy",
+			Quantity:  "10000",
+			Duration:  "good_till_cancel",
+			OrderType: "market",
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, WorkingStatus, orders[0].OrderState.Status)
+
+		fake.SetPrice("EUR/USD.E.FX", 1.15)
+		order, err := api.GetOrder(orders[0].OrderID)
+		assert.NoError(t, err)
+		assert.Equal(t, FilledStatus, order.OrderState.Status)
+		assert.Equal(t, "1.15", order.OrderState.Fills[0].Price)
+	})
+
+	t.Run("stop limit should fill only within its limit", func(t *testing.T) {
+		fake := NewFakeServer()
+		defer fake.Close()
+		api := fake.NewApi()
+		fake.SetPrice("EUR/USD.E.FX", 1.2)
+
+		stop := "1.1"
+		orders, err := api.PlaceOrderV3(&OrderSentTypeV3{
+			AccountID:  FakeAccountID,
+			SymbolID:   "EUR/USD.E.FX",
+			Side:       "sell",
+			Quantity:   "10000",
+			Duration:   "good_till_cancel",
+			OrderType:  "stop_limit",
+			StopPrice:  &stop,
+			LimitPrice: "1.09",
+		})
+		assert.NoError(t, err)
+
+		fake.SetPrice("EUR/USD.E.FX", 1.05)
+		order, _ := api.GetOrder(orders[0].OrderID)
+		assert.Equal(t, WorkingStatus, order.OrderState.Status, "gapped below the limit")
+
+		fake.SetPrice("EUR/USD.E.FX", 1.095)
+		order, _ = api.GetOrder(orders[0].OrderID)
+		assert.Equal(t, FilledStatus, order.OrderState.Status, "triggered, filled back within the limit")
+		assert.Equal(t, "1.095", order.OrderState.Fills[0].Price)
+	})
+
+	t.Run("market data", func(t *testing.T) {
+		fake := NewFakeServer()
+		defer fake.Close()
+		api := fake.NewApi()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		quotes, err := api.StreamQuotes(ctx, "EUR/USD.E.FX")
+		assert.NoError(t, err)
+
+		fake.SetPrice("EUR/USD.E.FX", 1.1)
+		quote := <-quotes
+		assert.Equal(t, "1.1", quote.Bid[0].Price)
+
+		last, err := api.GetLastQuote("EUR/USD.E.FX")
+		assert.NoError(t, err)
+		assert.Equal(t, "EUR/USD.E.FX", last.SymbolID)
+
+		candles, err := api.GetOHLC("EUR/USD.E.FX", 60, 10)
+		assert.NoError(t, err)
+		assert.Len(t, candles, 1)
+	})
+
+	t.Run("faults", func(t *testing.T) {
+		fake := NewFakeServer()
+		defer fake.Close()
+		api := fake.NewApi()
+
+		req := &OrderSentTypeV3{
+			AccountID:  FakeAccountID,
+			SymbolID:   "BTC.USD",
+			Side:       "buy",
+			Quantity:   "1",
+			Duration:   "good_till_cancel",
+			OrderType:  "limit",
+			LimitPrice: "40000",
+		}
+
+		fake.SetFault(EndpointPlaceOrder, Fault{Reject: "insufficient margin"})
+		_, err := api.PlaceOrderV3(req)
+		assert.ErrorContains(t, err, "insufficient margin")
+
+		fake.SetFault(EndpointPlaceOrder, Fault{DropResponse: true})
+		_, err = api.PlaceOrderV3(req)
+		assert.Error(t, err)
+		assert.Len(t, fake.Orders(), 1)
+
+		fake.SetFault(EndpointListOrders, Fault{ServerErrRate: 1})
+		_, err = api.GetOrdersByLimitV3(10, FakeAccountID)
+		assert.ErrorContains(t, err, "internal server error")
+
+		fake.SetFault(EndpointCancelOrder, Fault{UnableToModify: true})
+		err = api.CancelOrder(fake.Orders()[0].OrderID)
+		assert.ErrorContains(t, err, "Unable to modify order")
+
+		fake.ClearFault(EndpointCancelOrder)
+		assert.NoError(t, api.CancelOrder(fake.Orders()[0].OrderID))
+	})
+
+	t.Run("audit log", func(t *testing.T) {
+		fake := NewFakeServer()
+		defer fake.Close()
+		api := fake.NewApi()
+
+		auditLog, err := audit.New(t.TempDir())
+		assert.NoError(t, err)
+		defer auditLog.Close()
+		api.SetAuditLog(auditLog)
+
+		orders, err := api.PlaceOrderV3(&OrderSentTypeV3{
+			AccountID:  FakeAccountID,
+			SymbolID:   "BTC.USD",
+			Side:       "buy",
+			Quantity:   "1",
+			Duration:   "good_till_cancel",
+			OrderType:  "limit",
+			LimitPrice: "40000",
+			ClientTag:  "4321",
+		})
+		assert.NoError(t, err)
+		assert.NoError(t, api.CancelOrder(orders[0].OrderID))
+		_, err = api.GetOrder(orders[0].OrderID)
+		assert.NoError(t, err)
+
+		entries, err := auditLog.Query(audit.Filter{Ticket: "4321"})
+		assert.NoError(t, err)
+		assert.Len(t, entries, 2)
+		assert.Equal(t, audit.OperationPlace, entries[0].Operation)
+		assert.Equal(t, http.StatusCreated, entries[0].StatusCode)
+		assert.Equal(t, audit.OperationCancel, entries[1].Operation)
+		assert.NotContains(t, entries[0].RequestBody, "Bearer")
+	})
+
+	t.Run("circuit breaker", func(t *testing.T) {
+		fake := NewFakeServer()
+		defer fake.Close()
+		api := fake.NewApi()
+		api.breaker.Cooldown = 50 * time.Millisecond
+
+		fake.SetFault(EndpointListOrders, Fault{ServerErrRate: 1})
+		for i := 0; i < defaultBreakerThreshold; i++ {
+			_, err := api.GetOrdersByLimitV3(10, FakeAccountID)
+			assert.ErrorContains(t, err, "internal server error")
+		}
+		assert.Equal(t, BreakerOpen, api.BreakerState())
+
+		_, err := api.GetOrdersByLimitV3(10, FakeAccountID)
+		assert.True(t, IsCircuitOpen(err))
+
+		fake.ClearFault(EndpointListOrders)
+		time.Sleep(60 * time.Millisecond)
+		assert.Equal(t, BreakerHalfOpen, api.BreakerState())
+		_, err = api.GetOrdersByLimitV3(10, FakeAccountID)
+		assert.NoError(t, err)
+		assert.Equal(t, BreakerClosed, api.BreakerState())
+	})
+}
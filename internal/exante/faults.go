@@ -0,0 +1,130 @@
+package exante
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Endpoint identify an exante call for fault injection
+type Endpoint string
+
+const (
+	EndpointPlaceOrder   Endpoint = "placeOrder"
+	EndpointReplaceOrder Endpoint = "replaceOrder"
+	EndpointCancelOrder  Endpoint = "cancelOrder"
+	EndpointGetOrder     Endpoint = "getOrder"
+	EndpointListOrders   Endpoint = "listOrders"
+	EndpointActiveOrders Endpoint = "activeOrders"
+	EndpointAccounts     Endpoint = "accounts"
+	EndpointQuote        Endpoint = "quote"
+	EndpointOHLC         Endpoint = "ohlc"
+	EndpointSymbols      Endpoint = "symbols"
+	EndpointSummary      Endpoint = "summary"
+	EndpointTransactions Endpoint = "transactions"
+	EndpointUnknown      Endpoint = "unknown"
+)
+
+const (
+	unableToModifyOrderMsg = "Unable to modify order"
+	rateLimitMsg           = "rate limit exceeded"
+)
+
+// Fault describe the failures injected on an endpoint, rates are
+// probabilities between 0 and 1
+type Fault struct {
+	Latency        time.Duration
+	ServerErrRate  float64
+	RateLimitRate  float64
+	RetryAfter     time.Duration
+	UnableToModify bool
+	// Reject placements with this reason
+	Reject string
+	// DropResponse apply the call on exante but lose its response
+	DropResponse bool
+}
+
+// Faults hold the faults configured per endpoint, a nil *Faults
+// never inject anything
+type Faults struct {
+	mu     sync.Mutex
+	faults map[Endpoint]Fault
+	rand   *rand.Rand
+}
+
+func NewFaults(seed int64) *Faults {
+	return &Faults{
+		faults: make(map[Endpoint]Fault),
+		rand:   rand.New(rand.NewSource(seed)),
+	}
+}
+
+func (f *Faults) Set(endpoint Endpoint, fault Fault) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.faults[endpoint] = fault
+}
+
+func (f *Faults) Clear(endpoint Endpoint) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.faults, endpoint)
+}
+
+// faultOutcome is the decision taken for a single call
+type faultOutcome struct {
+	latency        time.Duration
+	serverErr      bool
+	rateLimited    bool
+	retryAfter     time.Duration
+	unableToModify bool
+	reject         string
+	dropResponse   bool
+}
+
+func (f *Faults) decide(endpoint Endpoint) faultOutcome {
+	if f == nil {
+		return faultOutcome{}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	fault, has := f.faults[endpoint]
+	if !has {
+		return faultOutcome{}
+	}
+
+	outcome := faultOutcome{
+		latency:      fault.Latency,
+		retryAfter:   fault.RetryAfter,
+		reject:       fault.Reject,
+		dropResponse: fault.DropResponse,
+	}
+	outcome.serverErr = fault.ServerErrRate > 0 && f.rand.Float64() < fault.ServerErrRate
+	outcome.rateLimited = !outcome.serverErr && fault.RateLimitRate > 0 && f.rand.Float64() < fault.RateLimitRate
+	outcome.unableToModify = fault.UnableToModify && (endpoint == EndpointReplaceOrder || endpoint == EndpointCancelOrder)
+	if endpoint != EndpointPlaceOrder {
+		outcome.reject = ""
+	}
+	return outcome
+}
+
+// err return the error the Api would return for the injected
+// fault before reaching exante
+func (o faultOutcome) err() error {
+	switch {
+	case o.serverErr:
+		return ErrInternalServer
+	case o.rateLimited:
+		return ErrorResponse{Message: rateLimitMsg}
+	case o.unableToModify:
+		return ErrorResponse{Message: unableToModifyOrderMsg}
+	case len(o.reject) > 0:
+		return ErrorResponse{Message: o.reject}
+	}
+	return nil
+}
+
+var errDroppedResponse = fmt.Errorf("connection reset by peer")
@@ -0,0 +1,216 @@
+package exante
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Quote model
+type Quote struct {
+	SymbolID  string       `json:"symbolId"`
+	Timestamp int64        `json:"timestamp"`
+	Bid       []QuoteLevel `json:"bid"`
+	Ask       []QuoteLevel `json:"ask"`
+}
+
+type QuoteLevel struct {
+	Price string `json:"price"`
+	Size  string `json:"size"`
+}
+
+// BestBid return the first bid level, if any
+func (q Quote) BestBid() (QuoteLevel, bool) {
+	if len(q.Bid) == 0 {
+		return QuoteLevel{}, false
+	}
+	return q.Bid[0], true
+}
+
+// BestAsk return the first ask level, if any
+func (q Quote) BestAsk() (QuoteLevel, bool) {
+	if len(q.Ask) == 0 {
+		return QuoteLevel{}, false
+	}
+	return q.Ask[0], true
+}
+
+// OHLC model
+type OHLC struct {
+	Timestamp int64  `json:"timestamp"`
+	Open      string `json:"open"`
+	High      string `json:"high"`
+	Low       string `json:"low"`
+	Close     string `json:"close"`
+	Volume    string `json:"volume"`
+}
+
+type OHLCs []OHLC
+
+// GetLastQuote return the last best price quote of a symbol
+func (a Api) GetLastQuote(symbolID string) (*Quote, error) {
+	var result []Quote
+	var errRes []ErrorResponse
+
+	resp, err := a.cli.R().
+		SetResult(&result).
+		SetError(&errRes).
+		SetQueryParam("level", "best_price").
+		SetHeader("Authorization", a.Bearer()).
+		Get(fmt.Sprintf("%s/md/3.0/feed/%s/last", a.BaseURL, url.PathEscape(symbolID)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode() >= http.StatusInternalServerError {
+		return nil, ErrInternalServer
+	}
+
+	if resp.IsError() {
+		if len(errRes) == 0 {
+			return nil, fmt.Errorf("error: %s", string(resp.Body()))
+		}
+		return nil, errRes[0]
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("no quote for symbol %s", symbolID)
+	}
+
+	return &result[0], nil
+}
+
+const (
+	defaultStreamBackoff = time.Second
+	maxStreamBackoff     = 30 * time.Second
+)
+
+// StreamQuotes keep a connection to the quote feed and send each new quote
+// to the returned channel. A dropped connection is opened again with an
+// exponential backoff, the channel is closed when ctx is done or exante
+// refuses the connection with a non temporary error.
+func (a Api) StreamQuotes(ctx context.Context, symbolID string) (<-chan Quote, error) {
+	body, err := a.openQuoteStream(ctx, symbolID)
+	if err != nil {
+		return nil, err
+	}
+
+	quotes := make(chan Quote)
+	go func() {
+		defer close(quotes)
+
+		backoff := a.streamBackoff
+		for {
+			if !readQuotes(ctx, body, quotes) {
+				return
+			}
+
+			for {
+				select {
+				case <-time.After(backoff):
+				case <-ctx.Done():
+					return
+				}
+
+				body, err = a.openQuoteStream(ctx, symbolID)
+				if err == nil {
+					backoff = a.streamBackoff
+					break
+				}
+				if !IsTemporary(err) {
+					return
+				}
+				backoff = min(2*backoff, maxStreamBackoff)
+			}
+		}
+	}()
+
+	return quotes, nil
+}
+
+func (a Api) openQuoteStream(ctx context.Context, symbolID string) (io.ReadCloser, error) {
+	resp, err := a.cli.R().
+		SetContext(ctx).
+		SetDoNotParseResponse(true).
+		SetQueryParam("level", "best_price").
+		SetHeader("Accept", "application/x-json-stream").
+		SetHeader("Authorization", a.Bearer()).
+		Get(fmt.Sprintf("%s/md/3.0/feed/%s", a.BaseURL, url.PathEscape(symbolID)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode() >= http.StatusInternalServerError {
+		resp.RawBody().Close()
+		return nil, ErrInternalServer
+	}
+
+	if resp.IsError() {
+		resp.RawBody().Close()
+		return nil, fmt.Errorf("error: %s", resp.Status())
+	}
+
+	return resp.RawBody(), nil
+}
+
+// readQuotes send the quotes of body until the connection drops, it
+// return false when ctx is done
+func readQuotes(ctx context.Context, body io.ReadCloser, quotes chan<- Quote) bool {
+	defer body.Close()
+
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		var q Quote
+		if err := json.Unmarshal(scanner.Bytes(), &q); err != nil {
+			continue
+		}
+		// heartbeats and status events have no symbol
+		if len(q.SymbolID) == 0 {
+			continue
+		}
+
+		select {
+		case quotes <- q:
+		case <-ctx.Done():
+			return false
+		}
+	}
+	return ctx.Err() == nil
+}
+
+// GetOHLC return the last candles of a symbol, duration is the candle size in seconds
+func (a Api) GetOHLC(symbolID string, duration int, size int) (OHLCs, error) {
+	var result OHLCs
+	var errRes []ErrorResponse
+
+	resp, err := a.cli.R().
+		SetResult(&result).
+		SetError(&errRes).
+		SetQueryParam("size", fmt.Sprintf("%d", size)).
+		SetHeader("Authorization", a.Bearer()).
+		Get(fmt.Sprintf("%s/md/3.0/ohlc/%s/%d", a.BaseURL, url.PathEscape(symbolID), duration))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode() >= http.StatusInternalServerError {
+		return nil, ErrInternalServer
+	}
+
+	if resp.IsError() {
+		if len(errRes) == 0 {
+			return nil, fmt.Errorf("error: %s", string(resp.Body()))
+		}
+		return nil, errRes[0]
+	}
+
+	return result, nil
+}
@@ -0,0 +1,117 @@
+package exante
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newStreamApi(url string) *Api {
+	api := NewApi(url, "stream-app", "stream-client", "stream-key")
+	api.cli.SetRetryCount(0)
+	api.streamBackoff = time.Millisecond
+	return api
+}
+
+func receive(t *testing.T, quotes <-chan Quote) (Quote, bool) {
+	select {
+	case q, ok := <-quotes:
+		return q, ok
+	case <-time.After(5 * time.Second):
+		t.Fatal("no quote received")
+		return Quote{}, false
+	}
+}
+
+func TestStreamQuotes(t *testing.T) {
+	t.Run("json lines should be parsed skipping heartbeats and broken lines", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/md/3.0/feed/EUR/USD.E.FX", r.URL.Path)
+			assert.Equal(t, "application/x-json-stream", r.Header.Get("Accept"))
+			fmt.Fprintln(w, `{"event":"heartbeat"}`)
+			fmt.Fprintln(w, `{"symbolId":"EUR/USD.E.FX","timestamp":1,"bid":[{"price":"1.1","size":"10"}],"ask":[{"price":"1.2","size":"5"}]}`)
+			fmt.Fprintln(w, `{broken`)
+			fmt.Fprintln(w, `{"symbolId":"EUR/USD.E.FX","timestamp":2}`)
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		}))
+		defer server.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		quotes, err := newStreamApi(server.URL).StreamQuotes(ctx, "EUR/USD.E.FX")
+		assert.NoError(t, err)
+
+		q, _ := receive(t, quotes)
+		assert.Equal(t, int64(1), q.Timestamp)
+		bid, _ := q.BestBid()
+		ask, _ := q.BestAsk()
+		assert.Equal(t, "1.1", bid.Price)
+		assert.Equal(t, "1.2", ask.Price)
+
+		q, _ = receive(t, quotes)
+		assert.Equal(t, int64(2), q.Timestamp)
+
+		cancel()
+		_, ok := receive(t, quotes)
+		assert.False(t, ok, "channel closed when ctx is done")
+	})
+
+	t.Run("server disconnect should open the stream again", func(t *testing.T) {
+		var conns int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := atomic.AddInt32(&conns, 1)
+			if n == 2 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			fmt.Fprintf(w, `{"symbolId":"EUR/USD.E.FX","timestamp":%d}`+"\n", n)
+		}))
+		defer server.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		quotes, err := newStreamApi(server.URL).StreamQuotes(ctx, "EUR/USD.E.FX")
+		assert.NoError(t, err)
+
+		q, _ := receive(t, quotes)
+		assert.Equal(t, int64(1), q.Timestamp)
+		q, _ = receive(t, quotes)
+		assert.Equal(t, int64(3), q.Timestamp, "reconnected after exante was unavailable")
+	})
+
+	t.Run("rejected reconnection should close the channel", func(t *testing.T) {
+		var conns int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&conns, 1) > 1 {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprintln(w, `{"symbolId":"EUR/USD.E.FX","timestamp":1}`)
+		}))
+		defer server.Close()
+
+		quotes, err := newStreamApi(server.URL).StreamQuotes(context.Background(), "EUR/USD.E.FX")
+		assert.NoError(t, err)
+
+		_, ok := receive(t, quotes)
+		assert.True(t, ok)
+		_, ok = receive(t, quotes)
+		assert.False(t, ok)
+	})
+
+	t.Run("unavailable feed should fail to open", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		_, err := newStreamApi(server.URL).StreamQuotes(context.Background(), "EUR/USD.E.FX")
+		assert.ErrorIs(t, err, ErrInternalServer)
+	})
+}
@@ -1,5 +1,7 @@
 package exante
 
+import "context"
+
 type Iface interface {
 	CancelOrder(orderID string) error
 	GetOrder(orderID string) (*OrderV3, error)
@@ -7,4 +9,10 @@ type Iface interface {
 	ReplaceOrder(orderID string, req ReplaceOrderPayload) (*OrderV3, error)
 	GetActiveOrdersV3() (OrdersV3, error)
 	GetOrdersByLimitV3(limit int, accountID string) (OrdersV3, error)
+	GetLastQuote(symbolID string) (*Quote, error)
+	StreamQuotes(ctx context.Context, symbolID string) (<-chan Quote, error)
+	GetOHLC(symbolID string, duration int, size int) (OHLCs, error)
+	GetInstrument(symbolID string) (*Instrument, error)
+	GetAccountSummary(accountID string, currency string) (*AccountSummary, error)
+	CircuitOpen() bool
 }
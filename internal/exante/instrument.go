@@ -0,0 +1,340 @@
+package exante
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SymbolV3 model
+type SymbolV3 struct {
+	SymbolID          string `json:"symbolId"`
+	Ticker            string `json:"ticker"`
+	Exchange          string `json:"exchange"`
+	Description       string `json:"description"`
+	SymbolType        string `json:"symbolType"`
+	Currency          string `json:"currency"`
+	MinPriceIncrement string `json:"minPriceIncrement"`
+	Expiration        int64  `json:"expiration"`
+}
+
+// SymbolSpecification model
+type SymbolSpecification struct {
+	Leverage           string `json:"leverage"`
+	ContractMultiplier string `json:"contractMultiplier"`
+	PriceUnit          string `json:"priceUnit"`
+	Units              string `json:"units"`
+	LotSize            string `json:"lotSize"`
+}
+
+// SymbolSchedule model
+type SymbolSchedule struct {
+	Intervals []ScheduleInterval `json:"intervals"`
+}
+
+type ScheduleInterval struct {
+	Name       string              `json:"name"`
+	Period     SchedulePeriod      `json:"period"`
+	OrderTypes map[string][]string `json:"orderTypes"`
+}
+
+type SchedulePeriod struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+}
+
+// Instrument aggregate symbol, specification and schedule of
+// an exante symbol in a single struct
+type Instrument struct {
+	SymbolID    string
+	Currency    string
+	TickSize    string
+	LotSize     string
+	MinQuantity string
+	// ContractMultiplier is the units of one contract
+	ContractMultiplier string
+	OrderTypes         []string
+	Schedule           []ScheduleInterval
+	Symbol             SymbolV3
+}
+
+// AllowOrderType check if the order type is accepted by the instrument,
+// an instrument without order types information accepts everything
+func (i Instrument) AllowOrderType(orderType string) bool {
+	if len(i.OrderTypes) == 0 {
+		return true
+	}
+	return slices.Contains(i.OrderTypes, orderType)
+}
+
+// RoundPrice round price to the nearest tick
+func (i Instrument) RoundPrice(price float64) string {
+	return roundToStep(price, i.TickSize, math.Round)
+}
+
+// RoundQuantity round down quantity to the lot size
+func (i Instrument) RoundQuantity(quantity float64) (string, error) {
+	qty := roundToStep(quantity, i.LotSize, math.Floor)
+
+	minQty, err := strconv.ParseFloat(i.MinQuantity, 64)
+	if err != nil {
+		return qty, nil
+	}
+	qtyF, _ := strconv.ParseFloat(qty, 64)
+	if qtyF < minQty {
+		return "", fmt.Errorf("quantity %s is lower than minimum %s for %s", qty, i.MinQuantity, i.SymbolID)
+	}
+	return qty, nil
+}
+
+func roundToStep(value float64, step string, roundFunc func(float64) float64) string {
+	stepF, err := strconv.ParseFloat(step, 64)
+	if err != nil || stepF <= 0 {
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+
+	decimals := 0
+	if idx := strings.Index(step, "."); idx > -1 {
+		decimals = len(strings.TrimRight(step[idx+1:], "0"))
+	}
+
+	// small epsilon avoid 0.3/0.1 = 2.9999 being floored to 2
+	rounded := roundFunc(value/stepF+1e-9) * stepF
+	s := strconv.FormatFloat(rounded, 'f', decimals, 64)
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	return s
+}
+
+func (a Api) GetSymbol(symbolID string) (*SymbolV3, error) {
+	var result SymbolV3
+	var errRes []ErrorResponse
+
+	resp, err := a.cli.R().
+		SetResult(&result).
+		SetError(&errRes).
+		SetHeader("Authorization", a.Bearer()).
+		Get(fmt.Sprintf("%s/md/3.0/symbols/%s", a.BaseURL, url.PathEscape(symbolID)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode() >= http.StatusInternalServerError {
+		return nil, ErrInternalServer
+	}
+
+	if resp.IsError() {
+		if len(errRes) == 0 {
+			return nil, fmt.Errorf("error: %s", string(resp.Body()))
+		}
+		return nil, errRes[0]
+	}
+
+	return &result, nil
+}
+
+// GetSymbols return every symbol available on exante
+func (a Api) GetSymbols() ([]SymbolV3, error) {
+	var result []SymbolV3
+	var errRes []ErrorResponse
+
+	resp, err := a.cli.R().
+		SetResult(&result).
+		SetError(&errRes).
+		SetHeader("Authorization", a.Bearer()).
+		Get(fmt.Sprintf("%s/md/3.0/symbols", a.BaseURL))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode() >= http.StatusInternalServerError {
+		return nil, ErrInternalServer
+	}
+
+	if resp.IsError() {
+		if len(errRes) == 0 {
+			return nil, fmt.Errorf("error: %s", string(resp.Body()))
+		}
+		return nil, errRes[0]
+	}
+
+	return result, nil
+}
+
+func (a Api) GetSymbolSpecification(symbolID string) (*SymbolSpecification, error) {
+	var result SymbolSpecification
+	var errRes []ErrorResponse
+
+	resp, err := a.cli.R().
+		SetResult(&result).
+		SetError(&errRes).
+		SetHeader("Authorization", a.Bearer()).
+		Get(fmt.Sprintf("%s/md/3.0/symbols/%s/specification", a.BaseURL, url.PathEscape(symbolID)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode() >= http.StatusInternalServerError {
+		return nil, ErrInternalServer
+	}
+
+	if resp.IsError() {
+		if len(errRes) == 0 {
+			return nil, fmt.Errorf("error: %s", string(resp.Body()))
+		}
+		return nil, errRes[0]
+	}
+
+	return &result, nil
+}
+
+func (a Api) GetSymbolSchedule(symbolID string) (*SymbolSchedule, error) {
+	var result SymbolSchedule
+	var errRes []ErrorResponse
+
+	resp, err := a.cli.R().
+		SetResult(&result).
+		SetError(&errRes).
+		SetQueryParam("types", "true").
+		SetHeader("Authorization", a.Bearer()).
+		Get(fmt.Sprintf("%s/md/3.0/symbols/%s/schedule", a.BaseURL, url.PathEscape(symbolID)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode() >= http.StatusInternalServerError {
+		return nil, ErrInternalServer
+	}
+
+	if resp.IsError() {
+		if len(errRes) == 0 {
+			return nil, fmt.Errorf("error: %s", string(resp.Body()))
+		}
+		return nil, errRes[0]
+	}
+
+	return &result, nil
+}
+
+// GetInstrument return the instrument metadata, results are cached
+// for instrumentCacheTTL to avoid calling exante on every order. Unknown
+// symbols are cached for instrumentMissTTL, exante being unavailable isn't.
+func (a Api) GetInstrument(symbolID string) (*Instrument, error) {
+	if instrument, err, has := a.instruments.get(symbolID); has {
+		if err != nil {
+			return nil, err
+		}
+		return &instrument, nil
+	}
+
+	symbol, err := a.GetSymbol(symbolID)
+	if err != nil {
+		return nil, a.instruments.miss(symbolID, err)
+	}
+
+	spec, err := a.GetSymbolSpecification(symbolID)
+	if err != nil {
+		return nil, a.instruments.miss(symbolID, err)
+	}
+
+	schedule, err := a.GetSymbolSchedule(symbolID)
+	if err != nil {
+		return nil, a.instruments.miss(symbolID, err)
+	}
+
+	instrument := newInstrument(*symbol, *spec, *schedule)
+	a.instruments.set(symbolID, instrument)
+	return &instrument, nil
+}
+
+func newInstrument(symbol SymbolV3, spec SymbolSpecification, schedule SymbolSchedule) Instrument {
+	orderTypes := make([]string, 0)
+	for _, interval := range schedule.Intervals {
+		for ot := range interval.OrderTypes {
+			if !slices.Contains(orderTypes, ot) {
+				orderTypes = append(orderTypes, ot)
+			}
+		}
+	}
+
+	return Instrument{
+		SymbolID:           symbol.SymbolID,
+		Currency:           symbol.Currency,
+		TickSize:           symbol.MinPriceIncrement,
+		LotSize:            spec.LotSize,
+		MinQuantity:        spec.LotSize,
+		ContractMultiplier: spec.ContractMultiplier,
+		OrderTypes:         orderTypes,
+		Schedule:           schedule.Intervals,
+		Symbol:             symbol,
+	}
+}
+
+const (
+	instrumentCacheTTL = 24 * time.Hour
+	instrumentMissTTL  = 5 * time.Minute
+)
+
+type instrumentCache struct {
+	mu    sync.RWMutex
+	items map[string]instrumentCacheItem
+}
+
+type instrumentCacheItem struct {
+	instrument Instrument
+	err        error
+	expiresAt  time.Time
+}
+
+func newInstrumentCache() *instrumentCache {
+	return &instrumentCache{items: make(map[string]instrumentCacheItem)}
+}
+
+// get return the cached instrument of symbolID, or the error of its lookup
+func (c *instrumentCache) get(symbolID string) (Instrument, error, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	item, has := c.items[symbolID]
+	if !has || time.Now().After(item.expiresAt) {
+		return Instrument{}, nil, false
+	}
+	return item.instrument, item.err, true
+}
+
+// miss keep err for symbolID unless it is temporary, err is returned
+func (c *instrumentCache) miss(symbolID string, err error) error {
+	if IsTemporary(err) {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.items[symbolID] = instrumentCacheItem{
+		err:       err,
+		expiresAt: time.Now().Add(instrumentMissTTL),
+	}
+	return err
+}
+
+func (c *instrumentCache) set(symbolID string, instrument Instrument) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.items[symbolID] = instrumentCacheItem{
+		instrument: instrument,
+		expiresAt:  time.Now().Add(instrumentCacheTTL),
+	}
+}
@@ -0,0 +1,54 @@
+package exante
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type countingTransport struct {
+	calls int
+}
+
+func (c *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.calls++
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestGetInstrument(t *testing.T) {
+	t.Run("unknown symbol should not call exante again while cached", func(t *testing.T) {
+		fake := NewFakeServer()
+		defer fake.Close()
+		api := fake.NewApi()
+		transport := &countingTransport{}
+		api.SetTransport(transport)
+
+		_, err := api.GetInstrument("UNKNOWN.FX")
+		assert.Error(t, err)
+		calls := transport.calls
+
+		_, again := api.GetInstrument("UNKNOWN.FX")
+		assert.Equal(t, err, again)
+		assert.Equal(t, calls, transport.calls)
+	})
+
+	t.Run("exante being unavailable should not be cached", func(t *testing.T) {
+		fake := NewFakeServer()
+		defer fake.Close()
+		api := fake.NewApi()
+		transport := &countingTransport{}
+		api.SetTransport(transport)
+		fake.AddSymbol(SymbolV3{SymbolID: "EUR/USD.E.FX", MinPriceIncrement: "0.00001"}, SymbolSpecification{LotSize: "1"}, SymbolSchedule{})
+
+		fake.SetFault(EndpointSymbols, Fault{ServerErrRate: 1})
+		_, err := api.GetInstrument("EUR/USD.E.FX")
+		assert.True(t, IsTemporary(err))
+
+		fake.SetFault(EndpointSymbols, Fault{})
+		calls := transport.calls
+		_, err = api.GetInstrument("EUR/USD.E.FX")
+		assert.NoError(t, err)
+		assert.Greater(t, transport.calls, calls)
+	})
+}
@@ -1,5 +1,10 @@
 package exante
 
+import (
+	"context"
+	"time"
+)
+
 type ApiMock struct {
 	CancelOrderFunc        func(orderID string) error
 	GetOrderFunc           func(orderID string) (*OrderV3, error)
@@ -7,36 +12,122 @@ type ApiMock struct {
 	ReplaceOrderFunc       func(orderID string, req ReplaceOrderPayload) (*OrderV3, error)
 	GetOrdersByLimitV3Func func(limit int, accountID string) ([]OrderV3, error)
 	GetActiveOrdersV3Func  func() (OrdersV3, error)
+	GetLastQuoteFunc       func(symbolID string) (*Quote, error)
+	StreamQuotesFunc       func(ctx context.Context, symbolID string) (<-chan Quote, error)
+	GetOHLCFunc            func(symbolID string, duration int, size int) (OHLCs, error)
+	GetInstrumentFunc      func(symbolID string) (*Instrument, error)
+	GetAccountSummaryFunc  func(accountID string, currency string) (*AccountSummary, error)
+	CircuitOpenFunc        func() bool
+	Faults                 *Faults
 	TotalCalls             int
 	TotalPlaceOrderV3      int
 	orders                 []OrderV3
 }
 
+// inject apply the configured fault of endpoint before the call
+func (a *ApiMock) inject(endpoint Endpoint) (faultOutcome, error) {
+	outcome := a.Faults.decide(endpoint)
+	time.Sleep(outcome.latency)
+	return outcome, outcome.err()
+}
+
 func (a *ApiMock) GetActiveOrdersV3() (OrdersV3, error) {
+	if _, err := a.inject(EndpointActiveOrders); err != nil {
+		return nil, err
+	}
 	return a.GetActiveOrdersV3Func()
 }
 
 func (a *ApiMock) GetOrdersByLimitV3(limit int, accountID string) (OrdersV3, error) {
+	if _, err := a.inject(EndpointListOrders); err != nil {
+		return nil, err
+	}
 	return a.GetOrdersByLimitV3Func(limit, accountID)
 }
 
 func (a *ApiMock) ReplaceOrder(orderID string, req ReplaceOrderPayload) (*OrderV3, error) {
 	a.TotalCalls++
-	return a.ReplaceOrderFunc(orderID, req)
+	outcome, err := a.inject(EndpointReplaceOrder)
+	if err != nil {
+		return nil, err
+	}
+	order, err := a.ReplaceOrderFunc(orderID, req)
+	if outcome.dropResponse {
+		return nil, errDroppedResponse
+	}
+	return order, err
 }
 
 func (a *ApiMock) CancelOrder(orderID string) error {
 	a.TotalCalls++
-	return a.CancelOrderFunc(orderID)
+	outcome, err := a.inject(EndpointCancelOrder)
+	if err != nil {
+		return err
+	}
+	err = a.CancelOrderFunc(orderID)
+	if outcome.dropResponse {
+		return errDroppedResponse
+	}
+	return err
 }
 
 func (a *ApiMock) GetOrder(orderID string) (*OrderV3, error) {
 	a.TotalCalls++
+	if _, err := a.inject(EndpointGetOrder); err != nil {
+		return nil, err
+	}
 	return a.GetOrderFunc(orderID)
 }
 
 func (a *ApiMock) PlaceOrderV3(req *OrderSentTypeV3) ([]OrderV3, error) {
 	a.TotalCalls++
 	a.TotalPlaceOrderV3++
-	return a.PlaceOrderV3Func(req)
+	outcome, err := a.inject(EndpointPlaceOrder)
+	if err != nil {
+		return nil, err
+	}
+	orders, err := a.PlaceOrderV3Func(req)
+	if outcome.dropResponse {
+		return nil, errDroppedResponse
+	}
+	return orders, err
+}
+
+func (a *ApiMock) GetLastQuote(symbolID string) (*Quote, error) {
+	if _, err := a.inject(EndpointQuote); err != nil {
+		return nil, err
+	}
+	return a.GetLastQuoteFunc(symbolID)
+}
+
+func (a *ApiMock) StreamQuotes(ctx context.Context, symbolID string) (<-chan Quote, error) {
+	if _, err := a.inject(EndpointQuote); err != nil {
+		return nil, err
+	}
+	return a.StreamQuotesFunc(ctx, symbolID)
+}
+
+func (a *ApiMock) GetOHLC(symbolID string, duration int, size int) (OHLCs, error) {
+	if _, err := a.inject(EndpointOHLC); err != nil {
+		return nil, err
+	}
+	return a.GetOHLCFunc(symbolID, duration, size)
+}
+
+func (a *ApiMock) GetInstrument(symbolID string) (*Instrument, error) {
+	if _, err := a.inject(EndpointSymbols); err != nil {
+		return nil, err
+	}
+	return a.GetInstrumentFunc(symbolID)
+}
+
+func (a *ApiMock) GetAccountSummary(accountID string, currency string) (*AccountSummary, error) {
+	if _, err := a.inject(EndpointSummary); err != nil {
+		return nil, err
+	}
+	return a.GetAccountSummaryFunc(accountID, currency)
+}
+
+func (a *ApiMock) CircuitOpen() bool {
+	return a.CircuitOpenFunc()
 }
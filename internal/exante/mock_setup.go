@@ -1,6 +1,7 @@
 package exante
 
 import (
+	"context"
 	"fmt"
 	"github.com/google/uuid"
 	"slices"
@@ -30,21 +31,28 @@ func NewMock(ordersList []OrderV3) *ApiMock {
 			if len(req.IfDoneParentID) > 0 {
 				doneParentOrder = req.IfDoneParentID
 			}
-			if len(req.LimitPrice) > 0 {
+			if len(req.LimitPrice) > 0 || req.OrderType == "market" {
+				// an order attached to a parent has its own id
+				orderID := doneParentOrder
+				if len(req.IfDoneParentID) > 0 {
+					orderID = uuid.NewString()
+				}
 				orders = append(orders, OrderV3{
 					AccountID: req.AccountID,
 					OrderState: OrderState{
 						Status: convertTypeToStatus(req.OrderType),
 					},
 					OrderParameters: OrderParameters{
-						Quantity:   req.Quantity,
-						Side:       req.Side,
-						Instrument: req.Instrument,
-						OrderType:  req.OrderType,
-						LimitPrice: req.LimitPrice,
-						OcoGroup:   req.OcoGroup,
+						SymbolId:       req.SymbolID,
+						Quantity:       req.Quantity,
+						Side:           req.Side,
+						Instrument:     req.Instrument,
+						OrderType:      req.OrderType,
+						LimitPrice:     req.LimitPrice,
+						OcoGroup:       req.OcoGroup,
+						IfDoneParentID: req.IfDoneParentID,
 					},
-					OrderID:   doneParentOrder,
+					OrderID:   orderID,
 					ClientTag: req.ClientTag,
 				})
 			}
@@ -61,6 +69,7 @@ func NewMock(ordersList []OrderV3) *ApiMock {
 						Status: PendingStatus,
 					},
 					OrderParameters: OrderParameters{
+						SymbolId:       req.SymbolID,
 						OcoGroup:       ocoGroup,
 						LimitPrice:     *req.TakeProfit,
 						OrderType:      "limit",
@@ -77,6 +86,7 @@ func NewMock(ordersList []OrderV3) *ApiMock {
 						Status: PendingStatus,
 					},
 					OrderParameters: OrderParameters{
+						SymbolId:       req.SymbolID,
 						OcoGroup:       ocoGroup,
 						OrderType:      "stop",
 						LimitPrice:     *req.StopLoss,
@@ -95,6 +105,7 @@ func NewMock(ordersList []OrderV3) *ApiMock {
 						Status: PendingStatus,
 					},
 					OrderParameters: OrderParameters{
+						SymbolId:       req.SymbolID,
 						OcoGroup:       ocoGroup,
 						OrderType:      "stop",
 						LimitPrice:     *req.StopPrice,
@@ -139,6 +150,29 @@ func NewMock(ordersList []OrderV3) *ApiMock {
 			}
 			return newList, nil
 		},
+		GetLastQuoteFunc: func(symbolID string) (*Quote, error) {
+			return nil, fmt.Errorf("no quote for symbol %s", symbolID)
+		},
+		StreamQuotesFunc: func(ctx context.Context, symbolID string) (<-chan Quote, error) {
+			quotes := make(chan Quote)
+			go func() {
+				<-ctx.Done()
+				close(quotes)
+			}()
+			return quotes, nil
+		},
+		GetOHLCFunc: func(symbolID string, duration int, size int) (OHLCs, error) {
+			return OHLCs{}, nil
+		},
+		GetInstrumentFunc: func(symbolID string) (*Instrument, error) {
+			return &Instrument{SymbolID: symbolID}, nil
+		},
+		GetAccountSummaryFunc: func(accountID string, currency string) (*AccountSummary, error) {
+			return &AccountSummary{Account: accountID, Currency: currency}, nil
+		},
+		CircuitOpenFunc: func() bool {
+			return false
+		},
 	}
 }
 
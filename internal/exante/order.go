@@ -0,0 +1,223 @@
+package exante
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+)
+
+const (
+	OrderTypeMarket       = "market"
+	OrderTypeLimit        = "limit"
+	OrderTypeStop         = "stop"
+	OrderTypeStopLimit    = "stop_limit"
+	OrderTypeIceberg      = "iceberg"
+	OrderTypeTwap         = "twap"
+	OrderTypeTrailingStop = "trailing_stop"
+
+	DurationDay               = "day"
+	DurationFillOrKill        = "fill_or_kill"
+	DurationImmediateOrCancel = "immediate_or_cancel"
+	DurationGoodTillCancel    = "good_till_cancel"
+	DurationGoodTillTime      = "good_till_time"
+	DurationAtTheOpening      = "at_the_opening"
+	DurationAtTheClose        = "at_the_close"
+)
+
+var (
+	orderTypes = []string{
+		OrderTypeMarket, OrderTypeLimit, OrderTypeStop, OrderTypeStopLimit,
+		OrderTypeIceberg, OrderTypeTwap, OrderTypeTrailingStop,
+	}
+	durations = []string{
+		DurationDay, DurationFillOrKill, DurationImmediateOrCancel, DurationGoodTillCancel,
+		DurationGoodTillTime, DurationAtTheOpening, DurationAtTheClose,
+	}
+)
+
+// Validate check the fields required by the order type and duration,
+// it avoids sending to exante an order that would be rejected
+func (r OrderSentTypeV3) Validate() error {
+	if len(r.AccountID) == 0 {
+		return invalidOrder("accountId is required")
+	}
+	if len(r.SymbolID) == 0 && len(r.Instrument) == 0 {
+		return invalidOrder("symbolId is required")
+	}
+	if r.Side != "buy" && r.Side != "sell" {
+		return invalidOrder("side must be buy or sell, got %q", r.Side)
+	}
+	if !isPositive(r.Quantity) {
+		return invalidOrder("quantity must be positive, got %q", r.Quantity)
+	}
+	if !slices.Contains(durations, r.Duration) {
+		return invalidOrder("unknown duration %q", r.Duration)
+	}
+	if r.Duration == DurationGoodTillTime && len(r.GttExpiration) == 0 {
+		return invalidOrder("gttExpiration is required for %s duration", DurationGoodTillTime)
+	}
+	if !slices.Contains(orderTypes, r.OrderType) {
+		return invalidOrder("unknown order type %q", r.OrderType)
+	}
+
+	required := map[string]string{}
+	switch r.OrderType {
+	case OrderTypeLimit:
+		required["limitPrice"] = r.LimitPrice
+	case OrderTypeStop:
+		required["stopPrice"] = deref(r.StopPrice)
+	case OrderTypeStopLimit:
+		required["limitPrice"] = r.LimitPrice
+		required["stopPrice"] = deref(r.StopPrice)
+	case OrderTypeIceberg:
+		required["limitPrice"] = r.LimitPrice
+		required["partQuantity"] = r.PartQuantity
+	case OrderTypeTwap:
+		required["partQuantity"] = r.PartQuantity
+		required["placeInterval"] = r.PlaceInterval
+	case OrderTypeTrailingStop:
+		required["priceDistance"] = r.PriceDistance
+	}
+
+	// sorted to return always the same error
+	fields := make([]string, 0, len(required))
+	for field := range required {
+		fields = append(fields, field)
+	}
+	slices.Sort(fields)
+	for _, field := range fields {
+		if !isPositive(required[field]) {
+			return invalidOrder("%s is required for %s orders", field, r.OrderType)
+		}
+	}
+
+	return nil
+}
+
+func invalidOrder(format string, args ...any) error {
+	return fmt.Errorf("invalid order: %s", fmt.Sprintf(format, args...))
+}
+
+func isPositive(s string) bool {
+	v, err := strconv.ParseFloat(s, 64)
+	return err == nil && v > 0
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// OrderBuilder build a validated OrderSentTypeV3
+//
+//	req, err := NewOrderBuilder(accountID, "EUR/USD.E.FX", "buy", "10000").
+//		StopLimit("1.0950", "1.0955").
+//		GoodTillTime(time.Now().Add(time.Hour)).
+//		Build()
+type OrderBuilder struct {
+	req OrderSentTypeV3
+}
+
+func NewOrderBuilder(accountID, symbolID, side, quantity string) *OrderBuilder {
+	return &OrderBuilder{req: OrderSentTypeV3{
+		AccountID:  accountID,
+		SymbolID:   symbolID,
+		Instrument: symbolID,
+		Side:       side,
+		Quantity:   quantity,
+		Duration:   DurationGoodTillCancel,
+		OrderType:  OrderTypeMarket,
+	}}
+}
+
+func (b *OrderBuilder) Market() *OrderBuilder {
+	b.req.OrderType = OrderTypeMarket
+	return b
+}
+
+func (b *OrderBuilder) Limit(limitPrice string) *OrderBuilder {
+	b.req.OrderType = OrderTypeLimit
+	b.req.LimitPrice = limitPrice
+	return b
+}
+
+func (b *OrderBuilder) Stop(stopPrice string) *OrderBuilder {
+	b.req.OrderType = OrderTypeStop
+	b.req.StopPrice = &stopPrice
+	return b
+}
+
+func (b *OrderBuilder) StopLimit(stopPrice, limitPrice string) *OrderBuilder {
+	b.req.OrderType = OrderTypeStopLimit
+	b.req.StopPrice = &stopPrice
+	b.req.LimitPrice = limitPrice
+	return b
+}
+
+func (b *OrderBuilder) Iceberg(limitPrice, partQuantity string) *OrderBuilder {
+	b.req.OrderType = OrderTypeIceberg
+	b.req.LimitPrice = limitPrice
+	b.req.PartQuantity = partQuantity
+	return b
+}
+
+// Twap split the order in parts of partQuantity placed every placeInterval seconds
+func (b *OrderBuilder) Twap(partQuantity, placeInterval string) *OrderBuilder {
+	b.req.OrderType = OrderTypeTwap
+	b.req.PartQuantity = partQuantity
+	b.req.PlaceInterval = placeInterval
+	return b
+}
+
+func (b *OrderBuilder) TrailingStop(priceDistance string) *OrderBuilder {
+	b.req.OrderType = OrderTypeTrailingStop
+	b.req.PriceDistance = priceDistance
+	return b
+}
+
+func (b *OrderBuilder) Duration(duration string) *OrderBuilder {
+	b.req.Duration = duration
+	return b
+}
+
+func (b *OrderBuilder) GoodTillTime(expiration time.Time) *OrderBuilder {
+	b.req.Duration = DurationGoodTillTime
+	b.req.GttExpiration = expiration.UTC().Format(time.RFC3339)
+	return b
+}
+
+func (b *OrderBuilder) TakeProfit(price string) *OrderBuilder {
+	b.req.TakeProfit = &price
+	return b
+}
+
+func (b *OrderBuilder) StopLoss(price string) *OrderBuilder {
+	b.req.StopLoss = &price
+	return b
+}
+
+func (b *OrderBuilder) ClientTag(tag string) *OrderBuilder {
+	b.req.ClientTag = tag
+	return b
+}
+
+func (b *OrderBuilder) OcoGroup(group string) *OrderBuilder {
+	b.req.OcoGroup = group
+	return b
+}
+
+func (b *OrderBuilder) IfDoneParent(orderID string) *OrderBuilder {
+	b.req.IfDoneParentID = orderID
+	return b
+}
+
+func (b *OrderBuilder) Build() (*OrderSentTypeV3, error) {
+	req := b.req
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
@@ -0,0 +1,68 @@
+package exante
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderBuilder(t *testing.T) {
+	newBuilder := func() *OrderBuilder {
+		return NewOrderBuilder("ACC001.001", "EUR/USD.E.FX", "buy", "10000")
+	}
+
+	tests := []struct {
+		name    string
+		builder *OrderBuilder
+		err     string
+	}{
+		{name: "market", builder: newBuilder().Market()},
+		{name: "limit", builder: newBuilder().Limit("1.1")},
+		{name: "limit without price", builder: newBuilder().Limit(""), err: "limitPrice is required for limit orders"},
+		{name: "stop", builder: newBuilder().Stop("1.1")},
+		{name: "stop limit", builder: newBuilder().StopLimit("1.1", "1.11")},
+		{name: "stop limit without limit", builder: newBuilder().StopLimit("1.1", ""), err: "limitPrice is required for stop_limit orders"},
+		{name: "iceberg", builder: newBuilder().Iceberg("1.1", "1000")},
+		{name: "iceberg without part", builder: newBuilder().Iceberg("1.1", ""), err: "partQuantity is required for iceberg orders"},
+		{name: "twap", builder: newBuilder().Twap("1000", "60")},
+		{name: "twap without interval", builder: newBuilder().Twap("1000", ""), err: "placeInterval is required for twap orders"},
+		{name: "trailing stop", builder: newBuilder().TrailingStop("0.002")},
+		{name: "trailing stop without distance", builder: newBuilder().TrailingStop("0"), err: "priceDistance is required for trailing_stop orders"},
+		{name: "good till time", builder: newBuilder().Limit("1.1").GoodTillTime(time.Now().Add(time.Hour))},
+		{name: "good till time without expiration", builder: newBuilder().Limit("1.1").Duration(DurationGoodTillTime), err: "gttExpiration is required"},
+		{name: "unknown duration", builder: newBuilder().Duration("forever"), err: "unknown duration"},
+		{name: "no quantity", builder: NewOrderBuilder("ACC001.001", "EUR/USD.E.FX", "buy", "0"), err: "quantity must be positive"},
+		{name: "wrong side", builder: NewOrderBuilder("ACC001.001", "EUR/USD.E.FX", "long", "1"), err: "side must be buy or sell"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := tt.builder.Build()
+			if len(tt.err) > 0 {
+				assert.ErrorContains(t, err, tt.err)
+				assert.Nil(t, req)
+				return
+			}
+			assert.NoError(t, err)
+			assert.NotNil(t, req)
+		})
+	}
+
+	t.Run("invalid order is not sent to exante", func(t *testing.T) {
+		fake := NewFakeServer()
+		defer fake.Close()
+		api := fake.NewApi()
+
+		_, err := api.PlaceOrderV3(&OrderSentTypeV3{
+			AccountID: FakeAccountID,
+			SymbolID:  "EUR/USD.E.FX",
+			Side:      "buy",
+			Quantity:  "1",
+			Duration:  DurationGoodTillCancel,
+			OrderType: OrderTypeStop,
+		})
+		assert.ErrorContains(t, err, "stopPrice is required for stop orders")
+		assert.Len(t, fake.Orders(), 0)
+	})
+}
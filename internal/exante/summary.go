@@ -0,0 +1,81 @@
+package exante
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// AccountSummary model
+type AccountSummary struct {
+	Account            string            `json:"account"`
+	Currency           string            `json:"currency"`
+	NetAssetValue      string            `json:"netAssetValue"`
+	FreeMoney          string            `json:"freeMoney"`
+	MoneyUsedForMargin string            `json:"moneyUsedForMargin"`
+	MarginUtilization  string            `json:"marginUtilization"`
+	SessionDate        string            `json:"sessionDate"`
+	Timestamp          int64             `json:"timestamp"`
+	Currencies         []SummaryCurrency `json:"currencies"`
+	Positions          []SummaryPosition `json:"positions"`
+}
+
+type SummaryCurrency struct {
+	Code           string `json:"code"`
+	Value          string `json:"value"`
+	ConvertedValue string `json:"convertedValue"`
+}
+
+type SummaryPosition struct {
+	ID             string `json:"id"`
+	SymbolID       string `json:"symbolId"`
+	SymbolType     string `json:"symbolType"`
+	AccountID      string `json:"accountId"`
+	Currency       string `json:"currency"`
+	Quantity       string `json:"quantity"`
+	Price          string `json:"price"`
+	AveragePrice   string `json:"averagePrice"`
+	Value          string `json:"value"`
+	ConvertedValue string `json:"convertedValue"`
+	PnL            string `json:"pnl"`
+	ConvertedPnL   string `json:"convertedPnl"`
+}
+
+// GetPosition return the open position of a symbol, if any
+func (s AccountSummary) GetPosition(symbolID string) (SummaryPosition, bool) {
+	for _, position := range s.Positions {
+		if position.SymbolID == symbolID {
+			return position, true
+		}
+	}
+	return SummaryPosition{}, false
+}
+
+// GetAccountSummary return balances, margin and open positions of an account
+// with values converted to currency
+func (a Api) GetAccountSummary(accountID string, currency string) (*AccountSummary, error) {
+	var result AccountSummary
+	var errRes []ErrorResponse
+
+	resp, err := a.cli.R().
+		SetResult(&result).
+		SetError(&errRes).
+		SetHeader("Authorization", a.Bearer()).
+		Get(fmt.Sprintf("%s/md/3.0/summary/%s/%s", a.BaseURL, accountID, currency))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode() >= http.StatusInternalServerError {
+		return nil, ErrInternalServer
+	}
+
+	if resp.IsError() {
+		if len(errRes) == 0 {
+			return nil, fmt.Errorf("error: %s", string(resp.Body()))
+		}
+		return nil, errRes[0]
+	}
+
+	return &result, nil
+}
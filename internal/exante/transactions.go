@@ -0,0 +1,133 @@
+package exante
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Transaction model
+type Transaction struct {
+	ID            int64  `json:"id"`
+	UUID          string `json:"uuid"`
+	ParentUUID    string `json:"parentUuid"`
+	AccountID     string `json:"accountId"`
+	SymbolID      string `json:"symbolId"`
+	Asset         string `json:"asset"`
+	Amount        string `json:"amount"`
+	OperationType string `json:"operationType"`
+	Timestamp     int64  `json:"timestamp"`
+	ValueDate     string `json:"valueDate"`
+	OrderID       string `json:"orderId"`
+	OrderPos      int    `json:"orderPos"`
+	Category      string `json:"category"`
+	Comment       string `json:"comment"`
+}
+
+type Transactions []Transaction
+
+const (
+	OperationTrade      = "TRADE"
+	OperationCommission = "COMMISSION"
+	OperationFunding    = "FUNDING/WITHDRAWAL"
+	OperationRollover   = "ROLLOVER"
+	OperationInterest   = "INTEREST"
+)
+
+// TransactionsFilter is the optional filter of GetTransactions,
+// empty values are not sent
+type TransactionsFilter struct {
+	AccountID      string
+	SymbolID       string
+	Asset          string
+	OperationTypes []string
+	FromDate       time.Time
+	ToDate         time.Time
+	Offset         int
+	Limit          int
+}
+
+func (f TransactionsFilter) queryParams() map[string]string {
+	params := map[string]string{
+		"order": "asc",
+	}
+	if len(f.AccountID) > 0 {
+		params["accountId"] = f.AccountID
+	}
+	if len(f.SymbolID) > 0 {
+		params["symbolId"] = f.SymbolID
+	}
+	if len(f.Asset) > 0 {
+		params["asset"] = f.Asset
+	}
+	if len(f.OperationTypes) > 0 {
+		params["operationType"] = strings.Join(f.OperationTypes, ",")
+	}
+	if !f.FromDate.IsZero() {
+		params["fromDate"] = f.FromDate.Format("2006-01-02T15:04:05Z")
+	}
+	if !f.ToDate.IsZero() {
+		params["toDate"] = f.ToDate.Format("2006-01-02T15:04:05Z")
+	}
+	if f.Offset > 0 {
+		params["offset"] = fmt.Sprintf("%d", f.Offset)
+	}
+	if f.Limit > 0 {
+		params["limit"] = fmt.Sprintf("%d", f.Limit)
+	}
+	return params
+}
+
+// GetTransactions return a single page of transactions
+func (a Api) GetTransactions(filter TransactionsFilter) (Transactions, error) {
+	var result Transactions
+	var errRes []ErrorResponse
+
+	resp, err := a.cli.R().
+		SetResult(&result).
+		SetError(&errRes).
+		SetQueryParams(filter.queryParams()).
+		SetHeader("Authorization", a.Bearer()).
+		Get(fmt.Sprintf("%s/md/3.0/transactions", a.BaseURL))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode() >= http.StatusInternalServerError {
+		return nil, ErrInternalServer
+	}
+
+	if resp.IsError() {
+		if len(errRes) == 0 {
+			return nil, fmt.Errorf("error: %s", string(resp.Body()))
+		}
+		return nil, errRes[0]
+	}
+
+	return result, nil
+}
+
+const transactionsPageSize = 1000
+
+// GetAllTransactions walk through all pages of transactions matching filter
+func (a Api) GetAllTransactions(filter TransactionsFilter) (Transactions, error) {
+	if filter.Limit == 0 {
+		filter.Limit = transactionsPageSize
+	}
+
+	all := make(Transactions, 0)
+	for {
+		page, err := a.GetTransactions(filter)
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, page...)
+		if len(page) < filter.Limit {
+			return all, nil
+		}
+		filter.Offset += len(page)
+	}
+}
@@ -0,0 +1,96 @@
+package exchanges
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// rolloverNotice is how many days before its last contract a rollover
+// calendar is reported
+const rolloverNotice = 30
+
+// Warnings return mappings that load but probably do not do what is
+// expected: exact symbols also matched by a pattern or regex to another
+// exante symbol, symbols equal to another one once the suffix is stripped,
+// disabled exchanges still holding Placeholder and rollover calendars
+// ending before rolloverNotice days
+func (d Data) Warnings(now time.Time) []string {
+	warnings := make([]string, 0)
+
+	patterns := make([]DataExchanges, 0)
+	for _, e := range d.Exchanges {
+		if len(e.Pattern) > 0 || len(e.Regex) > 0 {
+			patterns = append(patterns, e)
+		}
+	}
+	byPattern := newIndex(Data{Suffixes: d.Suffixes, Exchanges: patterns})
+	exact := newIndex(Data{Exchanges: d.Exchanges})
+
+	for idx, e := range d.Exchanges {
+		if e.Exante == Placeholder && !e.IsEnabled() {
+			warnings = append(warnings, fmt.Sprintf("exchange %d (%s): exante is %s, the symbol stays disabled", idx+1, e.key(), Placeholder))
+		}
+		if e.Rollover != nil {
+			if _, has := e.Rollover.Current(now); !has {
+				warnings = append(warnings, fmt.Sprintf("exchange %d (%s): every rollover contract reached its roll date, new orders are rejected", idx+1, e.key()))
+			} else if _, has := e.Rollover.Current(now.AddDate(0, 0, rolloverNotice)); !has {
+				warnings = append(warnings, fmt.Sprintf("exchange %d (%s): the rollover calendar ends in less than %d days", idx+1, e.key(), rolloverNotice))
+			}
+		}
+		if len(e.MetaTrader) == 0 {
+			continue
+		}
+		e = e.At(now)
+
+		for _, symbol := range append([]string{e.MetaTrader}, e.Aliases...) {
+			if other, has := byPattern.resolve(symbol); has && other.At(now).Exante != e.Exante {
+				other = other.At(now)
+				warnings = append(warnings, fmt.Sprintf("exchange %d (%s): %s is also matched by %s to %s, %s is used", idx+1, e.key(), symbol, other.key(), other.Exante, e.Exante))
+			}
+
+			stripped, isStripped := byPattern.strip(symbol)
+			if !isStripped {
+				continue
+			}
+			if other, has := exact.get(stripped); has && other.At(now).Exante != e.Exante {
+				other = other.At(now)
+				warnings = append(warnings, fmt.Sprintf("exchange %d (%s): %s is %s without suffix, mapped to %s instead of %s", idx+1, e.key(), symbol, stripped, other.Exante, e.Exante))
+			}
+		}
+	}
+	return warnings
+}
+
+// ExanteSymbols return the exante symbols and rollover contracts of the
+// mapping, in file order. Placeholders and regex symbols using groups are
+// skipped, they are only known once a MT5 symbol is matched.
+func (d Data) ExanteSymbols() []string {
+	symbols := make([]string, 0, len(d.Exchanges))
+	seen := make(map[string]bool)
+	for _, e := range d.Exchanges {
+		candidates := []string{e.Exante}
+		if len(e.Regex) > 0 && regexGroup.MatchString(e.Exante) {
+			candidates = []string{}
+		}
+		if e.Rollover != nil {
+			for _, c := range e.Rollover.Contracts {
+				candidates = append(candidates, c.Exante)
+			}
+		}
+
+		for _, symbol := range candidates {
+			symbol = strings.TrimSpace(symbol)
+			if len(symbol) == 0 || symbol == Placeholder || seen[symbol] {
+				continue
+			}
+			seen[symbol] = true
+			symbols = append(symbols, symbol)
+		}
+	}
+	return symbols
+}
+
+// regexGroup match `$1` and `${name}` on exante of a regex exchange
+var regexGroup = regexp.MustCompile(`\$(\d+|\{\w+\}|\w+)`)
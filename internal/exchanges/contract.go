@@ -0,0 +1,116 @@
+package exchanges
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+)
+
+// OrderTypes exante accept
+var OrderTypes = []string{"market", "limit", "stop", "stop_limit"}
+
+// Contract is the specification of a symbol between MT5 and exante,
+// zero values keep MT5 volumes and prices as they are
+type Contract struct {
+	// ContractSize is the exante quantity of one MT5 lot, PriceStep when empty
+	ContractSize float64 `yaml:"contractSize"`
+	// PriceMultiplier and PriceOffset convert MT5 quotes to exante:
+	// exante = mt5 * multiplier + offset
+	PriceMultiplier float64 `yaml:"priceMultiplier"`
+	PriceOffset     float64 `yaml:"priceOffset"`
+	// TickSize round the exante price to the nearest tick
+	TickSize float64 `yaml:"tickSize"`
+	// QuantityStep round down the quantity before the instrument lot size
+	QuantityStep float64 `yaml:"quantityStep"`
+	MinQuantity  float64 `yaml:"minQuantity"`
+	MaxQuantity  float64 `yaml:"maxQuantity"`
+	// OrderTypes allowed for the symbol, every type when empty
+	OrderTypes []string `yaml:"orderTypes"`
+	// Enabled false stop new orders of the symbol, open ones
+	// are still closed and their SL/TP updated
+	Enabled *bool `yaml:"enabled"`
+}
+
+func (e DataExchanges) IsEnabled() bool {
+	return e.Enabled == nil || *e.Enabled
+}
+
+// LotSize is the exante quantity of one MT5 lot
+func (e DataExchanges) LotSize() float64 {
+	if e.ContractSize > 0 {
+		return e.ContractSize
+	}
+	return e.PriceStep
+}
+
+// Quantity convert a MT5 volume to the exante quantity, rounded
+// down to QuantityStep and checked against min/max quantity
+func (e DataExchanges) Quantity(volume float64) (float64, error) {
+	quantity := volume * e.LotSize()
+	if e.QuantityStep > 0 {
+		// the epsilon keeps 0.3/0.1 from rounding down to 2
+		quantity = math.Floor(quantity/e.QuantityStep+1e-9) * e.QuantityStep
+	}
+
+	if quantity <= 0 {
+		return 0, fmt.Errorf("quantity of %v lots is zero for %s", volume, e.Exante)
+	}
+	if e.MinQuantity > 0 && quantity < e.MinQuantity {
+		return 0, fmt.Errorf("quantity %v is below the minimum %v for %s", quantity, e.MinQuantity, e.Exante)
+	}
+	if e.MaxQuantity > 0 && quantity > e.MaxQuantity {
+		return 0, fmt.Errorf("quantity %v is above the maximum %v for %s", quantity, e.MaxQuantity, e.Exante)
+	}
+	return quantity, nil
+}
+
+// Price convert a MT5 quote to exante, rounded to TickSize. Zero stays
+// zero since it means no price
+func (e DataExchanges) Price(price float64) float64 {
+	if price == 0 {
+		return 0
+	}
+	multiplier := e.PriceMultiplier
+	if multiplier == 0 {
+		multiplier = 1
+	}
+	price = price*multiplier + e.PriceOffset
+	if e.TickSize > 0 {
+		// the second round drops the float noise of the multiplication, 1.1200000000000001
+		price = math.Round(math.Round(price/e.TickSize)*e.TickSize*1e9) / 1e9
+	}
+	return price
+}
+
+func (e DataExchanges) AllowOrderType(orderType string) bool {
+	return len(e.OrderTypes) == 0 || slices.Contains(e.OrderTypes, orderType)
+}
+
+func (c Contract) validate() error {
+	errs := make([]error, 0)
+	for _, field := range []struct {
+		name  string
+		value float64
+	}{
+		{"contractSize", c.ContractSize},
+		{"priceMultiplier", c.PriceMultiplier},
+		{"tickSize", c.TickSize},
+		{"quantityStep", c.QuantityStep},
+		{"minQuantity", c.MinQuantity},
+		{"maxQuantity", c.MaxQuantity},
+	} {
+		if field.value < 0 {
+			errs = append(errs, fmt.Errorf("%s must not be negative", field.name))
+		}
+	}
+	if c.MaxQuantity > 0 && c.MinQuantity > c.MaxQuantity {
+		errs = append(errs, fmt.Errorf("minQuantity is greater than maxQuantity"))
+	}
+	for _, orderType := range c.OrderTypes {
+		if !slices.Contains(OrderTypes, orderType) {
+			errs = append(errs, fmt.Errorf("unknown order type %s", orderType))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,278 @@
+package exchanges
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/danielsussa/mt5-to-exante/internal/exante"
+)
+
+// Placeholder is written where the operator must fill a value
+const Placeholder = "INSERT_VALUE"
+
+// symbolTypes preferred when a MT5 symbol match more than one exante symbol
+var symbolTypes = []string{"CURRENCY", "STOCK", "FUND", "CFD", "FUTURE", "BOND", "OPTION"}
+
+// Mt5Symbol is a symbol of the MT5 Market Watch, ContractSize is zero when unknown
+type Mt5Symbol struct {
+	Name         string
+	ContractSize float64
+}
+
+// DraftEntry is the exante symbol suggested for a MT5 symbol, Symbol
+// is nil when nothing matched
+type DraftEntry struct {
+	Mt5          Mt5Symbol
+	Symbol       *exante.SymbolV3
+	Instrument   *exante.Instrument
+	Alternatives []string
+}
+
+// ParseMt5Symbols read one symbol per line or a Market Watch export: a
+// csv (comma, semicolon or tab) with a `Symbol` column and optionally
+// `Contract Size`. Empty lines and lines starting with # are skipped.
+func ParseMt5Symbols(r io.Reader) ([]Mt5Symbol, error) {
+	lines := make([]string, 0)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return []Mt5Symbol{}, nil
+	}
+
+	separator := rune(0)
+	for _, sep := range []rune{'\t', ';', ','} {
+		if strings.ContainsRune(lines[0], sep) {
+			separator = sep
+			break
+		}
+	}
+	if separator == 0 {
+		symbols := make([]Mt5Symbol, 0, len(lines))
+		for _, line := range lines {
+			symbols = append(symbols, Mt5Symbol{Name: line})
+		}
+		return symbols, nil
+	}
+
+	reader := csv.NewReader(strings.NewReader(strings.Join(lines, "\n")))
+	reader.Comma = separator
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read symbols: %s", err.Error())
+	}
+
+	nameCol, sizeCol := -1, -1
+	for idx, header := range records[0] {
+		switch strings.ToLower(strings.ReplaceAll(strings.TrimSpace(header), " ", "")) {
+		case "symbol", "name":
+			nameCol = idx
+		case "contractsize":
+			sizeCol = idx
+		}
+	}
+	if nameCol == -1 {
+		return nil, fmt.Errorf("cannot read symbols: missing Symbol column")
+	}
+
+	symbols := make([]Mt5Symbol, 0, len(records)-1)
+	for _, record := range records[1:] {
+		if nameCol >= len(record) || len(strings.TrimSpace(record[nameCol])) == 0 {
+			continue
+		}
+		symbol := Mt5Symbol{Name: strings.TrimSpace(record[nameCol])}
+		if sizeCol > -1 && sizeCol < len(record) {
+			symbol.ContractSize, _ = strconv.ParseFloat(strings.TrimSpace(record[sizeCol]), 64)
+		}
+		symbols = append(symbols, symbol)
+	}
+	return symbols, nil
+}
+
+// Suggest match each MT5 symbol with the exante symbols by ticker or
+// by the symbol id before the exchange, forex pairs `EURUSD` match
+// `EUR/USD`. suffixes are stripped from MT5 symbols first.
+func Suggest(mt5 []Mt5Symbol, candidates []exante.SymbolV3, suffixes []string) []DraftEntry {
+	byKey := make(map[string][]int)
+	for idx, c := range candidates {
+		keys := []string{strings.ToUpper(c.Ticker), strings.ToUpper(strings.Split(c.SymbolID, ".")[0])}
+		for _, key := range slices.Compact(keys) {
+			if len(key) > 0 {
+				byKey[key] = append(byKey[key], idx)
+			}
+		}
+	}
+
+	stripper := newIndex(Data{Suffixes: suffixes})
+	entries := make([]DraftEntry, 0, len(mt5))
+	seen := make(map[string]bool)
+	for _, symbol := range mt5 {
+		if seen[symbol.Name] {
+			continue
+		}
+		seen[symbol.Name] = true
+		entry := DraftEntry{Mt5: symbol, Alternatives: []string{}}
+
+		matches := make([]int, 0)
+		for _, key := range draftKeys(symbol.Name, stripper) {
+			for _, idx := range byKey[key] {
+				if !slices.Contains(matches, idx) {
+					matches = append(matches, idx)
+				}
+			}
+		}
+		sort.SliceStable(matches, func(i, j int) bool {
+			return preferSymbol(candidates[matches[i]], candidates[matches[j]])
+		})
+
+		if len(matches) > 0 {
+			entry.Symbol = &candidates[matches[0]]
+			for _, idx := range matches[1:min(len(matches), 4)] {
+				entry.Alternatives = append(entry.Alternatives, candidates[idx].SymbolID)
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// draftKeys return the names a MT5 symbol may have on exante
+func draftKeys(name string, stripper *index) []string {
+	names := []string{strings.ToUpper(name)}
+	if stripped, ok := stripper.strip(name); ok {
+		names = append(names, strings.ToUpper(stripped))
+	}
+	name = names[0]
+	if idx := strings.IndexAny(name, ".-_"); idx > 0 {
+		names = append(names, name[:idx])
+	}
+
+	keys := make([]string, 0)
+	for _, n := range names {
+		keys = append(keys, n)
+		if len(n) == 6 && isLetters(n) {
+			keys = append(keys, n[:3]+"/"+n[3:])
+		}
+	}
+	return keys
+}
+
+func isLetters(s string) bool {
+	for _, r := range s {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// preferSymbol order matches: without expiration, by symbolTypes,
+// then the shortest id
+func preferSymbol(a exante.SymbolV3, b exante.SymbolV3) bool {
+	if (a.Expiration == 0) != (b.Expiration == 0) {
+		return a.Expiration == 0
+	}
+	rank := func(s exante.SymbolV3) int {
+		if idx := slices.Index(symbolTypes, strings.ToUpper(s.SymbolType)); idx > -1 {
+			return idx
+		}
+		return len(symbolTypes)
+	}
+	if rank(a) != rank(b) {
+		return rank(a) < rank(b)
+	}
+	if len(a.SymbolID) != len(b.SymbolID) {
+		return len(a.SymbolID) < len(b.SymbolID)
+	}
+	return a.SymbolID < b.SymbolID
+}
+
+// WriteDraft write entries as an exchanges.yaml to review, symbols
+// without a match get Placeholder and are disabled
+func WriteDraft(w io.Writer, entries []DraftEntry, suffixes []string) error {
+	b := new(strings.Builder)
+	fmt.Fprintf(b, "description: %s\n", strconv.Quote(fmt.Sprintf("Draft generated from Exante symbols on %s, review every entry before use", time.Now().Format(time.DateOnly))))
+	if len(suffixes) > 0 {
+		quoted := make([]string, 0, len(suffixes))
+		for _, suffix := range suffixes {
+			quoted = append(quoted, strconv.Quote(suffix))
+		}
+		fmt.Fprintf(b, "suffixes: [%s]\n", strings.Join(quoted, ", "))
+	}
+	b.WriteString("exchanges:\n")
+
+	for _, entry := range entries {
+		if entry.Symbol == nil {
+			b.WriteString("  # no exante symbol found\n")
+			fmt.Fprintf(b, "  - metaTrader: %s\n", strconv.Quote(entry.Mt5.Name))
+			fmt.Fprintf(b, "    exante: %s\n", strconv.Quote(Placeholder))
+			b.WriteString("    contractSize: 1\n")
+			b.WriteString("    enabled: false\n")
+			continue
+		}
+
+		comment := fmt.Sprintf("%s (%s)", entry.Symbol.Description, entry.Symbol.SymbolType)
+		if entry.Instrument != nil {
+			comment += fmt.Sprintf(", lot %s", entry.Instrument.LotSize)
+		}
+		if len(entry.Alternatives) > 0 {
+			comment += ", also: " + strings.Join(entry.Alternatives, ", ")
+		}
+		fmt.Fprintf(b, "  # %s\n", comment)
+		fmt.Fprintf(b, "  - metaTrader: %s\n", strconv.Quote(entry.Mt5.Name))
+		fmt.Fprintf(b, "    exante: %s\n", strconv.Quote(entry.Symbol.SymbolID))
+
+		contractSize, known := draftContractSize(entry)
+		if known {
+			fmt.Fprintf(b, "    contractSize: %s\n", formatFloat(contractSize))
+		} else {
+			fmt.Fprintf(b, "    contractSize: %s # MT5 contract size unknown\n", formatFloat(contractSize))
+		}
+		if entry.Instrument != nil {
+			if tick, err := strconv.ParseFloat(entry.Instrument.TickSize, 64); err == nil && tick > 0 {
+				fmt.Fprintf(b, "    tickSize: %s\n", formatFloat(tick))
+			}
+			if lot, err := strconv.ParseFloat(entry.Instrument.LotSize, 64); err == nil && lot > 0 {
+				fmt.Fprintf(b, "    quantityStep: %s\n", formatFloat(lot))
+				fmt.Fprintf(b, "    minQuantity: %s\n", formatFloat(lot))
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// draftContractSize is the exante quantity of one MT5 lot: the MT5
+// contract size in exante contracts
+func draftContractSize(entry DraftEntry) (float64, bool) {
+	if entry.Mt5.ContractSize <= 0 {
+		return 1, false
+	}
+	if entry.Instrument != nil {
+		if multiplier, err := strconv.ParseFloat(entry.Instrument.ContractMultiplier, 64); err == nil && multiplier > 0 {
+			return entry.Mt5.ContractSize / multiplier, true
+		}
+	}
+	return entry.Mt5.ContractSize, true
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
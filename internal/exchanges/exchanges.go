@@ -1,47 +1,248 @@
 package exchanges
 
 import (
+	"errors"
 	"fmt"
 	"github.com/goccy/go-yaml"
 	"os"
+	"path"
+	"reflect"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
 )
 
 type Api struct {
-	Data Data
+	mu    sync.RWMutex
+	path  string
+	index *index
+	Data  Data
 }
 
 type Data struct {
-	Description string          `yaml:"description"`
-	Exchanges   []DataExchanges `json:"exchanges"`
+	Description string `yaml:"description"`
+	// Suffixes the broker add to MT5 symbols, `EURUSD.m`
+	Suffixes  []string        `yaml:"suffixes"`
+	Exchanges []DataExchanges `json:"exchanges"`
 }
 
+// DataExchanges map MT5 symbols to an exante symbol, matching one of
+// MetaTrader, Pattern (wildcards, `US500*`) or Regex. Exante of a Regex
+// may use its groups, `$1/USD.E.FX`. With a Rollover, Exante is the
+// current contract of its calendar.
 type DataExchanges struct {
-	Exante     string  `yaml:"exante"`
-	MetaTrader string  `yaml:"metaTrader"`
-	PriceStep  float64 `yaml:"priceStep"`
+	Exante     string    `yaml:"exante"`
+	MetaTrader string    `yaml:"metaTrader"`
+	Pattern    string    `yaml:"pattern"`
+	Regex      string    `yaml:"regex"`
+	Aliases    []string  `yaml:"aliases"`
+	PriceStep  float64   `yaml:"priceStep"`
+	Rollover   *Rollover `yaml:"rollover"`
+	Contract   `yaml:",inline"`
+}
+
+// key identify the exchange on the file
+func (e DataExchanges) key() string {
+	switch {
+	case len(e.Pattern) > 0:
+		return e.Pattern
+	case len(e.Regex) > 0:
+		return e.Regex
+	}
+	return e.MetaTrader
+}
+
+// Change is the difference between two mappings, by MT5 symbol or pattern
+type Change struct {
+	Added   []string
+	Removed []string
+	Updated []string
+}
+
+func (c Change) IsEmpty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0 && len(c.Updated) == 0
 }
 
 func New(path string) (*Api, error) {
+	d, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Api{path: path, index: newIndex(d), Data: d}, nil
+}
+
+// Load read and validate the mapping file
+func Load(path string) (Data, error) {
 	dat, err := os.ReadFile(path)
 	if err != nil {
-		return nil, fmt.Errorf("cannot find exchange file")
+		return Data{}, fmt.Errorf("cannot find exchange file")
 	}
 
 	var d Data
 	err = yaml.Unmarshal(dat, &d)
 	if err != nil {
-		return nil, fmt.Errorf("error to convert exchange file: %s", err.Error())
+		return Data{}, fmt.Errorf("error to convert exchange file: %s", err.Error())
 	}
 
-	return &Api{Data: d}, err
+	return d, d.Validate()
 }
 
-func (a Api) GetByMTValue(mtval string) (DataExchanges, bool) {
-	for _, d := range a.Data.Exchanges {
-		if d.MetaTrader == mtval {
-			return d, true
+// Validate return every problem of the mapping: missing fields, enabled
+// placeholders, zero priceStep, invalid regex and MT5 symbols or patterns
+// mapped twice
+func (d Data) Validate() error {
+	errs := make([]error, 0)
+	seen := make(map[string]bool)
+	for idx, e := range d.Exchanges {
+		matchers := 0
+		for _, m := range []string{e.MetaTrader, e.Pattern, e.Regex} {
+			if len(m) > 0 {
+				matchers++
+			}
+		}
+		switch {
+		case matchers == 0:
+			errs = append(errs, fmt.Errorf("exchange %d: missing metaTrader, pattern or regex", idx+1))
+		case matchers > 1:
+			errs = append(errs, fmt.Errorf("exchange %d (%s): only one of metaTrader, pattern or regex is allowed", idx+1, e.key()))
+		}
+		if len(e.Exante) == 0 && e.Rollover == nil {
+			errs = append(errs, fmt.Errorf("exchange %d (%s): missing exante", idx+1, e.key()))
+		}
+		if len(e.Exante) > 0 && e.Rollover != nil {
+			errs = append(errs, fmt.Errorf("exchange %d (%s): exante is the current rollover contract, remove it", idx+1, e.key()))
+		}
+		if e.Rollover != nil {
+			if err := e.Rollover.validate(); err != nil {
+				errs = append(errs, fmt.Errorf("exchange %d (%s): %w", idx+1, e.key(), err))
+			}
+		}
+		if e.Exante == Placeholder && e.IsEnabled() {
+			errs = append(errs, fmt.Errorf("exchange %d (%s): exante is %s, set it or disable the exchange", idx+1, e.key(), Placeholder))
+		}
+		if e.LotSize() <= 0 {
+			errs = append(errs, fmt.Errorf("exchange %d (%s): priceStep must be greater than zero", idx+1, e.key()))
+		}
+		if err := e.Contract.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("exchange %d (%s): %w", idx+1, e.key(), err))
+		}
+		if len(e.Pattern) > 0 {
+			if _, err := path.Match(e.Pattern, ""); err != nil {
+				errs = append(errs, fmt.Errorf("exchange %d (%s): invalid pattern: %s", idx+1, e.key(), err.Error()))
+			}
+		}
+		if len(e.Regex) > 0 {
+			if _, err := regexp.Compile(e.Regex); err != nil {
+				errs = append(errs, fmt.Errorf("exchange %d (%s): invalid regex: %s", idx+1, e.key(), err.Error()))
+			}
+		}
+
+		for _, symbol := range append([]string{e.key()}, e.Aliases...) {
+			if len(symbol) == 0 {
+				continue
+			}
+			if seen[symbol] {
+				errs = append(errs, fmt.Errorf("exchange %d (%s): duplicated metaTrader symbol %s", idx+1, e.key(), symbol))
+			}
+			seen[symbol] = true
 		}
 	}
+	return errors.Join(errs...)
+}
 
-	return DataExchanges{}, false
+// Reload read the mapping file again and swap it, the current
+// mapping is kept when the file is invalid
+func (a *Api) Reload() (Change, error) {
+	d, err := Load(a.path)
+	if err != nil {
+		return Change{}, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	change := diff(a.Data, d)
+	if a.index != nil {
+		a.index.reset()
+	}
+	a.Data = d
+	a.index = newIndex(d)
+	return change, nil
+}
+
+// Watch reload the mapping each time the file changes, checking it every
+// interval, until stop is called. onReload receive the result of each reload.
+func (a *Api) Watch(interval time.Duration, onReload func(Change, error)) (stop func()) {
+	done := make(chan struct{})
+	last, _ := os.Stat(a.path)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			info, err := os.Stat(a.path)
+			if err != nil || (last != nil && info.ModTime().Equal(last.ModTime()) && info.Size() == last.Size()) {
+				continue
+			}
+			last = info
+			onReload(a.Reload())
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+// GetByMTValue return the exchange of a MT5 symbol, see index for the
+// precedence, on its current contract when it has a rollover
+func (a *Api) GetByMTValue(mtval string) (DataExchanges, bool) {
+	e, has := a.lookup().get(mtval)
+	return e.At(time.Now()), has
+}
+
+// lookup return the index of the current mapping, built on first use
+// when Api was not created by New
+func (a *Api) lookup() *index {
+	a.mu.RLock()
+	idx := a.index
+	a.mu.RUnlock()
+	if idx != nil {
+		return idx
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.index == nil {
+		a.index = newIndex(a.Data)
+	}
+	return a.index
+}
+
+func diff(old Data, new Data) Change {
+	change := Change{Added: []string{}, Removed: []string{}, Updated: []string{}}
+	for _, e := range new.Exchanges {
+		idx := slices.IndexFunc(old.Exchanges, func(o DataExchanges) bool { return o.key() == e.key() })
+		switch {
+		case idx == -1:
+			change.Added = append(change.Added, e.key())
+		case !reflect.DeepEqual(old.Exchanges[idx], e):
+			change.Updated = append(change.Updated, e.key())
+		}
+	}
+	for _, o := range old.Exchanges {
+		if !slices.ContainsFunc(new.Exchanges, func(e DataExchanges) bool { return e.key() == o.key() }) {
+			change.Removed = append(change.Removed, o.key())
+		}
+	}
+	if !slices.Equal(old.Suffixes, new.Suffixes) {
+		change.Updated = append(change.Updated, "suffixes")
+	}
+	return change
 }
@@ -0,0 +1,223 @@
+package exchanges
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/danielsussa/mt5-to-exante/internal/exante"
+	"github.com/stretchr/testify/assert"
+)
+
+const validFile = `
+exchanges:
+  - metaTrader: "EURUSD"
+    exante: "EUR/USD.E.FX"
+    priceStep: 10000
+`
+
+func TestExchanges(t *testing.T) {
+	t.Run("invalid file should keep the current mapping", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "exchanges.yaml")
+		assert.NoError(t, os.WriteFile(path, []byte(validFile), 0644))
+		api, err := New(path)
+		assert.NoError(t, err)
+
+		assert.NoError(t, os.WriteFile(path, []byte(`
+exchanges:
+  - metaTrader: "EURUSD"
+    exante: "EUR/USD.E.FX"
+    priceStep: 10000
+  - metaTrader: "EURUSD"
+    exante: "EUR/USD.E.FX"
+    priceStep: 0
+  - metaTrader: "BTCUSD"
+`), 0644))
+		_, err = api.Reload()
+		assert.ErrorContains(t, err, "duplicated metaTrader")
+		assert.ErrorContains(t, err, "priceStep must be greater than zero")
+		assert.ErrorContains(t, err, "missing exante")
+
+		exchange, has := api.GetByMTValue("EURUSD")
+		assert.True(t, has)
+		assert.Equal(t, "EUR/USD.E.FX", exchange.Exante)
+	})
+
+	t.Run("watch should swap the mapping when the file changes", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "exchanges.yaml")
+		assert.NoError(t, os.WriteFile(path, []byte(validFile), 0644))
+		api, err := New(path)
+		assert.NoError(t, err)
+
+		changes := make(chan Change, 1)
+		stop := api.Watch(10*time.Millisecond, func(change Change, err error) {
+			assert.NoError(t, err)
+			changes <- change
+		})
+		defer stop()
+
+		assert.NoError(t, os.WriteFile(path, []byte(validFile+`
+  - metaTrader: "BTCUSD"
+    exante: "BTC.USD"
+    priceStep: 1
+`), 0644))
+
+		select {
+		case change := <-changes:
+			assert.Equal(t, []string{"BTCUSD"}, change.Added)
+		case <-time.After(time.Second):
+			t.Fatal("file change not reloaded")
+		}
+		_, has := api.GetByMTValue("BTCUSD")
+		assert.True(t, has)
+	})
+
+	t.Run("reload should not keep the symbols resolved on the previous mapping", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "exchanges.yaml")
+		assert.NoError(t, os.WriteFile(path, []byte(`
+exchanges:
+  - regex: "^([A-Z]{3})USD$"
+    exante: "$1/USD.E.FX"
+    priceStep: 10000
+`), 0644))
+		api, err := New(path)
+		assert.NoError(t, err)
+
+		exchange, has := api.GetByMTValue("GBPUSD")
+		assert.True(t, has)
+		assert.Equal(t, "GBP/USD.E.FX", exchange.Exante)
+		_, has = api.GetByMTValue("US500.cash")
+		assert.False(t, has)
+		previous := api.lookup()
+
+		assert.NoError(t, os.WriteFile(path, []byte(`
+exchanges:
+  - pattern: "GBP*"
+    exante: "GBP/USD.PRO"
+    priceStep: 10000
+  - pattern: "US500*"
+    exante: "SPX.INDEX"
+    priceStep: 1
+`), 0644))
+		_, err = api.Reload()
+		assert.NoError(t, err)
+
+		exchange, has = api.GetByMTValue("GBPUSD")
+		assert.True(t, has)
+		assert.Equal(t, "GBP/USD.PRO", exchange.Exante)
+		exchange, has = api.GetByMTValue("US500.cash")
+		assert.True(t, has)
+		assert.Equal(t, "SPX.INDEX", exchange.Exante)
+
+		_, has = previous.resolved.Load("GBPUSD")
+		assert.False(t, has, "previous lookups are cleared")
+	})
+
+	t.Run("symbols should resolve by precedence", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "exchanges.yaml")
+		assert.NoError(t, os.WriteFile(path, []byte(`
+suffixes: [".m", "pro"]
+exchanges:
+  - metaTrader: "EURUSD"
+    aliases: ["EURUSD.ecn"]
+    exante: "EUR/USD.E.FX"
+    priceStep: 10000
+  - metaTrader: "EURUSDpro"
+    exante: "EUR/USD.PRO"
+    priceStep: 10000
+  - pattern: "US500*"
+    exante: "SPX.INDEX"
+    priceStep: 1
+  - regex: "^([A-Z]{3})USD$"
+    exante: "$1/USD.E.FX"
+    priceStep: 10000
+`), 0644))
+		api, err := New(path)
+		assert.NoError(t, err)
+
+		for symbol, exante := range map[string]string{
+			"EURUSD":     "EUR/USD.E.FX",
+			"EURUSD.ecn": "EUR/USD.E.FX",
+			"EURUSD.m":   "EUR/USD.E.FX",
+			"EURUSDpro":  "EUR/USD.PRO",
+			"US500.cash": "SPX.INDEX",
+			"GBPUSD":     "GBP/USD.E.FX",
+			"GBPUSD.m":   "GBP/USD.E.FX",
+		} {
+			exchange, has := api.GetByMTValue(symbol)
+			assert.True(t, has, symbol)
+			assert.Equal(t, exante, exchange.Exante, symbol)
+		}
+
+		_, has := api.GetByMTValue("BTCEUR")
+		assert.False(t, has)
+	})
+
+	t.Run("draft should suggest exante symbols and load back", func(t *testing.T) {
+		mt5, err := ParseMt5Symbols(strings.NewReader("Symbol;Description;Contract Size\nEURUSD.m;Euro;100000\nAAPL.m;Apple;1\nXYZ.m;Unknown;1\nEURUSD.m;Euro;100000\n"))
+		assert.NoError(t, err)
+		assert.Len(t, mt5, 4)
+
+		entries := Suggest(mt5, []exante.SymbolV3{
+			{SymbolID: "EUR/USD.E.FX", Ticker: "EUR/USD", SymbolType: "CURRENCY"},
+			{SymbolID: "EUR/USD.SPOT.FX", Ticker: "EUR/USD", SymbolType: "CURRENCY"},
+			{SymbolID: "AAPL.NASDAQ", Ticker: "AAPL", SymbolType: "STOCK"},
+			{SymbolID: "AAPL.CBOE.Z2024", Ticker: "AAPL", SymbolType: "FUTURE", Expiration: 1},
+		}, []string{".m"})
+		assert.Len(t, entries, 3)
+		assert.Equal(t, "EUR/USD.E.FX", entries[0].Symbol.SymbolID)
+		assert.Equal(t, []string{"EUR/USD.SPOT.FX"}, entries[0].Alternatives)
+		assert.Equal(t, "AAPL.NASDAQ", entries[1].Symbol.SymbolID)
+		assert.Nil(t, entries[2].Symbol)
+
+		entries[0].Instrument = &exante.Instrument{TickSize: "0.00001", LotSize: "1", ContractMultiplier: "1"}
+
+		path := filepath.Join(t.TempDir(), "exchanges.yaml")
+		f, err := os.Create(path)
+		assert.NoError(t, err)
+		assert.NoError(t, WriteDraft(f, entries, []string{".m"}))
+		assert.NoError(t, f.Close())
+
+		api, err := New(path)
+		assert.NoError(t, err)
+		exchange, has := api.GetByMTValue("EURUSD.m")
+		assert.True(t, has)
+		assert.Equal(t, "EUR/USD.E.FX", exchange.Exante)
+		assert.Equal(t, float64(100000), exchange.LotSize())
+		assert.Equal(t, float64(1), exchange.QuantityStep)
+		assert.Equal(t, 0.00001, exchange.TickSize)
+		assert.Equal(t, 1.12346, exchange.Price(1.123456))
+
+		unknown, _ := api.GetByMTValue("XYZ.m")
+		assert.Equal(t, Placeholder, unknown.Exante)
+		assert.False(t, unknown.IsEnabled())
+	})
+
+	t.Run("warnings should report shadowed and suffixed symbols", func(t *testing.T) {
+		d := Data{
+			Suffixes: []string{".m"},
+			Exchanges: []DataExchanges{
+				{MetaTrader: "EURUSD", Exante: "EUR/USD.E.FX", PriceStep: 10000},
+				{MetaTrader: "EURUSD.m", Exante: "EUR/USD.SPOT.FX", PriceStep: 10000},
+				{MetaTrader: "US500", Exante: "SPY.ARCA", PriceStep: 1},
+				{Pattern: "US500*", Exante: "SPX.INDEX", PriceStep: 1},
+				{Regex: "^([A-Z]{3})JPY$", Exante: "$1/JPY.E.FX", PriceStep: 100},
+				{MetaTrader: "XYZ", Exante: Placeholder, PriceStep: 1, Contract: Contract{Enabled: new(bool)}},
+			},
+		}
+		assert.NoError(t, d.Validate())
+
+		warnings := d.Warnings(time.Now())
+		assert.Len(t, warnings, 3)
+		assert.Contains(t, warnings[0], "EURUSD.m is EURUSD without suffix")
+		assert.Contains(t, warnings[1], "US500 is also matched by US500*")
+		assert.Contains(t, warnings[2], "exante is INSERT_VALUE")
+
+		assert.Equal(t, []string{"EUR/USD.E.FX", "EUR/USD.SPOT.FX", "SPY.ARCA", "SPX.INDEX"}, d.ExanteSymbols())
+
+		d.Exchanges[5].Enabled = nil
+		assert.ErrorContains(t, d.Validate(), "exante is INSERT_VALUE")
+	})
+}
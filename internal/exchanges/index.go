@@ -0,0 +1,152 @@
+package exchanges
+
+import (
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// index resolve MT5 symbols to their exchange, by precedence:
+//
+//  1. metaTrader, exact
+//  2. aliases, exact
+//  3. metaTrader and aliases after stripping the longest suffix
+//  4. wildcard patterns, in file order
+//  5. regex patterns, in file order
+//
+// patterns are tried on the symbol as sent by MT5 first, then stripped
+type index struct {
+	exact    map[string]int
+	aliases  map[string]int
+	suffixes []string
+	patterns []int
+	regexes  []compiledRegex
+	data     []DataExchanges
+
+	// resolved keep the pattern lookups already done
+	resolved sync.Map
+}
+
+type compiledRegex struct {
+	idx int
+	re  *regexp.Regexp
+}
+
+type resolution struct {
+	exchange DataExchanges
+	has      bool
+}
+
+func newIndex(d Data) *index {
+	i := &index{
+		exact:    make(map[string]int),
+		aliases:  make(map[string]int),
+		suffixes: append([]string{}, d.Suffixes...),
+		data:     d.Exchanges,
+	}
+	// longest first, so `.pro` is stripped before `o`
+	sort.SliceStable(i.suffixes, func(a, b int) bool { return len(i.suffixes[a]) > len(i.suffixes[b]) })
+
+	for idx, e := range d.Exchanges {
+		switch {
+		case len(e.MetaTrader) > 0:
+			if _, has := i.exact[e.MetaTrader]; !has {
+				i.exact[e.MetaTrader] = idx
+			}
+		case len(e.Pattern) > 0:
+			i.patterns = append(i.patterns, idx)
+		case len(e.Regex) > 0:
+			re, err := regexp.Compile(e.Regex)
+			if err != nil {
+				// rejected by Validate, skipped for mappings built by hand
+				continue
+			}
+			i.regexes = append(i.regexes, compiledRegex{idx: idx, re: re})
+		}
+		for _, alias := range e.Aliases {
+			if _, has := i.aliases[alias]; !has {
+				i.aliases[alias] = idx
+			}
+		}
+	}
+	return i
+}
+
+func (i *index) get(symbol string) (DataExchanges, bool) {
+	if idx, has := i.exact[symbol]; has {
+		return i.data[idx], true
+	}
+	if idx, has := i.aliases[symbol]; has {
+		return i.data[idx], true
+	}
+
+	if r, has := i.resolved.Load(symbol); has {
+		return r.(resolution).exchange, r.(resolution).has
+	}
+	e, has := i.resolve(symbol)
+	i.resolved.Store(symbol, resolution{exchange: e, has: has})
+	return e, has
+}
+
+// reset forget the pattern lookups, the mapping they were resolved on
+// is being replaced
+func (i *index) reset() {
+	i.resolved.Range(func(key, _ any) bool {
+		i.resolved.Delete(key)
+		return true
+	})
+}
+
+func (i *index) resolve(symbol string) (DataExchanges, bool) {
+	stripped, isStripped := i.strip(symbol)
+	if isStripped {
+		if idx, has := i.exact[stripped]; has {
+			return i.data[idx], true
+		}
+		if idx, has := i.aliases[stripped]; has {
+			return i.data[idx], true
+		}
+	}
+
+	candidates := []string{symbol}
+	if isStripped {
+		candidates = append(candidates, stripped)
+	}
+
+	for _, candidate := range candidates {
+		for _, idx := range i.patterns {
+			if ok, _ := path.Match(i.data[idx].Pattern, candidate); ok {
+				e := i.data[idx]
+				e.MetaTrader = symbol
+				return e, true
+			}
+		}
+	}
+
+	for _, candidate := range candidates {
+		for _, r := range i.regexes {
+			match := r.re.FindStringSubmatchIndex(candidate)
+			if match == nil {
+				continue
+			}
+			e := i.data[r.idx]
+			e.MetaTrader = symbol
+			// exante may reference groups of the regex, `$1/USD.E.FX`
+			e.Exante = string(r.re.ExpandString(nil, e.Exante, candidate, match))
+			return e, true
+		}
+	}
+
+	return DataExchanges{}, false
+}
+
+func (i *index) strip(symbol string) (string, bool) {
+	for _, suffix := range i.suffixes {
+		if len(suffix) > 0 && len(symbol) > len(suffix) && strings.HasSuffix(symbol, suffix) {
+			return strings.TrimSuffix(symbol, suffix), true
+		}
+	}
+	return symbol, false
+}
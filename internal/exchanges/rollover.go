@@ -0,0 +1,119 @@
+package exchanges
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Rollover is the expiry calendar of a futures exchange, new orders go to
+// the first contract whose roll date, RollDays before its expiry, is not
+// reached yet
+type Rollover struct {
+	RollDays  int              `yaml:"rollDays"`
+	Contracts []FutureContract `yaml:"contracts"`
+}
+
+// FutureContract is an exante contract of the calendar, Expiry as 2006-01-02
+type FutureContract struct {
+	Exante string `yaml:"exante"`
+	Expiry string `yaml:"expiry"`
+}
+
+func (c FutureContract) expiresAt() (time.Time, error) {
+	return time.Parse(time.DateOnly, c.Expiry)
+}
+
+// RollAt is the day positions leave the contract
+func (r Rollover) RollAt(c FutureContract) (time.Time, error) {
+	expiry, err := c.expiresAt()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return expiry.AddDate(0, 0, -r.RollDays), nil
+}
+
+// Current return the contract for new orders at now, false when every
+// contract of the calendar reached its roll date
+func (r Rollover) Current(now time.Time) (FutureContract, bool) {
+	contracts := append([]FutureContract{}, r.Contracts...)
+	sort.SliceStable(contracts, func(i, j int) bool { return contracts[i].Expiry < contracts[j].Expiry })
+
+	for _, c := range contracts {
+		rollAt, err := r.RollAt(c)
+		if err != nil {
+			continue
+		}
+		if now.Before(rollAt) {
+			return c, true
+		}
+	}
+	return FutureContract{}, false
+}
+
+func (r Rollover) validate() error {
+	errs := make([]error, 0)
+	if r.RollDays < 0 {
+		errs = append(errs, fmt.Errorf("rollDays must not be negative"))
+	}
+	if len(r.Contracts) == 0 {
+		errs = append(errs, fmt.Errorf("rollover without contracts"))
+	}
+	seen := make(map[string]bool)
+	for _, c := range r.Contracts {
+		if len(c.Exante) == 0 {
+			errs = append(errs, fmt.Errorf("rollover contract missing exante"))
+		}
+		if _, err := c.expiresAt(); err != nil {
+			errs = append(errs, fmt.Errorf("rollover contract %s: invalid expiry %q, expected 2006-01-02", c.Exante, c.Expiry))
+		}
+		if seen[c.Exante] {
+			errs = append(errs, fmt.Errorf("rollover contract %s is duplicated", c.Exante))
+		}
+		seen[c.Exante] = true
+	}
+	return errors.Join(errs...)
+}
+
+// At return the exchange for orders placed at now, Exante is the current
+// contract of the rollover and empty when the calendar is over
+func (e DataExchanges) At(now time.Time) DataExchanges {
+	if e.Rollover == nil {
+		return e
+	}
+	e.Exante = ""
+	if c, has := e.Rollover.Current(now); has {
+		e.Exante = c.Exante
+	}
+	return e
+}
+
+// RollTo return the contract positions held on symbolID must move to at
+// now, false when symbolID is not a past contract of a rollover or the
+// calendar has no contract left
+func (a *Api) RollTo(symbolID string, now time.Time) (string, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	for _, e := range a.Data.Exchanges {
+		if e.Rollover == nil {
+			continue
+		}
+		for _, c := range e.Rollover.Contracts {
+			if c.Exante != symbolID {
+				continue
+			}
+			// contracts ahead of the current one are left alone
+			if rollAt, err := e.Rollover.RollAt(c); err != nil || now.Before(rollAt) {
+				return "", false
+			}
+			current, has := e.Rollover.Current(now)
+			if !has {
+				return "", false
+			}
+			return current.Exante, true
+		}
+	}
+	return "", false
+}
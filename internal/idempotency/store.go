@@ -0,0 +1,132 @@
+package idempotency
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+	"time"
+)
+
+// FileName of the store inside the SDK folder
+const FileName = ".idempotency.json"
+
+var (
+	// ErrInFlight is returned while the first request of a key runs
+	ErrInFlight = errors.New("a request with this idempotency key is in progress")
+	// ErrMismatch is returned when a key is sent again with another request
+	ErrMismatch = errors.New("idempotency key already used with another request")
+)
+
+// Entry is the response sent to the first request of a key
+type Entry struct {
+	Fingerprint string          `json:"fingerprint"`
+	Status      int             `json:"status"`
+	Body        json.RawMessage `json:"body"`
+	CreatedAt   time.Time       `json:"createdAt"`
+}
+
+// Store keep the responses of requests sent with an idempotency key for
+// TTL, a retry with the same key gets the first response instead of
+// running again. path is empty for a store that is not saved.
+type Store struct {
+	path    string
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]Entry
+	running map[string]string
+}
+
+func New(path string, ttl time.Duration) (*Store, error) {
+	s := NewNoDisk(ttl)
+	s.path = path
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(b, &s.entries); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func NewNoDisk(ttl time.Duration) *Store {
+	return &Store{ttl: ttl, entries: make(map[string]Entry), running: make(map[string]string)}
+}
+
+// Fingerprint identify a request by its method, path and body
+func Fingerprint(method string, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Begin reserve key for the request of fingerprint. The stored entry is
+// returned when the key already has a response, Finish or Release must
+// be called otherwise.
+func (s *Store) Begin(key string, fingerprint string, now time.Time) (Entry, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if entry, has := s.entries[key]; has && now.Sub(entry.CreatedAt) < s.ttl {
+		if entry.Fingerprint != fingerprint {
+			return Entry{}, false, ErrMismatch
+		}
+		return entry, true, nil
+	}
+	if running, has := s.running[key]; has {
+		if running != fingerprint {
+			return Entry{}, false, ErrMismatch
+		}
+		return Entry{}, false, ErrInFlight
+	}
+
+	s.running[key] = fingerprint
+	return Entry{}, false, nil
+}
+
+// Finish keep the response of key, expired entries are dropped
+func (s *Store) Finish(key string, status int, body []byte, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries[key] = Entry{Fingerprint: s.running[key], Status: status, Body: body, CreatedAt: now}
+	delete(s.running, key)
+	for k, entry := range s.entries {
+		if now.Sub(entry.CreatedAt) >= s.ttl {
+			delete(s.entries, k)
+		}
+	}
+	return s.save()
+}
+
+// Release free key without a response, so the request can be sent again
+func (s *Store) Release(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.running, key)
+}
+
+func (s *Store) save() error {
+	if len(s.path) == 0 {
+		return nil
+	}
+
+	b, err := json.Marshal(s.entries)
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err = os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
@@ -0,0 +1,63 @@
+package idempotency
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStore(t *testing.T) {
+	now := time.Now()
+	fp := Fingerprint("POST", "/v1/orders", []byte(`{"symbol":"EURUSD"}`))
+
+	t.Run("a finished key should replay its response and reject another request", func(t *testing.T) {
+		s := NewNoDisk(time.Hour)
+		_, has, err := s.Begin("k1", fp, now)
+		assert.NoError(t, err)
+		assert.False(t, has)
+
+		_, _, err = s.Begin("k1", fp, now)
+		assert.ErrorIs(t, err, ErrInFlight)
+
+		assert.NoError(t, s.Finish("k1", 201, []byte(`{"ticket":"1"}`), now))
+		entry, has, err := s.Begin("k1", fp, now.Add(time.Minute))
+		assert.NoError(t, err)
+		assert.True(t, has)
+		assert.Equal(t, 201, entry.Status)
+		assert.JSONEq(t, `{"ticket":"1"}`, string(entry.Body))
+
+		_, _, err = s.Begin("k1", Fingerprint("DELETE", "/v1/orders/1", nil), now)
+		assert.ErrorIs(t, err, ErrMismatch)
+	})
+
+	t.Run("a released or expired key should run again", func(t *testing.T) {
+		s := NewNoDisk(time.Hour)
+		_, _, _ = s.Begin("k1", fp, now)
+		s.Release("k1")
+		_, has, err := s.Begin("k1", fp, now)
+		assert.NoError(t, err)
+		assert.False(t, has)
+
+		assert.NoError(t, s.Finish("k1", 201, []byte(`{}`), now))
+		_, has, err = s.Begin("k1", fp, now.Add(2*time.Hour))
+		assert.NoError(t, err)
+		assert.False(t, has)
+	})
+
+	t.Run("responses should be loaded back from disk", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), FileName)
+		s, err := New(path, time.Hour)
+		assert.NoError(t, err)
+		_, _, _ = s.Begin("k1", fp, now)
+		assert.NoError(t, s.Finish("k1", 201, []byte(`{"ticket":"1"}`), now))
+
+		s, err = New(path, time.Hour)
+		assert.NoError(t, err)
+		entry, has, err := s.Begin("k1", fp, now)
+		assert.NoError(t, err)
+		assert.True(t, has)
+		assert.Equal(t, 201, entry.Status)
+	})
+}
@@ -0,0 +1,81 @@
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileName of the journal inside the SDK folder
+const FileName = "journal.log"
+
+type Entry struct {
+	Time time.Time `json:"time"`
+	Text string    `json:"text"`
+}
+
+// Journal keep what the bridge did as json lines, one entry per line of text
+type Journal struct {
+	path string
+	mu   sync.Mutex
+}
+
+func New(path string) *Journal {
+	return &Journal{path: path}
+}
+
+func (j *Journal) Add(text string) error {
+	if len(text) == 0 {
+		return nil
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	f, err := os.OpenFile(j.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	now := time.Now()
+	enc := json.NewEncoder(f)
+	for _, line := range strings.Split(text, "\n") {
+		if err := enc.Encode(Entry{Time: now, Text: line}); err != nil {
+			return err
+		}
+	}
+	return f.Sync()
+}
+
+// Recent return the last n entries, oldest first
+func (j *Journal) Recent(n int) ([]Entry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	entries := make([]Entry, 0)
+	f, err := os.Open(j.path)
+	if os.IsNotExist(err) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		entries = append(entries, e)
+		if len(entries) > n {
+			entries = entries[1:]
+		}
+	}
+	return entries, scanner.Err()
+}
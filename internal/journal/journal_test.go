@@ -0,0 +1,50 @@
+package journal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJournal(t *testing.T) {
+	t.Run("missing file should have no entries", func(t *testing.T) {
+		j := New(filepath.Join(t.TempDir(), FileName))
+		entries, err := j.Recent(10)
+		assert.NoError(t, err)
+		assert.Len(t, entries, 0)
+	})
+
+	t.Run("each line of text should be one entry", func(t *testing.T) {
+		j := New(filepath.Join(t.TempDir(), FileName))
+		assert.NoError(t, j.Add("ENTRY_IN > 1\nQUEUED"))
+		assert.NoError(t, j.Add("ENTRY_OUT > 1"))
+		assert.NoError(t, j.Add(""))
+
+		entries, err := j.Recent(10)
+		assert.NoError(t, err)
+		assert.Len(t, entries, 3)
+		assert.Equal(t, "ENTRY_IN > 1", entries[0].Text)
+		assert.Equal(t, "QUEUED", entries[1].Text)
+		assert.Equal(t, "ENTRY_OUT > 1", entries[2].Text)
+		assert.Equal(t, entries[0].Time, entries[1].Time)
+	})
+
+	t.Run("recent should keep the newest entries and skip broken lines", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), FileName)
+		j := New(path)
+		assert.NoError(t, j.Add("1"))
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+		assert.NoError(t, err)
+		_, _ = f.WriteString("{broken\n")
+		assert.NoError(t, f.Close())
+		assert.NoError(t, j.Add("2\n3"))
+
+		entries, err := j.Recent(2)
+		assert.NoError(t, err)
+		assert.Len(t, entries, 2)
+		assert.Equal(t, "2", entries[0].Text)
+		assert.Equal(t, "3", entries[1].Text)
+	})
+}
@@ -3,16 +3,34 @@ package orderdb
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
+	"sync"
+	"time"
+
 	"github.com/google/uuid"
-	"github.com/peterbourgon/diskv/v3"
 )
 
+const (
+	GroupStatusActive    = "active"
+	GroupStatusCancelled = "cancelled"
+	GroupStatusClosed    = "closed"
+)
+
+// OrderState map MT5 tickets to their exante orders. Changes are
+// appended as events to the ticket log, the group derived from them
+// is kept too so it isn't replayed on every start.
 type OrderState struct {
-	d        *diskv.Diskv
+	s        Storage
+	mu       sync.RWMutex
 	orderMap map[string]OrderGroup
+	lastSeq  map[string]int
+	indexes  indexes
 }
 
 func (os *OrderState) List() []OrderGroup {
+	os.mu.RLock()
+	defer os.mu.RUnlock()
+
 	l := make([]OrderGroup, 0)
 	for _, val := range os.orderMap {
 		l = append(l, val)
@@ -23,14 +41,31 @@ func (os *OrderState) List() []OrderGroup {
 
 type OrderGroup struct {
 	Ticket     string
+	Status     string
 	Order      OrderDB
 	OcoGroup   string
 	StopLoss   *OrderDB
 	TakeProfit *OrderDB
+	// Rolling is true once a rollover closed the position on its contract
+	// and until it is opened on the next one
+	Rolling   bool `json:",omitempty"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Has return true if orderID is the parent, SL or TP of the group
+func (g OrderGroup) Has(orderID string) bool {
+	if len(orderID) == 0 {
+		return false
+	}
+	return g.Order.ID == orderID ||
+		(g.StopLoss != nil && g.StopLoss.ID == orderID) ||
+		(g.TakeProfit != nil && g.TakeProfit.ID == orderID)
 }
 
 type OrderDB struct {
 	ID         string
+	Status     string
 	Price      string
 	StopPrice  string
 	Quantity   string
@@ -42,75 +77,216 @@ type OrderDB struct {
 }
 
 func NewOrderGroup() OrderGroup {
+	now := time.Now()
 	return OrderGroup{
-		OcoGroup: uuid.NewString(),
+		Status:    GroupStatusActive,
+		OcoGroup:  uuid.NewString(),
+		CreatedAt: now,
+		UpdatedAt: now,
 	}
 }
 
 func NewOrderGroupWithTicket(ticket string) OrderGroup {
-	return OrderGroup{
-		Ticket:   ticket,
-		OcoGroup: uuid.NewString(),
-	}
+	group := NewOrderGroup()
+	group.Ticket = ticket
+	return group
 }
 
 func NewNoDisk() *OrderState {
-	return &OrderState{orderMap: map[string]OrderGroup{}}
+	return &OrderState{s: NewMemoryStorage(), orderMap: map[string]OrderGroup{}, lastSeq: map[string]int{}, indexes: newIndexes()}
 }
 
+// New open the diskv storage at path
 func New(path string) (*OrderState, error) {
-	d := diskv.New(diskv.Options{
-		BasePath:     fmt.Sprintf("%s/.db", path),
-		Transform:    func(s string) []string { return []string{} },
-		CacheSizeMax: 1024 * 1024,
-	})
+	s, err := NewDiskvStorage(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewWithStorage(s)
+}
 
-	err := d.Write("test", []byte{})
+// NewWithStorage migrate s to the current schema and load its groups
+func NewWithStorage(s Storage) (*OrderState, error) {
+	err := migrate(s)
 	if err != nil {
 		return nil, err
 	}
 
-	has := d.Has("root")
-	if has {
-		rootB, err := d.Read("root")
+	db := &OrderState{s: s, orderMap: make(map[string]OrderGroup), lastSeq: make(map[string]int), indexes: newIndexes()}
+	for _, key := range s.Keys() {
+		if ticket, seq, ok := parseEventKey(key); ok {
+			db.lastSeq[ticket] = max(db.lastSeq[ticket], seq)
+			continue
+		}
+		if !strings.HasPrefix(key, groupPrefix) {
+			continue
+		}
+		b, err := s.Read(key)
 		if err != nil {
 			return nil, err
 		}
 
-		var orderMap map[string]OrderGroup
-		err = json.Unmarshal(rootB, &orderMap)
+		var group OrderGroup
+		err = json.Unmarshal(b, &group)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read %s: %s", key, err.Error())
+		}
+		group.Ticket = strings.TrimPrefix(key, groupPrefix)
+		db.orderMap[group.Ticket] = group
+		db.indexes.add(group)
+	}
+
+	return db, nil
+}
+
+// Append add events to the ticket log and return the group after them
+func (os *OrderState) Append(ticketID string, events ...Event) (OrderGroup, error) {
+	os.mu.Lock()
+	defer os.mu.Unlock()
+
+	group := os.orderMap[ticketID]
+	for _, e := range events {
+		os.lastSeq[ticketID]++
+		e.Seq = os.lastSeq[ticketID]
+		e.Ticket = ticketID
+		if e.Time.IsZero() {
+			e.Time = time.Now()
+		}
+
+		b, err := json.Marshal(e)
+		if err != nil {
+			return group, err
+		}
+		err = os.s.Write(eventKey(ticketID, e.Seq), b)
+		if err != nil {
+			return group, err
+		}
+		group = group.Apply(e)
+	}
+
+	os.set(ticketID, group)
+	return group, os.write(ticketID)
+}
+
+// Events return the log of a ticket, oldest first
+func (os *OrderState) Events(ticketID string) ([]Event, error) {
+	os.mu.RLock()
+	defer os.mu.RUnlock()
+
+	return os.events(ticketID)
+}
+
+// events must be called with os.mu locked
+func (os *OrderState) events(ticketID string) ([]Event, error) {
+	events := make([]Event, 0)
+
+	for seq := 1; seq <= os.lastSeq[ticketID]; seq++ {
+		key := eventKey(ticketID, seq)
+		if !os.s.Has(key) {
+			continue
+		}
+		b, err := os.s.Read(key)
 		if err != nil {
 			return nil, err
 		}
 
-		return &OrderState{d: d, orderMap: orderMap}, err
+		var e Event
+		err = json.Unmarshal(b, &e)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read %s: %s", key, err.Error())
+		}
+		events = append(events, e)
 	}
+	return events, nil
+}
 
-	return &OrderState{d: d, orderMap: make(map[string]OrderGroup)}, nil
+// Rebuild replay the log of every ticket and replace the stored groups
+func (os *OrderState) Rebuild() error {
+	os.mu.Lock()
+	defer os.mu.Unlock()
+
+	for ticketID := range os.lastSeq {
+		events, err := os.events(ticketID)
+		if err != nil {
+			return err
+		}
+		os.set(ticketID, Replay(events))
+		err = os.write(ticketID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func (os *OrderState) Upsert(ticketID string, order OrderGroup) {
-	os.orderMap[ticketID] = order
-	//b, _ := json.Marshal(order)
-	//_ = os.d.Write(ticketID, b)
+// Delete remove the group of a ticket and its log
+func (os *OrderState) Delete(ticketID string) error {
+	os.mu.Lock()
+	defer os.mu.Unlock()
+
+	return os.erase(ticketID)
 }
 
-func (os *OrderState) Delete(ticketID string) {
+// erase must be called with os.mu locked. The group leaves the indexes
+// and its stored snapshot before the log, when erasing the log fails the
+// ticket is no longer queried and erasing it again remove the rest.
+func (os *OrderState) erase(ticketID string) error {
+	os.indexes.remove(os.orderMap[ticketID])
 	delete(os.orderMap, ticketID)
-	//_ = os.d.Erase(ticketID)
+	if err := os.s.Erase(groupPrefix + ticketID); err != nil {
+		return err
+	}
+
+	for seq := os.lastSeq[ticketID]; seq > 0; seq-- {
+		if err := os.s.Erase(eventKey(ticketID, seq)); err != nil {
+			os.lastSeq[ticketID] = seq
+			return err
+		}
+	}
+	delete(os.lastSeq, ticketID)
+	return nil
 }
 
 func (os *OrderState) Get(ticketID string) (OrderGroup, bool) {
-	for ticket, val := range os.orderMap {
-		if ticket == ticketID {
-			return val, true
+	os.mu.RLock()
+	defer os.mu.RUnlock()
+
+	val, has := os.orderMap[ticketID]
+	return val, has
+}
+
+// Flush write every group again
+func (os *OrderState) Flush() error {
+	os.mu.Lock()
+	defer os.mu.Unlock()
+
+	for ticketID := range os.orderMap {
+		if err := os.write(ticketID); err != nil {
+			return err
 		}
 	}
+	return nil
+}
 
-	return OrderGroup{}, false
+func (os *OrderState) Close() error {
+	return os.s.Close()
 }
 
-func (os *OrderState) Flush() error {
-	b, _ := json.Marshal(os.orderMap)
-	return os.d.Write("root", b)
+// set must be called with os.mu locked
+func (os *OrderState) set(ticketID string, group OrderGroup) {
+	if old, has := os.orderMap[ticketID]; has {
+		os.indexes.remove(old)
+	}
+	group.Ticket = ticketID
+	os.orderMap[ticketID] = group
+	os.indexes.add(group)
+}
+
+// write must be called with os.mu locked
+func (os *OrderState) write(ticketID string) error {
+	b, err := json.Marshal(os.orderMap[ticketID])
+	if err != nil {
+		return err
+	}
+	return os.s.Write(groupPrefix+ticketID, b)
 }
@@ -0,0 +1,284 @@
+package orderdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderState(t *testing.T) {
+	for _, backend := range []string{BackendDiskv, BackendFile} {
+		t.Run(backend+" should keep groups after reopen", func(t *testing.T) {
+			path := t.TempDir()
+			s, err := OpenStorage(path, backend)
+			assert.NoError(t, err)
+			db, err := NewWithStorage(s)
+			assert.NoError(t, err)
+
+			_, err = db.Append("1234",
+				Event{Type: EventPlaced, Role: RoleParent, Order: &OrderDB{ID: "parent", Symbol: "EUR/USD"}, OcoGroup: "oco"},
+				Event{Type: EventPlaced, Role: RoleStopLoss, Order: &OrderDB{ID: "sl"}},
+			)
+			assert.NoError(t, err)
+			_, err = db.Append("4321", Event{Type: EventPlaced, Role: RoleParent, Order: &OrderDB{ID: "other"}})
+			assert.NoError(t, err)
+			assert.NoError(t, db.Delete("4321"))
+			assert.NoError(t, db.Close())
+
+			s, err = OpenStorage(path, backend)
+			assert.NoError(t, err)
+			db, err = NewWithStorage(s)
+			assert.NoError(t, err)
+			defer db.Close()
+
+			assert.Len(t, db.List(), 1)
+			stored, has := db.Get("1234")
+			assert.True(t, has)
+			assert.Equal(t, "parent", stored.Order.ID)
+
+			events, err := db.Events("1234")
+			assert.NoError(t, err)
+			assert.Len(t, events, 2)
+
+			_, err = db.Append("1234", Event{Type: EventFilled, OrderID: "sl"})
+			assert.NoError(t, err)
+			stored, _ = db.Get("1234")
+			assert.Equal(t, GroupStatusClosed, stored.Status)
+		})
+	}
+
+	t.Run("group should be rebuilt from its events", func(t *testing.T) {
+		s, err := NewDiskvStorage(t.TempDir())
+		assert.NoError(t, err)
+		db, err := NewWithStorage(s)
+		assert.NoError(t, err)
+
+		_, err = db.Append("1234",
+			Event{Type: EventSnapshotSeen, Snapshot: []byte(`{"Ticket":"1234"}`)},
+			Event{Type: EventPlaced, Role: RoleParent, Order: &OrderDB{ID: "parent", Price: "1.1"}, OcoGroup: "oco"},
+			Event{Type: EventPlaced, Role: RoleTakeProfit, Order: &OrderDB{ID: "tp", Price: "1.3"}},
+			Event{Type: EventReplaced, Order: &OrderDB{ID: "tp", Price: "1.4"}},
+			Event{Type: EventPlaced, Role: RoleStopLoss, Order: &OrderDB{ID: "sl"}},
+			Event{Type: EventCancelled, OrderID: "sl"},
+		)
+		assert.NoError(t, err)
+		want, _ := db.Get("1234")
+
+		// corrupted group
+		assert.NoError(t, s.Write(groupPrefix+"1234", []byte(`{}`)))
+		db, err = NewWithStorage(s)
+		assert.NoError(t, err)
+		assert.NoError(t, db.Rebuild())
+
+		group, _ := db.Get("1234")
+		assert.Equal(t, want.Order, group.Order)
+		assert.Equal(t, "1.4", group.TakeProfit.Price)
+		assert.Nil(t, group.StopLoss)
+		assert.Equal(t, "oco", group.OcoGroup)
+		assert.Equal(t, GroupStatusActive, group.Status)
+	})
+
+	t.Run("file storage should ignore a record cut by a crash", func(t *testing.T) {
+		path := t.TempDir() + "/orders.db"
+		s, err := NewFileStorage(path)
+		assert.NoError(t, err)
+		assert.NoError(t, s.Write("a", []byte("1")))
+		// partial record, as left by a crash in the middle of a write
+		_, err = s.f.Write(encodeRecord("b", []byte("2"))[:13])
+		assert.NoError(t, err)
+		assert.NoError(t, s.Close())
+
+		s, err = NewFileStorage(path)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"a"}, s.Keys())
+		assert.NoError(t, s.Write("c", []byte("3")))
+		assert.NoError(t, s.Close())
+
+		s, err = NewFileStorage(path)
+		assert.NoError(t, err)
+		defer s.Close()
+		assert.ElementsMatch(t, []string{"a", "c"}, s.Keys())
+	})
+
+	t.Run("file storage should not truncate records after a corrupted one", func(t *testing.T) {
+		path := t.TempDir() + "/orders.db"
+		s, err := NewFileStorage(path)
+		assert.NoError(t, err)
+		assert.NoError(t, s.Write("a", []byte("1")))
+		assert.NoError(t, s.Write("b", []byte("2")))
+		assert.NoError(t, s.Write("c", []byte("3")))
+		assert.NoError(t, s.Close())
+
+		b, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		size := len(b)
+		// flip the value of b, in the middle of the file
+		b[2*len(encodeRecord("a", []byte("1")))-1] = '9'
+		assert.NoError(t, os.WriteFile(path, b, 0644))
+
+		_, err = NewFileStorage(path)
+		assert.ErrorIs(t, err, errCorruptedRecord)
+		info, err := os.Stat(path)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(size), info.Size())
+	})
+
+	t.Run("file storage should keep writing after compaction", func(t *testing.T) {
+		path := t.TempDir() + "/orders.db"
+		s, err := NewFileStorage(path)
+		assert.NoError(t, err)
+		for i := 0; i <= minCompactSize; i++ {
+			assert.NoError(t, s.Write("a", []byte(strconv.Itoa(i))))
+		}
+		assert.Equal(t, 1, s.records, "compacted")
+		assert.NoError(t, s.Write("b", []byte("2")))
+		assert.NoError(t, s.Close())
+
+		s, err = NewFileStorage(path)
+		assert.NoError(t, err)
+		defer s.Close()
+		a, _ := s.Read("a")
+		assert.Equal(t, strconv.Itoa(minCompactSize), string(a))
+		assert.ElementsMatch(t, []string{"a", "b"}, s.Keys())
+	})
+
+	t.Run("record longer than the file should be corrupted without reading it", func(t *testing.T) {
+		record := encodeRecord("a", []byte("1"))
+		binary.LittleEndian.PutUint32(record[4:8], math.MaxUint32)
+		_, _, size, err := readRecord(bytes.NewReader(record), int64(len(record)))
+		assert.ErrorIs(t, err, errCorruptedRecord)
+		assert.Greater(t, size, int64(len(record)))
+
+		binary.LittleEndian.PutUint32(record[4:8], 1)
+		binary.LittleEndian.PutUint32(record[8:12], uint32(0xfffffff0))
+		_, _, _, err = readRecord(bytes.NewReader(record), int64(len(record)))
+		assert.ErrorIs(t, err, errCorruptedRecord)
+	})
+
+	t.Run("file storage should drop a corrupted last record", func(t *testing.T) {
+		path := t.TempDir() + "/orders.db"
+		s, err := NewFileStorage(path)
+		assert.NoError(t, err)
+		assert.NoError(t, s.Write("a", []byte("1")))
+		assert.NoError(t, s.Write("b", []byte("2")))
+		assert.NoError(t, s.Close())
+
+		b, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		b[len(b)-1] = '9'
+		assert.NoError(t, os.WriteFile(path, b, 0644))
+
+		s, err = NewFileStorage(path)
+		assert.NoError(t, err)
+		defer s.Close()
+		assert.Equal(t, []string{"a"}, s.Keys())
+	})
+
+	t.Run("schema 1 root should be migrated to one key per group", func(t *testing.T) {
+		s, err := NewDiskvStorage(t.TempDir())
+		assert.NoError(t, err)
+		b, _ := json.Marshal(map[string]OrderGroup{"1234": {OcoGroup: "oco", Order: OrderDB{ID: "parent"}}})
+		assert.NoError(t, s.Write(legacyKey, b))
+
+		db, err := NewWithStorage(s)
+		assert.NoError(t, err)
+		group, has := db.Get("1234")
+		assert.True(t, has)
+		assert.Equal(t, "1234", group.Ticket)
+		assert.Equal(t, GroupStatusActive, group.Status)
+		assert.False(t, s.Has(legacyKey))
+
+		events, err := db.Events("1234")
+		assert.NoError(t, err)
+		assert.Len(t, events, 1)
+		assert.Equal(t, EventPlaced, events[0].Type)
+
+		version, err := schemaVersion(s)
+		assert.NoError(t, err)
+		assert.Equal(t, SchemaVersion, version)
+	})
+
+	t.Run("storage should have groups in any schema", func(t *testing.T) {
+		for _, key := range []string{legacyKey, groupPrefix + "1", eventPrefix + "1.1"} {
+			s := NewMemoryStorage()
+			assert.NoError(t, s.Write(schemaKey, []byte("3")))
+			assert.False(t, HasGroups(s))
+			assert.NoError(t, s.Write(key, []byte("{}")))
+			assert.True(t, HasGroups(s), key)
+		}
+	})
+	t.Run("query should use indexes and paginate", func(t *testing.T) {
+		db := NewNoDisk()
+		for _, ticket := range []string{"1", "2", "3", "4"} {
+			symbol := "EUR/USD"
+			if ticket == "4" {
+				symbol = "BTC.USD"
+			}
+			_, err := db.Append(ticket, Event{Type: EventPlaced, Role: RoleParent, Order: &OrderDB{ID: "p" + ticket, Symbol: symbol, AccountId: "acc-1"}, OcoGroup: "oco" + ticket})
+			assert.NoError(t, err)
+		}
+		_, err := db.Append("2", Event{Type: EventClosed})
+		assert.NoError(t, err)
+
+		page := db.Query(Query{Symbol: "EUR/USD", Status: GroupStatusActive})
+		assert.Equal(t, 2, page.Total)
+
+		page = db.Query(Query{AccountID: "acc-1", Limit: 3})
+		assert.Equal(t, 4, page.Total)
+		assert.Len(t, page.Groups, 3)
+		assert.Equal(t, "2", page.Groups[0].Ticket)
+
+		page = db.Query(Query{AccountID: "acc-1", Limit: 3, Offset: 3})
+		assert.Len(t, page.Groups, 1)
+
+		page = db.Query(Query{OcoGroup: "oco4"})
+		assert.Equal(t, "4", page.Groups[0].Ticket)
+
+		page = db.Query(Query{From: page.Groups[0].UpdatedAt.Add(time.Hour)})
+		assert.Equal(t, 0, page.Total)
+
+		assert.NoError(t, db.Delete("4"))
+		assert.Equal(t, 0, db.Query(Query{Symbol: "BTC.USD"}).Total)
+	})
+
+	t.Run("delete failing on the log should not leave the ticket queried", func(t *testing.T) {
+		s := &failingEraseStorage{MemoryStorage: NewMemoryStorage(), fail: eventKey("1", 1)}
+		db, err := NewWithStorage(s)
+		assert.NoError(t, err)
+		_, err = db.Append("1",
+			Event{Type: EventSnapshotSeen, Snapshot: []byte(`{"Ticket":"1"}`)},
+			Event{Type: EventPlaced, Role: RoleParent, Order: &OrderDB{ID: "p1", Symbol: "EUR/USD"}},
+		)
+		assert.NoError(t, err)
+
+		assert.Error(t, db.Delete("1"))
+		_, has := db.Get("1")
+		assert.False(t, has)
+		assert.Equal(t, 0, db.Query(Query{Symbol: "EUR/USD"}).Total)
+		assert.False(t, s.Has(groupPrefix+"1"))
+
+		s.fail = ""
+		assert.NoError(t, db.Delete("1"))
+		assert.Equal(t, []string{schemaKey}, s.Keys())
+	})
+}
+
+type failingEraseStorage struct {
+	*MemoryStorage
+	fail string
+}
+
+func (s *failingEraseStorage) Erase(key string) error {
+	if key == s.fail {
+		return errors.New("disk full")
+	}
+	return s.MemoryStorage.Erase(key)
+}
@@ -0,0 +1,184 @@
+package orderdb
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type (
+	EventType string
+	Role      string
+)
+
+const (
+	EventSnapshotSeen EventType = "mt5_snapshot_seen"
+	EventPlaced       EventType = "placed"
+	EventReplaced     EventType = "replaced"
+	EventFilled       EventType = "filled"
+	EventCancelled    EventType = "cancelled"
+	EventClosed       EventType = "closed"
+	// EventRolling is the close leg of a rollover, OrderID, sent before
+	// the position is opened on the next contract
+	EventRolling EventType = "rolling"
+
+	RoleParent     Role = "parent"
+	RoleStopLoss   Role = "stop_loss"
+	RoleTakeProfit Role = "take_profit"
+
+	OrderStatusFilled    = "filled"
+	OrderStatusCancelled = "cancelled"
+)
+
+// Event is a step of a ticket lifecycle, the OrderGroup of a ticket
+// is the result of applying its events in order
+type Event struct {
+	Seq      int             `json:"seq"`
+	Ticket   string          `json:"ticket"`
+	Type     EventType       `json:"type"`
+	Time     time.Time       `json:"time"`
+	Role     Role            `json:"role,omitempty"`
+	Order    *OrderDB        `json:"order,omitempty"`
+	OrderID  string          `json:"orderId,omitempty"`
+	OcoGroup string          `json:"ocoGroup,omitempty"`
+	Snapshot json.RawMessage `json:"snapshot,omitempty"`
+}
+
+// Apply return the group after e
+func (g OrderGroup) Apply(e Event) OrderGroup {
+	g.Ticket = e.Ticket
+	if g.CreatedAt.IsZero() {
+		g.CreatedAt = e.Time
+	}
+	g.UpdatedAt = e.Time
+
+	switch e.Type {
+	case EventPlaced:
+		if e.Order == nil {
+			break
+		}
+		order := *e.Order
+		switch e.Role {
+		case RoleParent:
+			// a new parent start the group again
+			g.Order = order
+			g.StopLoss = nil
+			g.TakeProfit = nil
+			g.Status = GroupStatusActive
+			g.Rolling = false
+		case RoleStopLoss:
+			g.StopLoss = &order
+		case RoleTakeProfit:
+			g.TakeProfit = &order
+		}
+		if len(e.OcoGroup) > 0 {
+			g.OcoGroup = e.OcoGroup
+		}
+
+	case EventReplaced:
+		if e.Order == nil || len(e.Order.ID) == 0 {
+			break
+		}
+		order := *e.Order
+		switch order.ID {
+		case g.Order.ID:
+			g.Order = order
+		case g.stopLossID():
+			g.StopLoss = &order
+		case g.takeProfitID():
+			g.TakeProfit = &order
+		}
+
+	case EventFilled:
+		if len(e.OrderID) == 0 {
+			break
+		}
+		switch e.OrderID {
+		case g.Order.ID:
+			g.Order.Status = OrderStatusFilled
+		case g.stopLossID():
+			stopLoss := *g.StopLoss
+			stopLoss.Status = OrderStatusFilled
+			g.StopLoss = &stopLoss
+			g.Status = GroupStatusClosed
+		case g.takeProfitID():
+			takeProfit := *g.TakeProfit
+			takeProfit.Status = OrderStatusFilled
+			g.TakeProfit = &takeProfit
+			g.Status = GroupStatusClosed
+		}
+
+	case EventCancelled:
+		if len(e.OrderID) == 0 {
+			break
+		}
+		switch e.OrderID {
+		case g.Order.ID:
+			g.Order.Status = OrderStatusCancelled
+			g.Status = GroupStatusCancelled
+		case g.stopLossID():
+			g.StopLoss = nil
+		case g.takeProfitID():
+			g.TakeProfit = nil
+		}
+
+	case EventRolling:
+		g.Rolling = true
+
+	case EventClosed:
+		g.Status = GroupStatusClosed
+	}
+
+	return g
+}
+
+func (g OrderGroup) stopLossID() string {
+	if g.StopLoss == nil {
+		return ""
+	}
+	return g.StopLoss.ID
+}
+
+func (g OrderGroup) takeProfitID() string {
+	if g.TakeProfit == nil {
+		return ""
+	}
+	return g.TakeProfit.ID
+}
+
+// Replay build the group of a ticket from its events
+func Replay(events []Event) OrderGroup {
+	var g OrderGroup
+	for _, e := range events {
+		g = g.Apply(e)
+	}
+	return g
+}
+
+// GroupEvents return the events that build group, used to start the
+// log of groups stored before events existed
+func GroupEvents(group OrderGroup) []Event {
+	events := make([]Event, 0)
+	at := group.UpdatedAt
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	if len(group.Order.ID) > 0 {
+		order := group.Order
+		events = append(events, Event{Ticket: group.Ticket, Type: EventPlaced, Time: at, Role: RoleParent, Order: &order, OcoGroup: group.OcoGroup})
+	}
+	if group.StopLoss != nil {
+		events = append(events, Event{Ticket: group.Ticket, Type: EventPlaced, Time: at, Role: RoleStopLoss, Order: group.StopLoss})
+	}
+	if group.TakeProfit != nil {
+		events = append(events, Event{Ticket: group.Ticket, Type: EventPlaced, Time: at, Role: RoleTakeProfit, Order: group.TakeProfit})
+	}
+
+	switch group.Status {
+	case GroupStatusCancelled:
+		events = append(events, Event{Ticket: group.Ticket, Type: EventCancelled, Time: at, OrderID: group.Order.ID})
+	case GroupStatusClosed:
+		events = append(events, Event{Ticket: group.Ticket, Type: EventClosed, Time: at})
+	}
+	return events
+}
@@ -0,0 +1,284 @@
+package orderdb
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"sync"
+)
+
+// FileStorage is a key/value store kept in a single append only file.
+// Each write append a record (crc, key and value) and sync the file, the
+// last record is ignored when it was cut by a crash, a corrupted record
+// before it fails the open. The file is compacted when most records are
+// stale.
+type FileStorage struct {
+	path string
+
+	mu      sync.Mutex
+	f       *os.File
+	values  map[string][]byte
+	records int
+}
+
+var errCorruptedRecord = errors.New("corrupted record")
+
+const (
+	recordHeaderSize = 12
+	tombstone        = -1
+	minCompactSize   = 1000
+)
+
+func NewFileStorage(path string) (*FileStorage, error) {
+	s := &FileStorage{path: path, values: make(map[string][]byte)}
+
+	valid, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	s.f, err = os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	// drop a torn record left by a crash
+	err = s.f.Truncate(valid)
+	if err != nil {
+		s.f.Close()
+		return nil, err
+	}
+	_, err = s.f.Seek(valid, io.SeekStart)
+	if err != nil {
+		s.f.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// load read every record and return the size of the valid part of the
+// file. Only the last record may be torn, a corrupted record followed by
+// other records is an error so they are not truncated with it.
+func (s *FileStorage) load() (int64, error) {
+	f, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	r := bufio.NewReader(f)
+	var valid int64
+	for {
+		key, val, size, err := readRecord(r, info.Size()-valid)
+		if errors.Is(err, errCorruptedRecord) && valid+size < info.Size() {
+			return 0, fmt.Errorf("%s: %w at offset %d", s.path, err, valid)
+		}
+		if err != nil {
+			return valid, nil
+		}
+		valid += size
+		s.records++
+		if val == nil {
+			delete(s.values, key)
+			continue
+		}
+		s.values[key] = val
+	}
+}
+
+// readRecord read the next record, remaining is the size left in the file
+// so a corrupted header can't ask for more than the file has
+func readRecord(r io.Reader, remaining int64) (string, []byte, int64, error) {
+	header := make([]byte, recordHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return "", nil, 0, err
+	}
+	sum := binary.LittleEndian.Uint32(header[0:4])
+	keyLen := binary.LittleEndian.Uint32(header[4:8])
+	valLen := int32(binary.LittleEndian.Uint32(header[8:12]))
+	if valLen < tombstone {
+		return "", nil, recordHeaderSize, errCorruptedRecord
+	}
+
+	bodyLen := int64(keyLen)
+	if valLen > 0 {
+		bodyLen += int64(valLen)
+	}
+	if recordHeaderSize+bodyLen > remaining {
+		// past the end of the file, a torn record or a corrupted length
+		return "", nil, recordHeaderSize + bodyLen, errCorruptedRecord
+	}
+	body := make([]byte, bodyLen)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return "", nil, 0, err
+	}
+	size := recordHeaderSize + bodyLen
+	if crc32.ChecksumIEEE(append(header[4:12:12], body...)) != sum {
+		return "", nil, size, errCorruptedRecord
+	}
+
+	key := string(body[:keyLen])
+	if valLen == tombstone {
+		return key, nil, size, nil
+	}
+	return key, body[keyLen:], size, nil
+}
+
+func encodeRecord(key string, val []byte) []byte {
+	valLen := int32(len(val))
+	if val == nil {
+		valLen = tombstone
+	}
+
+	b := make([]byte, recordHeaderSize, recordHeaderSize+len(key)+len(val))
+	binary.LittleEndian.PutUint32(b[4:8], uint32(len(key)))
+	binary.LittleEndian.PutUint32(b[8:12], uint32(valLen))
+	b = append(b, key...)
+	b = append(b, val...)
+	binary.LittleEndian.PutUint32(b[0:4], crc32.ChecksumIEEE(b[4:]))
+	return b
+}
+
+// append must be called with s.mu locked
+func (s *FileStorage) append(key string, val []byte) error {
+	_, err := s.f.Write(encodeRecord(key, val))
+	if err != nil {
+		return err
+	}
+	err = s.f.Sync()
+	if err != nil {
+		return err
+	}
+	s.records++
+	return nil
+}
+
+// maybeCompact must be called with s.mu locked, after values is updated
+func (s *FileStorage) maybeCompact() error {
+	if s.records > minCompactSize && s.records > 2*len(s.values) {
+		return s.compact()
+	}
+	return nil
+}
+
+// compact rewrite the live keys to a new file, must be called with s.mu locked
+func (s *FileStorage) compact() error {
+	tmpPath := s.path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(tmp)
+	for key, val := range s.values {
+		if _, err := w.Write(encodeRecord(key, val)); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	// windows can't rename over an open file
+	if err := s.f.Close(); err != nil {
+		return err
+	}
+	renameErr := os.Rename(tmpPath, s.path)
+	s.f, err = os.OpenFile(s.path, os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if renameErr != nil {
+		// the previous file is still there, keep appending to it
+		_ = os.Remove(tmpPath)
+		return renameErr
+	}
+	s.records = len(s.values)
+	return nil
+}
+
+func (s *FileStorage) Read(key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	val, has := s.values[key]
+	if !has {
+		return nil, fmt.Errorf("%s: %w", key, ErrKeyNotFound)
+	}
+	return append([]byte{}, val...), nil
+}
+
+func (s *FileStorage) Write(key string, val []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if val == nil {
+		val = []byte{}
+	}
+	err := s.append(key, val)
+	if err != nil {
+		return err
+	}
+	s.values[key] = append([]byte{}, val...)
+	return s.maybeCompact()
+}
+
+func (s *FileStorage) Erase(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, has := s.values[key]; !has {
+		return nil
+	}
+	err := s.append(key, nil)
+	if err != nil {
+		return err
+	}
+	delete(s.values, key)
+	return s.maybeCompact()
+}
+
+func (s *FileStorage) Has(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, has := s.values[key]
+	return has
+}
+
+func (s *FileStorage) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]string, 0, len(s.values))
+	for key := range s.values {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func (s *FileStorage) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.f.Close()
+}
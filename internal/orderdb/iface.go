@@ -1,9 +1,15 @@
 package orderdb
 
+import "time"
+
 type Iface interface {
-	Upsert(ticketID string, order OrderGroup)
-	Delete(ticketID string)
+	Append(ticketID string, events ...Event) (OrderGroup, error)
+	Events(ticketID string) ([]Event, error)
+	Rebuild() error
+	Delete(ticketID string) error
+	Archive(before time.Time) ([]string, error)
 	Get(ticketID string) (OrderGroup, bool)
 	List() []OrderGroup
+	Query(q Query) Page
 	Flush() error
 }
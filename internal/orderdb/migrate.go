@@ -0,0 +1,211 @@
+package orderdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SchemaVersion is the layout written by this version:
+//
+//	1: every group in a single json map under `root`
+//	2: one key per group, `group.<ticket>`
+//	3: groups derived from the event log, `event.<ticket>.<seq>`
+const SchemaVersion = 3
+
+const (
+	schemaKey   = "schema"
+	legacyKey   = "root"
+	groupPrefix = "group."
+	eventPrefix = "event."
+)
+
+type migration struct {
+	version int
+	up      func(s Storage) error
+}
+
+// migrations upgrade the storage from version-1 to version, in order
+var migrations = []migration{
+	{version: 2, up: splitRoot},
+	{version: 3, up: startEventLog},
+}
+
+func schemaVersion(s Storage) (int, error) {
+	if !s.Has(schemaKey) {
+		// stores written before versioning kept everything on root
+		if s.Has(legacyKey) {
+			return 1, nil
+		}
+		return 0, nil
+	}
+
+	b, err := s.Read(schemaKey)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(string(b))
+}
+
+// migrate run the pending migrations, a new storage start on SchemaVersion
+func migrate(s Storage) error {
+	version, err := schemaVersion(s)
+	if err != nil {
+		return fmt.Errorf("cannot read orderdb schema: %s", err.Error())
+	}
+	if version > SchemaVersion {
+		return fmt.Errorf("orderdb schema %d is newer than supported %d", version, SchemaVersion)
+	}
+
+	if version > 0 {
+		for _, m := range migrations {
+			if m.version <= version {
+				continue
+			}
+			err = m.up(s)
+			if err != nil {
+				return fmt.Errorf("cannot migrate orderdb to schema %d: %s", m.version, err.Error())
+			}
+			err = s.Write(schemaKey, []byte(strconv.Itoa(m.version)))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return s.Write(schemaKey, []byte(strconv.Itoa(SchemaVersion)))
+}
+
+// splitRoot move each group of root to its own key
+func splitRoot(s Storage) error {
+	if !s.Has(legacyKey) {
+		return nil
+	}
+
+	b, err := s.Read(legacyKey)
+	if err != nil {
+		return err
+	}
+
+	var orderMap map[string]OrderGroup
+	err = json.Unmarshal(b, &orderMap)
+	if err != nil {
+		return err
+	}
+
+	for ticket, group := range orderMap {
+		if len(group.Ticket) == 0 {
+			group.Ticket = ticket
+		}
+		if len(group.Status) == 0 {
+			group.Status = GroupStatusActive
+		}
+		b, err := json.Marshal(group)
+		if err != nil {
+			return err
+		}
+		err = s.Write(groupPrefix+ticket, b)
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.Erase(legacyKey)
+}
+
+// startEventLog write the events of each group stored before the log existed
+func startEventLog(s Storage) error {
+	for _, key := range s.Keys() {
+		if !strings.HasPrefix(key, groupPrefix) {
+			continue
+		}
+		b, err := s.Read(key)
+		if err != nil {
+			return err
+		}
+
+		var group OrderGroup
+		err = json.Unmarshal(b, &group)
+		if err != nil {
+			return err
+		}
+
+		ticket := strings.TrimPrefix(key, groupPrefix)
+		group.Ticket = ticket
+		for idx, e := range GroupEvents(group) {
+			e.Seq = idx + 1
+			b, err := json.Marshal(e)
+			if err != nil {
+				return err
+			}
+			err = s.Write(eventKey(ticket, e.Seq), b)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func eventKey(ticket string, seq int) string {
+	return fmt.Sprintf("%s%s.%010d", eventPrefix, ticket, seq)
+}
+
+func parseEventKey(key string) (string, int, bool) {
+	if !strings.HasPrefix(key, eventPrefix) {
+		return "", 0, false
+	}
+	rest := strings.TrimPrefix(key, eventPrefix)
+	idx := strings.LastIndex(rest, ".")
+	if idx == -1 {
+		return "", 0, false
+	}
+	seq, err := strconv.Atoi(rest[idx+1:])
+	if err != nil {
+		return "", 0, false
+	}
+	return rest[:idx], seq, true
+}
+
+// Dump return every key of s, used to move the state to another storage
+func Dump(s Storage) (map[string]string, error) {
+	dump := make(map[string]string)
+	for _, key := range s.Keys() {
+		b, err := s.Read(key)
+		if err != nil {
+			return nil, err
+		}
+		dump[key] = string(b)
+	}
+	return dump, nil
+}
+
+// Restore replace every key of s by dump, the schema of dump is
+// migrated when the storage is opened
+func Restore(s Storage, dump map[string]string) error {
+	for _, key := range s.Keys() {
+		if _, has := dump[key]; has {
+			continue
+		}
+		if err := s.Erase(key); err != nil {
+			return err
+		}
+	}
+	for key, val := range dump {
+		if err := s.Write(key, []byte(val)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HasGroups return true when s keeps any group, whatever its schema
+func HasGroups(s Storage) bool {
+	for _, key := range s.Keys() {
+		if key == legacyKey || strings.HasPrefix(key, groupPrefix) || strings.HasPrefix(key, eventPrefix) {
+			return true
+		}
+	}
+	return false
+}
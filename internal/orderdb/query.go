@@ -0,0 +1,167 @@
+package orderdb
+
+import (
+	"sort"
+	"time"
+)
+
+const defaultQueryLimit = 100
+
+// Query of groups, empty values match everything. From/To filter
+// the last update of the group.
+type Query struct {
+	Symbol    string
+	AccountID string
+	Status    string
+	OcoGroup  string
+	From      time.Time
+	To        time.Time
+	Offset    int
+	Limit     int
+}
+
+// Page of a query, Total is the number of groups matching before pagination
+type Page struct {
+	Groups []OrderGroup `json:"groups"`
+	Total  int          `json:"total"`
+	Offset int          `json:"offset"`
+	Limit  int          `json:"limit"`
+}
+
+// index map a value to the tickets that have it
+type index map[string]map[string]struct{}
+
+func (i index) add(value string, ticket string) {
+	if len(value) == 0 {
+		return
+	}
+	if i[value] == nil {
+		i[value] = make(map[string]struct{})
+	}
+	i[value][ticket] = struct{}{}
+}
+
+func (i index) remove(value string, ticket string) {
+	delete(i[value], ticket)
+	if len(i[value]) == 0 {
+		delete(i, value)
+	}
+}
+
+type indexes struct {
+	symbol  index
+	account index
+	status  index
+	oco     index
+}
+
+func newIndexes() indexes {
+	return indexes{symbol: index{}, account: index{}, status: index{}, oco: index{}}
+}
+
+func (i indexes) add(g OrderGroup) {
+	i.symbol.add(g.Order.Symbol, g.Ticket)
+	i.symbol.add(g.Order.Instrument, g.Ticket)
+	i.account.add(g.Order.AccountId, g.Ticket)
+	i.status.add(g.Status, g.Ticket)
+	i.oco.add(g.OcoGroup, g.Ticket)
+}
+
+func (i indexes) remove(g OrderGroup) {
+	i.symbol.remove(g.Order.Symbol, g.Ticket)
+	i.symbol.remove(g.Order.Instrument, g.Ticket)
+	i.account.remove(g.Order.AccountId, g.Ticket)
+	i.status.remove(g.Status, g.Ticket)
+	i.oco.remove(g.OcoGroup, g.Ticket)
+}
+
+// Query return the groups matching q, most recently updated first
+func (os *OrderState) Query(q Query) Page {
+	os.mu.RLock()
+	defer os.mu.RUnlock()
+
+	if q.Limit <= 0 {
+		q.Limit = defaultQueryLimit
+	}
+	if q.Offset < 0 {
+		q.Offset = 0
+	}
+
+	groups := make([]OrderGroup, 0)
+	for _, ticket := range os.candidates(q) {
+		if g := os.orderMap[ticket]; q.match(g) {
+			groups = append(groups, g)
+		}
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].UpdatedAt.Equal(groups[j].UpdatedAt) {
+			return groups[i].Ticket < groups[j].Ticket
+		}
+		return groups[i].UpdatedAt.After(groups[j].UpdatedAt)
+	})
+
+	page := Page{Groups: make([]OrderGroup, 0), Total: len(groups), Offset: q.Offset, Limit: q.Limit}
+	if q.Offset < len(groups) {
+		page.Groups = groups[q.Offset:min(q.Offset+q.Limit, len(groups))]
+	}
+	return page
+}
+
+// candidates return the tickets of the smallest index of q filters,
+// every ticket when q has no indexed filter
+func (os *OrderState) candidates(q Query) []string {
+	var smallest map[string]struct{}
+	indexed := false
+	for _, filter := range []struct {
+		value string
+		idx   index
+	}{
+		{q.Symbol, os.indexes.symbol},
+		{q.AccountID, os.indexes.account},
+		{q.Status, os.indexes.status},
+		{q.OcoGroup, os.indexes.oco},
+	} {
+		if len(filter.value) == 0 {
+			continue
+		}
+		if tickets := filter.idx[filter.value]; !indexed || len(tickets) < len(smallest) {
+			smallest = tickets
+		}
+		indexed = true
+	}
+
+	tickets := make([]string, 0)
+	if !indexed {
+		for ticket := range os.orderMap {
+			tickets = append(tickets, ticket)
+		}
+		return tickets
+	}
+	for ticket := range smallest {
+		tickets = append(tickets, ticket)
+	}
+	return tickets
+}
+
+func (q Query) match(g OrderGroup) bool {
+	if len(q.Symbol) > 0 && g.Order.Symbol != q.Symbol && g.Order.Instrument != q.Symbol {
+		return false
+	}
+	if len(q.AccountID) > 0 && g.Order.AccountId != q.AccountID {
+		return false
+	}
+	if len(q.Status) > 0 && g.Status != q.Status {
+		return false
+	}
+	if len(q.OcoGroup) > 0 && g.OcoGroup != q.OcoGroup {
+		return false
+	}
+	if !q.From.IsZero() && g.UpdatedAt.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && g.UpdatedAt.After(q.To) {
+		return false
+	}
+	return true
+}
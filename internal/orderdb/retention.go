@@ -0,0 +1,88 @@
+package orderdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+const archivePrefix = "archive."
+
+// ArchivedGroup is a group moved out of the live state with its log
+type ArchivedGroup struct {
+	Group      OrderGroup `json:"group"`
+	Events     []Event    `json:"events"`
+	ArchivedAt time.Time  `json:"archivedAt"`
+}
+
+// Done return true when the group was explicitly closed or cancelled. A
+// group without parent order is not done, its placement may have failed
+// or still be queued.
+func (g OrderGroup) Done() bool {
+	return g.Status == GroupStatusClosed || g.Status == GroupStatusCancelled
+}
+
+// HasOrders return true once an exante order of the group was tracked,
+// groups seen only on MT5 snapshots have none
+func (g OrderGroup) HasOrders() bool {
+	return len(g.Order.ID) > 0 || g.StopLoss != nil || g.TakeProfit != nil
+}
+
+// Archive move the groups not updated since before to `archive.<ticket>`
+// when they are done or never had an order, their log is removed from
+// the live state. A ticket archived again replace the previous archive.
+func (os *OrderState) Archive(before time.Time) ([]string, error) {
+	os.mu.Lock()
+	defer os.mu.Unlock()
+
+	archived := make([]string, 0)
+	for ticketID, group := range os.orderMap {
+		if (!group.Done() && group.HasOrders()) || !group.UpdatedAt.Before(before) {
+			continue
+		}
+
+		events, err := os.events(ticketID)
+		if err != nil {
+			return archived, err
+		}
+		b, err := json.Marshal(ArchivedGroup{Group: group, Events: events, ArchivedAt: time.Now()})
+		if err != nil {
+			return archived, err
+		}
+		err = os.s.Write(archivePrefix+ticketID, b)
+		if err != nil {
+			return archived, err
+		}
+
+		err = os.erase(ticketID)
+		if err != nil {
+			return archived, err
+		}
+		archived = append(archived, ticketID)
+	}
+
+	sort.Strings(archived)
+	return archived, nil
+}
+
+// Archived return the archive of a ticket
+func (os *OrderState) Archived(ticketID string) (ArchivedGroup, bool, error) {
+	os.mu.RLock()
+	defer os.mu.RUnlock()
+
+	if !os.s.Has(archivePrefix + ticketID) {
+		return ArchivedGroup{}, false, nil
+	}
+	b, err := os.s.Read(archivePrefix + ticketID)
+	if err != nil {
+		return ArchivedGroup{}, false, err
+	}
+
+	var archived ArchivedGroup
+	err = json.Unmarshal(b, &archived)
+	if err != nil {
+		return ArchivedGroup{}, false, fmt.Errorf("cannot read archive of %s: %s", ticketID, err.Error())
+	}
+	return archived, true, nil
+}
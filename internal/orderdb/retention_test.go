@@ -0,0 +1,46 @@
+package orderdb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArchive(t *testing.T) {
+	t.Run("archive should move done groups and groups without orders", func(t *testing.T) {
+		db := NewNoDisk()
+		_, err := db.Append("1", Event{Type: EventSnapshotSeen, Snapshot: []byte(`{"Ticket":"1"}`)})
+		assert.NoError(t, err)
+		_, err = db.Append("2", Event{Type: EventPlaced, Role: RoleParent, Order: &OrderDB{ID: "parent"}})
+		assert.NoError(t, err)
+		_, err = db.Append("3",
+			Event{Type: EventPlaced, Role: RoleParent, Order: &OrderDB{ID: "parent"}},
+			Event{Type: EventClosed},
+		)
+		assert.NoError(t, err)
+
+		archived, err := db.Archive(time.Now().Add(time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"1", "3"}, archived)
+		_, has := db.Get("2")
+		assert.True(t, has, "active group is kept")
+
+		archive, has, err := db.Archived("1")
+		assert.NoError(t, err)
+		assert.True(t, has)
+		assert.Len(t, archive.Events, 1)
+	})
+
+	t.Run("group without orders should wait for before", func(t *testing.T) {
+		db := NewNoDisk()
+		_, err := db.Append("1", Event{Type: EventSnapshotSeen, Snapshot: []byte(`{"Ticket":"1"}`)})
+		assert.NoError(t, err)
+
+		archived, err := db.Archive(time.Now().Add(-time.Hour))
+		assert.NoError(t, err)
+		assert.Len(t, archived, 0, "placement may still be queued")
+		_, has := db.Get("1")
+		assert.True(t, has)
+	})
+}
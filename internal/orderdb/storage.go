@@ -0,0 +1,150 @@
+package orderdb
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/peterbourgon/diskv/v3"
+)
+
+const (
+	BackendDiskv = "diskv"
+	BackendFile  = "file"
+)
+
+var ErrKeyNotFound = errors.New("key not found")
+
+// Storage is the key/value backend of OrderState
+type Storage interface {
+	Read(key string) ([]byte, error)
+	Write(key string, val []byte) error
+	Erase(key string) error
+	Has(key string) bool
+	Keys() []string
+	Close() error
+}
+
+// OpenStorage open the backend by name at path, empty is diskv
+func OpenStorage(path string, backend string) (Storage, error) {
+	switch backend {
+	case "", BackendDiskv:
+		return NewDiskvStorage(path)
+	case BackendFile:
+		return NewFileStorage(fmt.Sprintf("%s/orders.db", path))
+	}
+	return nil, fmt.Errorf("unknown orderdb backend %s", backend)
+}
+
+// DiskvStorage keep each key as a file on <path>/.db
+type DiskvStorage struct {
+	d *diskv.Diskv
+}
+
+func NewDiskvStorage(path string) (*DiskvStorage, error) {
+	d := diskv.New(diskv.Options{
+		BasePath: fmt.Sprintf("%s/.db", path),
+		// writes go to a temp file renamed over the key, a crash
+		// never leaves a half written value
+		TempDir:      fmt.Sprintf("%s/.db-tmp", path),
+		Transform:    func(s string) []string { return []string{} },
+		CacheSizeMax: 1024 * 1024,
+	})
+
+	err := d.Write("test", []byte{})
+	if err != nil {
+		return nil, err
+	}
+	return &DiskvStorage{d: d}, nil
+}
+
+func (s *DiskvStorage) Read(key string) ([]byte, error) {
+	return s.d.Read(key)
+}
+
+func (s *DiskvStorage) Write(key string, val []byte) error {
+	return s.d.Write(key, val)
+}
+
+func (s *DiskvStorage) Erase(key string) error {
+	if !s.d.Has(key) {
+		return nil
+	}
+	return s.d.Erase(key)
+}
+
+func (s *DiskvStorage) Has(key string) bool {
+	return s.d.Has(key)
+}
+
+func (s *DiskvStorage) Keys() []string {
+	keys := make([]string, 0)
+	for key := range s.d.Keys(nil) {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func (s *DiskvStorage) Close() error {
+	return nil
+}
+
+// MemoryStorage keep keys in memory only
+type MemoryStorage struct {
+	mu     sync.RWMutex
+	values map[string][]byte
+}
+
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{values: make(map[string][]byte)}
+}
+
+func (s *MemoryStorage) Read(key string) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	val, has := s.values[key]
+	if !has {
+		return nil, fmt.Errorf("%s: %w", key, ErrKeyNotFound)
+	}
+	return append([]byte{}, val...), nil
+}
+
+func (s *MemoryStorage) Write(key string, val []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.values[key] = append([]byte{}, val...)
+	return nil
+}
+
+func (s *MemoryStorage) Erase(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.values, key)
+	return nil
+}
+
+func (s *MemoryStorage) Has(key string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, has := s.values[key]
+	return has
+}
+
+func (s *MemoryStorage) Keys() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make([]string, 0, len(s.values))
+	for key := range s.values {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func (s *MemoryStorage) Close() error {
+	return nil
+}
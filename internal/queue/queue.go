@@ -0,0 +1,296 @@
+package queue
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/peterbourgon/diskv/v3"
+)
+
+const (
+	KindPlace = "place"
+	KindClose = "close"
+
+	defaultMaxAttempts = 10
+	defaultBaseBackoff = 5 * time.Second
+	defaultMaxBackoff  = 5 * time.Minute
+)
+
+// Action is an exante call that failed and has to be sent again,
+// Payload keep whatever the caller needs to rebuild the call
+type Action struct {
+	ID          string          `json:"id"`
+	Kind        string          `json:"kind"`
+	Ticket      string          `json:"ticket"`
+	AccountID   string          `json:"accountId"`
+	Payload     json.RawMessage `json:"payload"`
+	CreatedAt   time.Time       `json:"createdAt"`
+	Attempts    int             `json:"attempts"`
+	NextAttempt time.Time       `json:"nextAttempt"`
+	LastError   string          `json:"lastError,omitempty"`
+	DeadReason  string          `json:"deadReason,omitempty"`
+}
+
+func NewAction(kind string, ticket string, accountID string, payload any) (Action, error) {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return Action{}, err
+	}
+	now := time.Now()
+	return Action{
+		ID:          fmt.Sprintf("%s-%s", kind, ticket),
+		Kind:        kind,
+		Ticket:      ticket,
+		AccountID:   accountID,
+		Payload:     b,
+		CreatedAt:   now,
+		NextAttempt: now,
+	}, nil
+}
+
+type root struct {
+	Pending map[string]Action `json:"pending"`
+	Dead    map[string]Action `json:"dead"`
+}
+
+// Queue keep pending actions with retry/backoff, actions that fail
+// MaxAttempts times or expire are moved to the dead letter list
+// until they are replayed or discarded. Every change is persisted.
+type Queue struct {
+	d           *diskv.Diskv
+	MaxAttempts int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+
+	mu      sync.Mutex
+	pending map[string]Action
+	dead    map[string]Action
+}
+
+func NewNoDisk() *Queue {
+	return &Queue{
+		MaxAttempts: defaultMaxAttempts,
+		BaseBackoff: defaultBaseBackoff,
+		MaxBackoff:  defaultMaxBackoff,
+		pending:     make(map[string]Action),
+		dead:        make(map[string]Action),
+	}
+}
+
+func New(path string) (*Queue, error) {
+	d := diskv.New(diskv.Options{
+		BasePath: fmt.Sprintf("%s/.queue", path),
+		// writes go to a temp file renamed over the key, a crash
+		// never leaves a truncated queue
+		TempDir:      fmt.Sprintf("%s/.queue-tmp", path),
+		Transform:    func(s string) []string { return []string{} },
+		CacheSizeMax: 1024 * 1024,
+	})
+
+	q := NewNoDisk()
+	q.d = d
+
+	if !d.Has("root") {
+		return q, q.flush()
+	}
+
+	b, err := d.Read("root")
+	if err != nil {
+		return nil, err
+	}
+
+	var r root
+	err = json.Unmarshal(b, &r)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read pending queue: %s", err.Error())
+	}
+	if r.Pending != nil {
+		q.pending = r.Pending
+	}
+	if r.Dead != nil {
+		q.dead = r.Dead
+	}
+	return q, nil
+}
+
+// flush must be called with q.mu locked
+func (q *Queue) flush() error {
+	if q.d == nil {
+		return nil
+	}
+	b, err := json.Marshal(root{Pending: q.pending, Dead: q.dead})
+	if err != nil {
+		return err
+	}
+	return q.d.Write("root", b)
+}
+
+// Export return the pending actions and dead letters as json
+func (q *Queue) Export() ([]byte, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return json.Marshal(root{Pending: q.pending, Dead: q.dead})
+}
+
+// Import replace the queue by an Export
+func (q *Queue) Import(b []byte) error {
+	var r root
+	err := json.Unmarshal(b, &r)
+	if err != nil {
+		return fmt.Errorf("cannot read pending queue: %s", err.Error())
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.pending = make(map[string]Action)
+	q.dead = make(map[string]Action)
+	maps.Copy(q.pending, r.Pending)
+	maps.Copy(q.dead, r.Dead)
+	return q.flush()
+}
+
+// Push add an action to the queue, an action with the same id is replaced
+func (q *Queue) Push(action Action) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.pending[action.ID] = action
+	return q.flush()
+}
+
+// Due return pending actions ready to be sent, oldest first
+func (q *Queue) Due(now time.Time) []Action {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	due := make([]Action, 0)
+	for _, action := range q.pending {
+		if !action.NextAttempt.After(now) {
+			due = append(due, action)
+		}
+	}
+	sortActions(due)
+	return due
+}
+
+// Done remove a pending action that was sent
+func (q *Queue) Done(id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	delete(q.pending, id)
+	return q.flush()
+}
+
+// Retry register a failed attempt and schedule the next one with
+// exponential backoff, the action is dead lettered after MaxAttempts
+func (q *Queue) Retry(id string, cause error, now time.Time) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	action, has := q.pending[id]
+	if !has {
+		return fmt.Errorf("action %s not found", id)
+	}
+
+	action.Attempts++
+	action.LastError = cause.Error()
+	if action.Attempts >= q.MaxAttempts {
+		action.DeadReason = fmt.Sprintf("failed %d times", action.Attempts)
+		delete(q.pending, id)
+		q.dead[id] = action
+		return q.flush()
+	}
+
+	backoff := q.BaseBackoff << (action.Attempts - 1)
+	if backoff > q.MaxBackoff || backoff < q.BaseBackoff {
+		backoff = q.MaxBackoff
+	}
+	action.NextAttempt = now.Add(backoff)
+	q.pending[id] = action
+	return q.flush()
+}
+
+// Dead move a pending action to the dead letter list
+func (q *Queue) Dead(id string, reason string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	action, has := q.pending[id]
+	if !has {
+		return fmt.Errorf("action %s not found", id)
+	}
+
+	action.DeadReason = reason
+	delete(q.pending, id)
+	q.dead[id] = action
+	return q.flush()
+}
+
+func (q *Queue) Pending() []Action {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return listActions(q.pending)
+}
+
+func (q *Queue) DeadLetters() []Action {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return listActions(q.dead)
+}
+
+// Replay move a dead letter back to the queue to be sent on the next sync,
+// the action age is reset so it isn't expired again
+func (q *Queue) Replay(id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	action, has := q.dead[id]
+	if !has {
+		return fmt.Errorf("dead letter %s not found", id)
+	}
+
+	now := time.Now()
+	action.Attempts = 0
+	action.DeadReason = ""
+	action.CreatedAt = now
+	action.NextAttempt = now
+	delete(q.dead, id)
+	q.pending[id] = action
+	return q.flush()
+}
+
+func (q *Queue) Discard(id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, has := q.dead[id]; !has {
+		return fmt.Errorf("dead letter %s not found", id)
+	}
+
+	delete(q.dead, id)
+	return q.flush()
+}
+
+func listActions(m map[string]Action) []Action {
+	l := make([]Action, 0, len(m))
+	for _, action := range m {
+		l = append(l, action)
+	}
+	sortActions(l)
+	return l
+}
+
+func sortActions(l []Action) {
+	sort.Slice(l, func(i, j int) bool {
+		return l[i].CreatedAt.Before(l[j].CreatedAt)
+	})
+}
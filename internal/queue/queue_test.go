@@ -0,0 +1,118 @@
+package queue
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueue(t *testing.T) {
+	now := time.Now()
+
+	t.Run("due actions should be returned oldest first", func(t *testing.T) {
+		q := NewNoDisk()
+		newer, _ := NewAction(KindPlace, "2", "acc-1", nil)
+		older, _ := NewAction(KindClose, "1", "acc-1", nil)
+		older.CreatedAt = newer.CreatedAt.Add(-time.Minute)
+		later, _ := NewAction(KindPlace, "3", "acc-1", nil)
+		later.NextAttempt = now.Add(time.Hour)
+
+		assert.NoError(t, q.Push(newer))
+		assert.NoError(t, q.Push(older))
+		assert.NoError(t, q.Push(later))
+
+		due := q.Due(now.Add(time.Second))
+		assert.Len(t, due, 2)
+		assert.Equal(t, "close-1", due[0].ID)
+		assert.Equal(t, "place-2", due[1].ID)
+	})
+
+	t.Run("retry should back off and dead letter after max attempts", func(t *testing.T) {
+		q := NewNoDisk()
+		q.MaxAttempts = 3
+		action, _ := NewAction(KindPlace, "1", "acc-1", nil)
+		assert.NoError(t, q.Push(action))
+
+		assert.NoError(t, q.Retry(action.ID, errors.New("timeout"), now))
+		assert.Equal(t, now.Add(q.BaseBackoff), q.Pending()[0].NextAttempt)
+		assert.NoError(t, q.Retry(action.ID, errors.New("timeout"), now))
+		assert.Equal(t, now.Add(2*q.BaseBackoff), q.Pending()[0].NextAttempt)
+		assert.Len(t, q.Due(now), 0)
+
+		assert.NoError(t, q.Retry(action.ID, errors.New("timeout"), now))
+		assert.Len(t, q.Pending(), 0)
+		dead := q.DeadLetters()
+		assert.Len(t, dead, 1)
+		assert.Equal(t, "failed 3 times", dead[0].DeadReason)
+		assert.Equal(t, "timeout", dead[0].LastError)
+	})
+
+	t.Run("backoff should not go over max backoff", func(t *testing.T) {
+		q := NewNoDisk()
+		q.MaxAttempts = 100
+		action, _ := NewAction(KindPlace, "1", "acc-1", nil)
+		assert.NoError(t, q.Push(action))
+		for i := 0; i < 70; i++ {
+			assert.NoError(t, q.Retry(action.ID, errors.New("timeout"), now))
+		}
+		assert.Equal(t, now.Add(q.MaxBackoff), q.Pending()[0].NextAttempt)
+	})
+
+	t.Run("dead letters should be replayed or discarded", func(t *testing.T) {
+		q := NewNoDisk()
+		action, _ := NewAction(KindPlace, "1", "acc-1", nil)
+		action.Attempts = 4
+		assert.NoError(t, q.Push(action))
+		assert.NoError(t, q.Dead(action.ID, "market order expired"))
+		assert.Error(t, q.Dead(action.ID, "again"))
+
+		assert.NoError(t, q.Replay(action.ID))
+		pending := q.Pending()
+		assert.Len(t, pending, 1)
+		assert.Equal(t, 0, pending[0].Attempts)
+		assert.Empty(t, pending[0].DeadReason)
+
+		assert.NoError(t, q.Dead(action.ID, "market order expired"))
+		assert.NoError(t, q.Discard(action.ID))
+		assert.Len(t, q.DeadLetters(), 0)
+		assert.Error(t, q.Discard(action.ID))
+	})
+
+	t.Run("actions should be loaded back from disk", func(t *testing.T) {
+		dir := t.TempDir()
+		q, err := New(dir)
+		assert.NoError(t, err)
+		action, _ := NewAction(KindClose, "1", "acc-1", map[string]string{"Ticket": "1"})
+		assert.NoError(t, q.Push(action))
+		dead, _ := NewAction(KindPlace, "2", "acc-1", nil)
+		assert.NoError(t, q.Push(dead))
+		assert.NoError(t, q.Dead(dead.ID, "expired"))
+
+		q, err = New(dir)
+		assert.NoError(t, err)
+		assert.Len(t, q.Pending(), 1)
+		assert.JSONEq(t, `{"Ticket":"1"}`, string(q.Pending()[0].Payload))
+		assert.Len(t, q.DeadLetters(), 1)
+
+		tmp, err := os.ReadDir(filepath.Join(dir, ".queue-tmp"))
+		assert.NoError(t, err)
+		assert.Len(t, tmp, 0, "writes go through the temp dir")
+	})
+
+	t.Run("export should be imported on another queue", func(t *testing.T) {
+		q := NewNoDisk()
+		action, _ := NewAction(KindPlace, "1", "acc-1", nil)
+		assert.NoError(t, q.Push(action))
+		b, err := q.Export()
+		assert.NoError(t, err)
+
+		other := NewNoDisk()
+		assert.NoError(t, other.Import(b))
+		assert.Len(t, other.Pending(), 1)
+		assert.Error(t, other.Import([]byte("{")))
+	})
+}
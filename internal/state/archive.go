@@ -0,0 +1,222 @@
+package state
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/danielsussa/mt5-to-exante/internal/controller"
+	"github.com/danielsussa/mt5-to-exante/internal/journal"
+	"github.com/danielsussa/mt5-to-exante/internal/orderdb"
+	"github.com/danielsussa/mt5-to-exante/internal/queue"
+)
+
+// ArchiveVersion is the layout of the archive written by Export
+const ArchiveVersion = 1
+
+const (
+	manifestFile = "manifest.json"
+	orderdbFile  = "orderdb.json"
+	historyFile  = "history.json"
+	queueFile    = "queue.json"
+	journalFile  = "journal.log"
+)
+
+type Manifest struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"createdAt"`
+	Files     []string  `json:"files"`
+}
+
+// Dir is the SDK folder the state is read from and written to
+type Dir struct {
+	Path           string
+	OrderdbBackend string
+}
+
+func (d Dir) file(name string) string {
+	return filepath.Join(d.Path, name)
+}
+
+// Export write orderdb, dedup history, pending queue and journal
+// of dir to a tar.gz archive. The SDK must be stopped.
+func Export(w io.Writer, dir Dir) error {
+	files := make(map[string][]byte)
+
+	storage, err := orderdb.OpenStorage(dir.Path, dir.OrderdbBackend)
+	if err != nil {
+		return err
+	}
+	dump, err := orderdb.Dump(storage)
+	_ = storage.Close()
+	if err != nil {
+		return err
+	}
+	if files[orderdbFile], err = json.Marshal(dump); err != nil {
+		return err
+	}
+
+	pending, err := queue.New(dir.Path)
+	if err != nil {
+		return err
+	}
+	if files[queueFile], err = pending.Export(); err != nil {
+		return err
+	}
+
+	if files[historyFile], err = readOptional(dir.file(controller.HistoryFile), []byte("{}")); err != nil {
+		return err
+	}
+	if files[journalFile], err = readOptional(dir.file(journal.FileName), []byte{}); err != nil {
+		return err
+	}
+
+	manifest := Manifest{
+		Version:   ArchiveVersion,
+		CreatedAt: time.Now(),
+		Files:     []string{orderdbFile, queueFile, historyFile, journalFile},
+	}
+	manifestB, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	err = writeFile(tw, manifestFile, manifestB, manifest.CreatedAt)
+	if err != nil {
+		return err
+	}
+	for _, name := range manifest.Files {
+		err = writeFile(tw, name, files[name], manifest.CreatedAt)
+		if err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// Import replace the state of dir by an archive written by Export, dir
+// must have no orderdb groups unless force is set. The SDK must be stopped.
+func Import(r io.Reader, dir Dir, force bool) error {
+	files, err := readArchive(r)
+	if err != nil {
+		return err
+	}
+
+	var manifest Manifest
+	err = json.Unmarshal(files[manifestFile], &manifest)
+	if err != nil {
+		return fmt.Errorf("invalid archive, cannot read manifest: %s", err.Error())
+	}
+	if manifest.Version != ArchiveVersion {
+		return fmt.Errorf("archive version %d is not supported, expected %d", manifest.Version, ArchiveVersion)
+	}
+	for _, name := range manifest.Files {
+		if _, has := files[name]; !has {
+			return fmt.Errorf("invalid archive, missing %s", name)
+		}
+	}
+
+	var dump map[string]string
+	err = json.Unmarshal(files[orderdbFile], &dump)
+	if err != nil {
+		return fmt.Errorf("invalid archive, cannot read %s: %s", orderdbFile, err.Error())
+	}
+
+	storage, err := orderdb.OpenStorage(dir.Path, dir.OrderdbBackend)
+	if err != nil {
+		return err
+	}
+	defer storage.Close()
+
+	if !force && orderdb.HasGroups(storage) {
+		return fmt.Errorf("%s already has state, import with force to replace it", dir.Path)
+	}
+
+	err = orderdb.Restore(storage, dump)
+	if err != nil {
+		return err
+	}
+
+	pending, err := queue.New(dir.Path)
+	if err != nil {
+		return err
+	}
+	err = pending.Import(files[queueFile])
+	if err != nil {
+		return err
+	}
+
+	err = writeAtomic(dir.file(controller.HistoryFile), files[historyFile])
+	if err != nil {
+		return err
+	}
+	return writeAtomic(dir.file(journal.FileName), files[journalFile])
+}
+
+func readOptional(path string, empty []byte) ([]byte, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return empty, nil
+	}
+	return b, err
+}
+
+func writeAtomic(path string, b []byte) error {
+	tmp := path + ".tmp"
+	err := os.WriteFile(tmp, b, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func writeFile(tw *tar.Writer, name string, b []byte, modTime time.Time) error {
+	err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(b)),
+		ModTime: modTime,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = tw.Write(b)
+	return err
+}
+
+func readArchive(r io.Reader) (map[string][]byte, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid archive: %s", err.Error())
+	}
+	defer gz.Close()
+
+	files := make(map[string][]byte)
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid archive: %s", err.Error())
+		}
+
+		b, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		files[header.Name] = b
+	}
+}
@@ -0,0 +1,70 @@
+package state
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/danielsussa/mt5-to-exante/internal/controller"
+	"github.com/danielsussa/mt5-to-exante/internal/journal"
+	"github.com/danielsussa/mt5-to-exante/internal/orderdb"
+	"github.com/danielsussa/mt5-to-exante/internal/queue"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArchive(t *testing.T) {
+	t.Run("should move the state to another folder", func(t *testing.T) {
+		from := Dir{Path: t.TempDir(), OrderdbBackend: orderdb.BackendDiskv}
+		to := Dir{Path: t.TempDir(), OrderdbBackend: orderdb.BackendFile}
+
+		s, err := orderdb.OpenStorage(from.Path, from.OrderdbBackend)
+		assert.NoError(t, err)
+		db, err := orderdb.NewWithStorage(s)
+		assert.NoError(t, err)
+		_, err = db.Append("1234", orderdb.Event{Type: orderdb.EventPlaced, Role: orderdb.RoleParent, Order: &orderdb.OrderDB{ID: "parent"}})
+		assert.NoError(t, err)
+		assert.NoError(t, db.Close())
+
+		pending, err := queue.New(from.Path)
+		assert.NoError(t, err)
+		action, err := queue.NewAction(queue.KindPlace, "1234", "ACC", map[string]string{})
+		assert.NoError(t, err)
+		assert.NoError(t, pending.Push(action))
+
+		assert.NoError(t, os.WriteFile(filepath.Join(from.Path, controller.HistoryFile), []byte(`{"order-1234":"hash"}`), 0644))
+		assert.NoError(t, journal.New(filepath.Join(from.Path, journal.FileName)).Add("1234 > PLACED"))
+
+		archive := new(bytes.Buffer)
+		assert.NoError(t, Export(archive, from))
+		assert.NoError(t, Import(bytes.NewReader(archive.Bytes()), to, false))
+
+		s, err = orderdb.OpenStorage(to.Path, to.OrderdbBackend)
+		assert.NoError(t, err)
+		db, err = orderdb.NewWithStorage(s)
+		assert.NoError(t, err)
+		group, has := db.Get("1234")
+		assert.True(t, has)
+		assert.Equal(t, "parent", group.Order.ID)
+		events, err := db.Events("1234")
+		assert.NoError(t, err)
+		assert.Len(t, events, 1)
+		assert.NoError(t, db.Close())
+
+		pending, err = queue.New(to.Path)
+		assert.NoError(t, err)
+		assert.Len(t, pending.Pending(), 1)
+
+		history, err := os.ReadFile(filepath.Join(to.Path, controller.HistoryFile))
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"order-1234":"hash"}`, string(history))
+
+		entries, err := journal.New(filepath.Join(to.Path, journal.FileName)).Recent(10)
+		assert.NoError(t, err)
+		assert.Len(t, entries, 1)
+
+		// the target has state now
+		assert.Error(t, Import(bytes.NewReader(archive.Bytes()), to, false))
+		assert.NoError(t, Import(bytes.NewReader(archive.Bytes()), to, true))
+	})
+}
@@ -19,6 +19,7 @@ func ConvertNDecimals(k float64) string {
 func ConvertExOrderToDB(v3 exante.OrderV3) *orderdb.OrderDB {
 	return &orderdb.OrderDB{
 		ID:         v3.OrderID,
+		Status:     string(v3.OrderState.Status),
 		StopPrice:  v3.OrderParameters.StopPrice,
 		Price:      v3.OrderParameters.LimitPrice,
 		Quantity:   v3.OrderParameters.Quantity,